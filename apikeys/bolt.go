@@ -0,0 +1,92 @@
+package apikeys
+
+import (
+	"context"
+
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type boltRepository struct {
+	db     *bbolt.DB
+	bucket []byte
+}
+
+// NewBoltRepository returns a KeyRepository that stores keys in the given bucket of a bolt database file.
+// Documents are encoded as BSON and keyed by id.
+func NewBoltRepository(db *bbolt.DB, bucket string) (KeyRepository, error) {
+	r := &boltRepository{db: db, bucket: []byte(bucket)}
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(r.bucket)
+		return err
+	})
+	return r, err
+}
+
+func (r *boltRepository) put(b *bbolt.Bucket, k apiKey) error {
+	data, err := bson.Marshal(k)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(k.ID), data)
+}
+
+func (r *boltRepository) Create(ctx context.Context, k apiKey) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		if b.Get([]byte(k.ID)) != nil {
+			return ErrDuplicateID
+		}
+		return r.put(b, k)
+	})
+}
+
+func (r *boltRepository) Get(ctx context.Context, id string) (apiKey, error) {
+	result := apiKey{}
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(r.bucket).Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		return bson.Unmarshal(data, &result)
+	})
+	return result, err
+}
+
+func (r *boltRepository) ListByLogin(ctx context.Context, login string) ([]apiKey, error) {
+	var keys = []apiKey{}
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(r.bucket).ForEach(func(_, v []byte) error {
+			result := apiKey{}
+			if err := bson.Unmarshal(v, &result); err != nil {
+				return err
+			}
+			if result.Login == login {
+				keys = append(keys, result)
+			}
+			return nil
+		})
+	})
+	sortByCreation(keys)
+	return keys, err
+}
+
+func (r *boltRepository) Update(ctx context.Context, k apiKey) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		if b.Get([]byte(k.ID)) == nil {
+			return ErrNotFound
+		}
+		return r.put(b, k)
+	})
+}
+
+func (r *boltRepository) Delete(ctx context.Context, id string) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		if b.Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete([]byte(id))
+	})
+}
@@ -0,0 +1,227 @@
+package apikeys
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/pokemon-handbook/audit"
+	"example.com/pokemon-handbook/auth"
+)
+
+type newKey struct {
+	Label  string   `json:"label" example:"nightly import"`
+	Scopes []string `json:"scopes" enums:"pokemons:read,pokemons:write,users:manage"`
+	// ExpiresAt is optional, keys without it don't expire.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// createdKey is the only response which contains the key itself.
+type createdKey struct {
+	apiKey
+	Key string `json:"key" example:"phk_9f86d081884c7d65_..."`
+}
+
+type labelUpdate struct {
+	Label string `json:"label" example:"nightly import"`
+}
+
+// Handler serves the /apikeys routes on top of a KeyRepository. Users only ever see and change their own keys.
+type Handler struct {
+	repo     KeyRepository
+	auditLog *audit.Log
+}
+
+// NewHandler returns a Handler that stores keys in repo and records changes in auditLog.
+func NewHandler(repo KeyRepository, auditLog *audit.Log) *Handler {
+	return &Handler{repo: repo, auditLog: auditLog}
+}
+
+// PostAPIKey godoc
+// @title        Post API Key
+// @summary      Create an API key
+// @description  Create an API key for the authenticated user. The key is returned only in this response, only its hash is stored. Send it in the X-API-Key header; it allows what both the role of the user and one of its scopes allow.
+// @accept       json
+// @produce      json
+// @param        key  body  newKey  true  "label, scopes and optional expiry of the key"
+// @success      201 {object} createdKey
+// @failure      400 {string} string "object can't be parsed into JSON"
+// @failure      400 {string} string "unknown scope"
+// @router       /apikeys [post]
+func (h *Handler) PostAPIKey(c *gin.Context) {
+	p, _ := auth.CurrentUser(c)
+	var req newKey
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "object can't be parsed into JSON"})
+		return
+	}
+	if len(req.Scopes) == 0 {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "at least one scope is required, use " + strings.Join(auth.Scopes(), ", ")})
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !auth.IsScope(scope) {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("unknown scope %q, use %s", scope, strings.Join(auth.Scopes(), ", "))})
+			return
+		}
+	}
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "expires_at must be in the future"})
+		return
+	}
+
+	key, id, err := generateKey()
+	if err != nil {
+		respondWithInternalError(c, err)
+		return
+	}
+	k := apiKey{
+		ID:        id,
+		Hash:      hashKey(key),
+		Login:     p.Login,
+		Label:     req.Label,
+		Scopes:    req.Scopes,
+		CreatedAt: now,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := h.repo.Create(c.Request.Context(), k); err != nil {
+		respondWithInternalError(c, err)
+		return
+	}
+	// the key itself is left out of the log, like out of every other response
+	h.auditLog.Record(c, audit.ActionCreate, resource(k.ID), nil, k)
+	c.IndentedJSON(http.StatusCreated, createdKey{apiKey: k, Key: key})
+}
+
+// GetAPIKeys godoc
+// @title        Get API Keys
+// @summary      List the API keys of the authenticated user
+// @description  Get the API keys of the authenticated user, oldest first. The keys themselves are not returned.
+// @produce      json
+// @success      200 {array} apiKey
+// @router       /apikeys [get]
+func (h *Handler) GetAPIKeys(c *gin.Context) {
+	p, _ := auth.CurrentUser(c)
+	keys, err := h.repo.ListByLogin(c.Request.Context(), p.Login)
+	if err != nil {
+		respondWithInternalError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, keys)
+}
+
+// UpdateAPIKeyByID godoc
+// @title        Update API Key By ID
+// @summary      Change the label of an API key
+// @description  Change the label of an API key of the authenticated user.
+// @accept       json
+// @produce      json
+// @param        id     path  string       true  "id of the key"
+// @param        label  body  labelUpdate  true  "the new label"
+// @success      200 {object} apiKey
+// @failure      400 {string} string "object can't be parsed into JSON"
+// @failure      404 {string} string "api key not found"
+// @router       /apikeys/{id} [put]
+func (h *Handler) UpdateAPIKeyByID(c *gin.Context) {
+	var req labelUpdate
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "object can't be parsed into JSON"})
+		return
+	}
+	k, ok := h.find(c)
+	if !ok {
+		return
+	}
+
+	before := k
+	k.Label = req.Label
+	if err := h.repo.Update(c.Request.Context(), k); err != nil {
+		respondWithLookupError(c, err)
+		return
+	}
+	h.auditLog.Record(c, audit.ActionUpdate, resource(k.ID), before, k)
+	c.IndentedJSON(http.StatusOK, k)
+}
+
+// DeleteAPIKeyByID godoc
+// @title        Delete API Key By ID
+// @summary      Revoke an API key
+// @description  Delete an API key of the authenticated user. Requests with the key are refused from then on.
+// @produce      json
+// @param        id  path  string  true  "id of the key"
+// @success      200 {string} string "api key was revoked"
+// @failure      404 {string} string "api key not found"
+// @router       /apikeys/{id} [delete]
+func (h *Handler) DeleteAPIKeyByID(c *gin.Context) {
+	k, ok := h.find(c)
+	if !ok {
+		return
+	}
+	if err := h.repo.Delete(c.Request.Context(), k.ID); err != nil {
+		respondWithLookupError(c, err)
+		return
+	}
+	h.auditLog.Record(c, audit.ActionDelete, resource(k.ID), k, nil)
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "api key was revoked"})
+}
+
+// resource names the key with the given id in the audit log.
+func resource(id string) string {
+	return "apikeys/" + id
+}
+
+// RevokeKeys deletes every key of login on behalf of the request of c, recording it in the audit log.
+// It lets users.Handler revoke the keys of a deleted user, which would otherwise be inherited by
+// a user created later with the same login.
+func (h *Handler) RevokeKeys(c *gin.Context, login string) error {
+	keys, err := h.repo.ListByLogin(c.Request.Context(), login)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		err := h.repo.Delete(c.Request.Context(), k.ID)
+		if errors.Is(err, ErrNotFound) {
+			// revoked by another request in the meantime
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		h.auditLog.Record(c, audit.ActionDelete, resource(k.ID), k, nil)
+	}
+	return nil
+}
+
+// find returns the key named by the id parameter if it belongs to the authenticated user.
+// When it returns false the error response has already been written.
+func (h *Handler) find(c *gin.Context) (apiKey, bool) {
+	p, _ := auth.CurrentUser(c)
+	k, err := h.repo.Get(c.Request.Context(), c.Param("id"))
+	if err == nil && k.Login != p.Login {
+		// keys of other users are not even acknowledged
+		err = ErrNotFound
+	}
+	if err != nil {
+		respondWithLookupError(c, err)
+		return apiKey{}, false
+	}
+	return k, true
+}
+
+func respondWithLookupError(c *gin.Context, err error) {
+	if errors.Is(err, ErrNotFound) {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "api key not found"})
+		return
+	}
+	respondWithInternalError(c, err)
+}
+
+func respondWithInternalError(c *gin.Context, err error) {
+	fmt.Println(err)
+	c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+}
@@ -0,0 +1,65 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/pokemon-handbook/audit"
+	"example.com/pokemon-handbook/auth"
+	"example.com/pokemon-handbook/users"
+)
+
+// noLockout is a users.LockoutClearer for users without failed logins.
+type noLockout struct{}
+
+func (noLockout) Clear(c *gin.Context, login string) {}
+
+func serve(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestDeletedUserKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	keys := NewMemoryRepository()
+	userRepo := users.NewMemoryRepository()
+	auditLog := audit.NewLog(audit.NewMemoryRepository())
+	userHandler := users.NewHandler(userRepo, auditLog, noLockout{}, NewHandler(keys, auditLog))
+	verifier := NewVerifier(keys, users.NewAuthenticator(userRepo, time.Minute))
+
+	r := gin.New()
+	r.POST("/users", userHandler.PostUser)
+	r.DELETE("/users/:id", userHandler.DeleteUserByLogin)
+	ash := `{"login": "ash", "password": "pikachu", "role": "editor"}`
+	if w := serve(r, http.MethodPost, "/users", ash); w.Code != http.StatusCreated {
+		t.Fatalf("create ash: status = %d: %s", w.Code, w.Body)
+	}
+	key := storeKey(t, keys, apiKey{Login: "ash", Scopes: []string{auth.ScopeWritePokemons}})
+	if _, err := verifier.VerifyToken(ctx, key); err != nil {
+		t.Fatalf("the key of ash is rejected: %v", err)
+	}
+
+	if w := serve(r, http.MethodDelete, "/users/ash", ""); w.Code != http.StatusOK {
+		t.Fatalf("delete ash: status = %d: %s", w.Code, w.Body)
+	}
+	if w := serve(r, http.MethodPost, "/users", ash); w.Code != http.StatusCreated {
+		t.Fatalf("re-create ash: status = %d: %s", w.Code, w.Body)
+	}
+	if _, err := verifier.VerifyToken(ctx, key); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("the re-created ash inherited the key of the deleted one: %v", err)
+	}
+	if left, err := keys.ListByLogin(ctx, "ash"); err != nil || len(left) != 0 {
+		t.Errorf("ash still has %d keys: %v", len(left), err)
+	}
+}
@@ -0,0 +1,101 @@
+// Package apikeys lets users create personal API keys for machine clients, sent in the X-API-Key header.
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"example.com/pokemon-handbook/auth"
+)
+
+// keyPrefix starts every key, so that leaked keys are easy to recognize.
+const keyPrefix = "phk_"
+
+// idLen is the length of the hex encoded id following keyPrefix.
+const idLen = 16
+
+type apiKey struct {
+	ID string `bson:"_id" json:"id" example:"9f86d081884c7d65"`
+	// Hash is the SHA-256 of the whole key, hex encoded. The key itself is only shown when it is created.
+	Hash      string     `bson:"hash" json:"-"`
+	Login     string     `bson:"login" json:"login" example:"admin"`
+	Label     string     `bson:"label" json:"label" example:"nightly import"`
+	Scopes    []string   `bson:"scopes" json:"scopes" enums:"pokemons:read,pokemons:write,users:manage"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+	ExpiresAt *time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+func (k apiKey) expired(now time.Time) bool {
+	return k.ExpiresAt != nil && now.After(*k.ExpiresAt)
+}
+
+// generateKey returns a new key of the form phk_<id>_<secret> and its id.
+func generateKey() (key, id string, err error) {
+	b := make([]byte, idLen/2+32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	id = hex.EncodeToString(b[:idLen/2])
+	return keyPrefix + id + "_" + base64.RawURLEncoding.EncodeToString(b[idLen/2:]), id, nil
+}
+
+// parseKey returns the id of a key, or false when key doesn't look like one.
+func parseKey(key string) (string, bool) {
+	rest := strings.TrimPrefix(key, keyPrefix)
+	if len(rest) == len(key) || len(rest) < idLen+2 || rest[idLen] != '_' {
+		return "", false
+	}
+	return rest[:idLen], true
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verifier checks API keys. It implements auth.TokenVerifier.
+type Verifier struct {
+	repo  KeyRepository
+	users auth.UserLookup
+}
+
+// NewVerifier returns a Verifier accepting the keys stored in repo. The role of the key owner is read
+// from users on every request, so keys stop working as soon as their user is deleted.
+func NewVerifier(repo KeyRepository, users auth.UserLookup) *Verifier {
+	return &Verifier{repo: repo, users: users}
+}
+
+// VerifyToken implements auth.TokenVerifier. The principal it returns is limited to the scopes of the key.
+func (v *Verifier) VerifyToken(ctx context.Context, key string) (auth.Principal, error) {
+	id, ok := parseKey(key)
+	if !ok {
+		return auth.Principal{}, auth.ErrInvalidToken
+	}
+	k, err := v.repo.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return auth.Principal{}, auth.ErrInvalidToken
+	}
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(k.Hash), []byte(hashKey(key))) != 1 || k.expired(time.Now()) {
+		return auth.Principal{}, auth.ErrInvalidToken
+	}
+
+	p, err := v.users.LookupUser(ctx, k.Login)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		return auth.Principal{}, auth.ErrInvalidToken
+	}
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	p.Scopes = append([]string{}, k.Scopes...)
+	return p, nil
+}
@@ -0,0 +1,94 @@
+package apikeys
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/pokemon-handbook/auth"
+)
+
+// storeKey stores k under a new key, which it returns.
+func storeKey(t *testing.T, repo KeyRepository, k apiKey) string {
+	t.Helper()
+	key, id, err := generateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.ID, k.Hash, k.CreatedAt = id, hashKey(key), time.Now()
+	if err := repo.Create(context.Background(), k); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// roles is an auth.UserLookup of the users with the given roles, by login.
+type roles map[string]string
+
+func (r roles) LookupUser(ctx context.Context, login string) (auth.Principal, error) {
+	role, ok := r[login]
+	if !ok {
+		return auth.Principal{}, auth.ErrInvalidCredentials
+	}
+	return auth.Principal{Login: login, Role: role}, nil
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	tests := []struct {
+		name string
+		key  apiKey
+		// sent turns the stored key into the one sent in the X-API-Key header.
+		sent   func(key string) string
+		path   string
+		status int
+	}{
+		{"valid key", apiKey{Login: "ash", Scopes: []string{auth.ScopeWritePokemons}}, nil, "/pokemons", http.StatusOK},
+		{"unexpired key", apiKey{Login: "ash", Scopes: []string{auth.ScopeWritePokemons}, ExpiresAt: &future}, nil, "/pokemons", http.StatusOK},
+		{"expired key", apiKey{Login: "ash", Scopes: []string{auth.ScopeWritePokemons}, ExpiresAt: &past}, nil, "/pokemons", http.StatusUnauthorized},
+		{"wrong secret", apiKey{Login: "ash", Scopes: []string{auth.ScopeWritePokemons}}, func(key string) string {
+			return key[:len(keyPrefix)+idLen+1] + strings.Repeat("A", len(key)-len(keyPrefix)-idLen-1)
+		}, "/pokemons", http.StatusUnauthorized},
+		{"unknown id", apiKey{Login: "ash", Scopes: []string{auth.ScopeWritePokemons}}, func(key string) string {
+			return keyPrefix + strings.Repeat("0", idLen) + key[len(keyPrefix)+idLen:]
+		}, "/pokemons", http.StatusUnauthorized},
+		{"malformed key", apiKey{Login: "ash", Scopes: []string{auth.ScopeWritePokemons}}, func(key string) string {
+			return strings.TrimPrefix(key, keyPrefix)
+		}, "/pokemons", http.StatusUnauthorized},
+		{"deleted user", apiKey{Login: "gary", Scopes: []string{auth.ScopeWritePokemons}}, nil, "/pokemons", http.StatusUnauthorized},
+		{"scope narrower than the route", apiKey{Login: "ash", Scopes: []string{auth.ScopeReadPokemons}}, nil, "/pokemons", http.StatusForbidden},
+		{"scope of another route", apiKey{Login: "ash", Scopes: []string{auth.ScopeWritePokemons}}, nil, "/users", http.StatusForbidden},
+		{"scope beyond the role", apiKey{Login: "misty", Scopes: []string{auth.ScopeManageUsers}}, nil, "/users", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			repo := NewMemoryRepository()
+			users := roles{"ash": auth.Admin, "misty": auth.Editor}
+			key := storeKey(t, repo, tt.key)
+			if tt.sent != nil {
+				key = tt.sent(key)
+			}
+
+			r := gin.New()
+			r.Use(auth.Middleware(nil, nil, NewVerifier(repo, users)))
+			ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+			r.POST("/pokemons", auth.Require(auth.WritePokemons), ok)
+			r.POST("/users", auth.Require(auth.ManageUsers), ok)
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			req.Header.Set(auth.APIKeyHeader, key)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package apikeys
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+type memoryRepository struct {
+	mu   sync.RWMutex
+	keys map[string]apiKey
+}
+
+// NewMemoryRepository returns a KeyRepository that keeps keys in process memory.
+// It is safe for concurrent use and loses its data when the process exits.
+func NewMemoryRepository() KeyRepository {
+	return &memoryRepository{keys: make(map[string]apiKey)}
+}
+
+func (r *memoryRepository) Create(ctx context.Context, k apiKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.keys[k.ID]; ok {
+		return ErrDuplicateID
+	}
+	r.keys[k.ID] = k
+	return nil
+}
+
+func (r *memoryRepository) Get(ctx context.Context, id string) (apiKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	k, ok := r.keys[id]
+	if !ok {
+		return apiKey{}, ErrNotFound
+	}
+	return k, nil
+}
+
+func (r *memoryRepository) ListByLogin(ctx context.Context, login string) ([]apiKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := []apiKey{}
+	for _, k := range r.keys {
+		if k.Login == login {
+			keys = append(keys, k)
+		}
+	}
+	sortByCreation(keys)
+	return keys, nil
+}
+
+func (r *memoryRepository) Update(ctx context.Context, k apiKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.keys[k.ID]; !ok {
+		return ErrNotFound
+	}
+	r.keys[k.ID] = k
+	return nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.keys[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.keys, id)
+	return nil
+}
+
+func sortByCreation(keys []apiKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+}
@@ -0,0 +1,82 @@
+package apikeys
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoRepository returns a KeyRepository backed by the given MongoDB collection
+// and creates the indexes it relies on.
+func NewMongoRepository(collection *mongo.Collection) (KeyRepository, error) {
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "login", Value: 1}, {Key: "created_at", Value: 1}},
+		Options: options.Index().SetName("login_created_at"),
+	})
+	return &mongoRepository{collection: collection}, err
+}
+
+func (r *mongoRepository) Create(ctx context.Context, k apiKey) error {
+	_, err := r.collection.InsertOne(ctx, k)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateID
+	}
+	return err
+}
+
+func (r *mongoRepository) Get(ctx context.Context, id string) (apiKey, error) {
+	result := apiKey{}
+
+	err := r.collection.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		return result, ErrNotFound
+	}
+	return result, err
+}
+
+func (r *mongoRepository) ListByLogin(ctx context.Context, login string) ([]apiKey, error) {
+	var keys = []apiKey{}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := r.collection.Find(ctx, bson.D{{Key: "login", Value: login}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		result := apiKey{}
+		if err := cur.Decode(&result); err != nil {
+			return nil, err
+		}
+		keys = append(keys, result)
+	}
+	return keys, cur.Err()
+}
+
+func (r *mongoRepository) Update(ctx context.Context, k apiKey) error {
+	res, err := r.collection.ReplaceOne(ctx, bson.D{{Key: "_id", Value: k.ID}}, k)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoRepository) Delete(ctx context.Context, id string) error {
+	res, err := r.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: id}})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned by a KeyRepository when there is no key with the requested id.
+var ErrNotFound = errors.New("api key not found")
+
+// ErrDuplicateID is returned by KeyRepository.Create when a key with the same id is already stored.
+var ErrDuplicateID = errors.New("an api key with such id already exists")
+
+// KeyRepository is the storage used by the API key handlers.
+type KeyRepository interface {
+	// Create stores a new key or returns ErrDuplicateID.
+	Create(ctx context.Context, k apiKey) error
+	// Get returns the key with the given id or ErrNotFound.
+	Get(ctx context.Context, id string) (apiKey, error)
+	// ListByLogin returns the keys of a user, oldest first.
+	ListByLogin(ctx context.Context, login string) ([]apiKey, error)
+	// Update replaces the stored key with k or returns ErrNotFound.
+	Update(ctx context.Context, k apiKey) error
+	// Delete removes the key with the given id or returns ErrNotFound.
+	Delete(ctx context.Context, id string) error
+}
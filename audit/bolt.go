@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"context"
+
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type boltRepository struct {
+	db     *bbolt.DB
+	bucket []byte
+}
+
+// NewBoltRepository returns a Repository that stores the audit log in the given bucket of a bolt database file.
+// Documents are encoded as BSON and keyed by id, so the keys are in time order.
+func NewBoltRepository(db *bbolt.DB, bucket string) (Repository, error) {
+	r := &boltRepository{db: db, bucket: []byte(bucket)}
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(r.bucket)
+		return err
+	})
+	return r, err
+}
+
+func (r *boltRepository) Append(ctx context.Context, e entry) error {
+	data, err := bson.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(r.bucket).Put([]byte(e.ID), data)
+	})
+}
+
+func (r *boltRepository) List(ctx context.Context, f Filter) ([]entry, error) {
+	entries := []entry{}
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(r.bucket).Cursor()
+		skipped := 0
+		for k, v := c.Last(); k != nil && (f.Limit == 0 || len(entries) < f.Limit); k, v = c.Prev() {
+			e := entry{}
+			if err := bson.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			if !f.matches(e) {
+				continue
+			}
+			if skipped < f.Offset {
+				skipped++
+				continue
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	return entries, err
+}
@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultLimit and maxLimit bound the number of entries returned by GetAudit.
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// GetAudit godoc
+// @title        Get Audit Log
+// @summary      Retrieve the audit log
+// @description  Get the recorded changes to pokemons, users, their lockouts and API keys, newest first.
+// @produce      json
+// @param        actor     query  string  false  "only changes made by this login"
+// @param        resource  query  string  false  "only changes to this resource, e.g. pokemons/25, or below it, e.g. pokemons"
+// @param        from      query  string  false  "only changes made at or after this RFC 3339 time"
+// @param        to        query  string  false  "only changes made before this RFC 3339 time"
+// @param        limit     query  int     false  "maximum number of entries to return (1-1000)"  default(100)
+// @param        offset    query  int     false  "number of entries to skip"
+// @success      200 {array} entry
+// @failure      400 {string} string "invalid query parameters"
+// @router       /audit [get]
+func (l *Log) GetAudit(c *gin.Context) {
+	f := Filter{
+		Actor:    c.Query("actor"),
+		Resource: c.Query("resource"),
+		Limit:    defaultLimit,
+	}
+
+	var err error
+	if s := c.Query("from"); s != "" {
+		if f.From, err = time.Parse(time.RFC3339, s); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "from must be an RFC 3339 time"})
+			return
+		}
+	}
+	if s := c.Query("to"); s != "" {
+		if f.To, err = time.Parse(time.RFC3339, s); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "to must be an RFC 3339 time"})
+			return
+		}
+	}
+	if s := c.Query("limit"); s != "" {
+		if f.Limit, err = strconv.Atoi(s); err != nil || f.Limit < 1 || f.Limit > maxLimit {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("limit must be a number from 1 to %d", maxLimit)})
+			return
+		}
+	}
+	if s := c.Query("offset"); s != "" {
+		if f.Offset, err = strconv.Atoi(s); err != nil || f.Offset < 0 {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "offset must be a non-negative number"})
+			return
+		}
+	}
+
+	entries, err := l.repo.List(c.Request.Context(), f)
+	if err != nil {
+		fmt.Println(err)
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, entries)
+}
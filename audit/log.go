@@ -0,0 +1,88 @@
+// Package audit keeps an append-only log of the changes made through the API.
+package audit
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"example.com/pokemon-handbook/auth"
+)
+
+// RequestIDHeader carries the id of a request, taken from the client when it sends one.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the gin.Context key under which RequestID stores the id.
+const requestIDKey = "audit.request_id"
+
+// maxRequestIDLen bounds the ids accepted from clients.
+const maxRequestIDLen = 128
+
+// Log records changes to a Repository.
+type Log struct {
+	repo Repository
+}
+
+// NewLog returns a Log appending to repo.
+func NewLog(repo Repository) *Log {
+	return &Log{repo: repo}
+}
+
+// Record appends an entry for a change made by the request of c. before and after are the resource
+// as the API returns it, nil when it didn't or doesn't exist. The change has already happened, so
+// failures to record it are printed rather than returned.
+func (l *Log) Record(c *gin.Context, action, resource string, before, after interface{}) {
+	e := entry{
+		ID:        primitive.NewObjectID().Hex(),
+		Time:      time.Now().UTC().Truncate(time.Millisecond),
+		Action:    action,
+		Resource:  resource,
+		ClientIP:  c.ClientIP(),
+		RequestID: c.GetString(requestIDKey),
+	}
+	if p, ok := auth.CurrentUser(c); ok {
+		e.Actor = p.Login
+	}
+
+	var err error
+	if e.Before, err = snapshot(before); err == nil {
+		e.After, err = snapshot(after)
+	}
+	if err == nil {
+		err = l.repo.Append(c.Request.Context(), e)
+	}
+	if err != nil {
+		fmt.Printf("failed to record %s of %s in the audit log: %v\n", action, resource, err)
+	}
+}
+
+// snapshot encodes v as the API does, so that secrets left out of responses are left out of the log too.
+func snapshot(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// RequestID returns a handler giving every request an id, which is sent back in the X-Request-ID header
+// and recorded with the changes the request makes. Ids sent by clients are kept.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLen {
+			b := make([]byte, 16)
+			if _, err := rand.Read(b); err != nil {
+				panic(err)
+			}
+			id = hex.EncodeToString(b)
+		}
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
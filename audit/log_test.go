@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/pokemon-handbook/auth"
+)
+
+// anyPassword is an auth.Authenticator letting every login in as an editor.
+type anyPassword struct{}
+
+func (anyPassword) Authenticate(ctx context.Context, login, password string) (auth.Principal, error) {
+	return auth.Principal{Login: login, Role: auth.Editor}, nil
+}
+
+// resource is a resource with a secret the API doesn't return.
+type resource struct {
+	Name   string `json:"name"`
+	Secret string `json:"-"`
+}
+
+func TestRecord(t *testing.T) {
+	tests := []struct {
+		name          string
+		action        string
+		before, after interface{}
+		// wantBefore and wantAfter are the snapshots, empty when there is none.
+		wantBefore, wantAfter string
+	}{
+		{"create", ActionCreate, nil, resource{Name: "Pikachu"}, "", `{"name":"Pikachu"}`},
+		{"update", ActionUpdate, resource{Name: "Pikachu"}, &resource{Name: "Raichu"}, `{"name":"Pikachu"}`, `{"name":"Raichu"}`},
+		{"delete", ActionDelete, resource{Name: "Raichu"}, nil, `{"name":"Raichu"}`, ""},
+		{"secrets left out", ActionUpdate, resource{Name: "Pikachu", Secret: "old"}, resource{Name: "Pikachu", Secret: "new"}, `{"name":"Pikachu"}`, `{"name":"Pikachu"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			repo := NewMemoryRepository()
+			l := NewLog(repo)
+			r := gin.New()
+			r.Use(RequestID(), auth.Middleware(anyPassword{}, nil, nil))
+			r.POST("/pokemons/25", func(c *gin.Context) {
+				l.Record(c, tt.action, "pokemons/25", tt.before, tt.after)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/pokemons/25", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			req.SetBasicAuth("ash", "pikachu")
+			req.Header.Set(RequestIDHeader, "request-1")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			entries, err := repo.List(context.Background(), Filter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("%d entries are recorded, want 1", len(entries))
+			}
+			e := entries[0]
+			if e.Actor != "ash" || e.Action != tt.action || e.Resource != "pokemons/25" || e.ClientIP != "10.0.0.1" || e.RequestID != "request-1" {
+				t.Errorf("entry = %+v, want %s of pokemons/25 by ash from 10.0.0.1 in request-1", e, tt.action)
+			}
+			if string(e.Before) != tt.wantBefore || string(e.After) != tt.wantAfter {
+				t.Errorf("before = %s, after = %s, want %s and %s", e.Before, e.After, tt.wantBefore, tt.wantAfter)
+			}
+			if e.ID == "" || e.Time.IsZero() {
+				t.Errorf("entry has id %q at %v", e.ID, e.Time)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+type memoryRepository struct {
+	mu      sync.RWMutex
+	entries []entry
+}
+
+// NewMemoryRepository returns a Repository that keeps the audit log in process memory.
+// It is safe for concurrent use and loses its data when the process exits.
+func NewMemoryRepository() Repository {
+	return &memoryRepository{}
+}
+
+func (r *memoryRepository) Append(ctx context.Context, e entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, e)
+	return nil
+}
+
+func (r *memoryRepository) List(ctx context.Context, f Filter) ([]entry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := []entry{}
+	skipped := 0
+	for i := len(r.entries) - 1; i >= 0 && (f.Limit == 0 || len(entries) < f.Limit); i-- {
+		if !f.matches(r.entries[i]) {
+			continue
+		}
+		if skipped < f.Offset {
+			skipped++
+			continue
+		}
+		entries = append(entries, r.entries[i])
+	}
+	return entries, nil
+}
@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"context"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoRepository returns a Repository backed by the given MongoDB collection
+// and creates the indexes the filters of List rely on.
+func NewMongoRepository(collection *mongo.Collection) (Repository, error) {
+	_, err := collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "time", Value: -1}}, Options: options.Index().SetName("time")},
+		{Keys: bson.D{{Key: "actor", Value: 1}, {Key: "time", Value: -1}}, Options: options.Index().SetName("actor_time")},
+		{Keys: bson.D{{Key: "resource", Value: 1}, {Key: "time", Value: -1}}, Options: options.Index().SetName("resource_time")},
+	})
+	return &mongoRepository{collection: collection}, err
+}
+
+func (r *mongoRepository) Append(ctx context.Context, e entry) error {
+	_, err := r.collection.InsertOne(ctx, e)
+	return err
+}
+
+func (r *mongoRepository) List(ctx context.Context, f Filter) ([]entry, error) {
+	filter := bson.D{}
+	if f.Actor != "" {
+		filter = append(filter, bson.E{Key: "actor", Value: f.Actor})
+	}
+	if f.Resource != "" {
+		filter = append(filter, bson.E{Key: "resource", Value: bson.D{
+			{Key: "$regex", Value: "^" + regexp.QuoteMeta(f.Resource) + "(/|$)"},
+		}})
+	}
+	timeRange := bson.D{}
+	if !f.From.IsZero() {
+		timeRange = append(timeRange, bson.E{Key: "$gte", Value: f.From})
+	}
+	if !f.To.IsZero() {
+		timeRange = append(timeRange, bson.E{Key: "$lt", Value: f.To})
+	}
+	if len(timeRange) > 0 {
+		filter = append(filter, bson.E{Key: "time", Value: timeRange})
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetSkip(int64(f.Offset))
+	if f.Limit > 0 {
+		opts.SetLimit(int64(f.Limit))
+	}
+	cur, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	entries := []entry{}
+	for cur.Next(ctx) {
+		e := entry{}
+		if err := cur.Decode(&e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, cur.Err()
+}
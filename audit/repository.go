@@ -0,0 +1,73 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionDelete    = "delete"
+	ActionDeleteAll = "delete_all"
+	ActionRevert    = "revert"
+	ActionRestore   = "restore"
+	ActionPurge     = "purge"
+	ActionImport    = "import"
+)
+
+// entry is one change recorded in the audit log. Entries are never changed or deleted.
+type entry struct {
+	// ID grows with Time, so entries are ordered by it.
+	ID       string    `bson:"_id" json:"id" example:"62d6a5c1f1e4a3b2c1d0e9f8"`
+	Time     time.Time `bson:"time" json:"time"`
+	Actor    string    `bson:"actor" json:"actor" example:"admin"`
+	Action   string    `bson:"action" json:"action" enums:"create,update,delete,delete_all,revert,restore,purge,import"`
+	Resource string    `bson:"resource" json:"resource" example:"pokemons/25"`
+	// Before and After are the JSON of the resource as the API returns it, null when it didn't or doesn't exist.
+	Before    json.RawMessage `bson:"before" json:"before" swaggertype:"object"`
+	After     json.RawMessage `bson:"after" json:"after" swaggertype:"object"`
+	ClientIP  string          `bson:"client_ip" json:"client_ip" example:"127.0.0.1"`
+	RequestID string          `bson:"request_id" json:"request_id"`
+}
+
+// Filter selects audit entries. Zero fields don't filter.
+type Filter struct {
+	Actor string
+	// Resource matches the resource itself and, like "pokemons", everything below it.
+	Resource string
+	// From and To bound Time, From inclusive and To exclusive.
+	From time.Time
+	To   time.Time
+	// Limit caps the number of entries returned, newest first, after skipping Offset of them.
+	Limit  int
+	Offset int
+}
+
+// matches reports whether e is selected by f, ignoring Limit and Offset.
+func (f Filter) matches(e entry) bool {
+	if f.Actor != "" && e.Actor != f.Actor {
+		return false
+	}
+	if f.Resource != "" && e.Resource != f.Resource && !strings.HasPrefix(e.Resource, f.Resource+"/") {
+		return false
+	}
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.Time.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// Repository is the append-only storage of the audit log.
+type Repository interface {
+	// Append stores a new entry.
+	Append(ctx context.Context, e entry) error
+	// List returns the entries selected by f, newest first.
+	List(ctx context.Context, f Filter) ([]entry, error)
+}
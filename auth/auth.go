@@ -0,0 +1,135 @@
+// Package auth authenticates the clients of the API and keeps the authenticated user on the gin.Context.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrInvalidCredentials is returned by an Authenticator when the login is unknown or the password is wrong.
+var ErrInvalidCredentials = errors.New("invalid login or password")
+
+// ErrInvalidToken is returned by a TokenVerifier for tokens that are malformed, expired or revoked.
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Principal is the user a request was authenticated as.
+type Principal struct {
+	Login string `json:"login"`
+	Role  string `json:"role"`
+	// Scopes limit the permissions of the role when the request was authenticated with an API key.
+	// They are nil for the other ways of authentication.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// Authenticator verifies the credentials sent by clients.
+type Authenticator interface {
+	// Authenticate returns the user with the given login and password or ErrInvalidCredentials.
+	Authenticate(ctx context.Context, login, password string) (Principal, error)
+}
+
+// TokenVerifier verifies the bearer tokens or API keys sent by clients.
+type TokenVerifier interface {
+	// VerifyToken returns the user the token was issued to or ErrInvalidToken.
+	VerifyToken(ctx context.Context, token string) (Principal, error)
+}
+
+// UserLookup finds users by login, for example to issue them new tokens.
+type UserLookup interface {
+	// LookupUser returns the user with the given login or ErrInvalidCredentials when there is none.
+	LookupUser(ctx context.Context, login string) (Principal, error)
+}
+
+// principalKey is the gin.Context key under which Middleware stores the Principal.
+const principalKey = "auth.principal"
+
+// APIKeyHeader is the header carrying API keys.
+const APIKeyHeader = "X-API-Key"
+
+// Middleware returns a handler that authenticates requests with, in this order, an API key in the X-API-Key header
+// accepted by apiKeys, a bearer token accepted by tokens or HTTP Basic credentials accepted by a. The verifiers
+// may be nil to disable their scheme. The authenticated user is available to the next handlers through CurrentUser.
+func Middleware(a Authenticator, tokens, apiKeys TokenVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var p Principal
+		var err error
+		bearer := false
+		if key := c.GetHeader(APIKeyHeader); key != "" && apiKeys != nil {
+			p, err = apiKeys.VerifyToken(c.Request.Context(), key)
+		} else if token, ok := BearerToken(c.Request); ok && tokens != nil {
+			p, err = tokens.VerifyToken(c.Request.Context(), token)
+			bearer = true
+		} else if login, password, ok := c.Request.BasicAuth(); ok {
+			p, err = a.Authenticate(WithClientIP(c.Request.Context(), c.ClientIP()), login, password)
+		} else {
+			unauthorized(c, "authentication required")
+			return
+		}
+
+		var locked *LockedOutError
+		switch {
+		case errors.As(err, &locked):
+			SetRetryAfter(c, err)
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"message": err.Error()})
+			return
+		case errors.Is(err, ErrInvalidCredentials):
+			SetRetryAfter(c, err)
+			unauthorized(c, "invalid login or password")
+			return
+		case errors.Is(err, ErrInvalidToken):
+			if bearer {
+				c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": err.Error()})
+			return
+		case err != nil:
+			fmt.Println(err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+			return
+		}
+
+		c.Set(principalKey, p)
+		c.Set(gin.AuthUserKey, p.Login)
+		c.Next()
+	}
+}
+
+// Optional returns a handler running the authentication handler mw only for requests which carry credentials,
+// so that public routes know who is calling them when they can.
+func Optional(mw gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" && c.GetHeader(APIKeyHeader) == "" {
+			c.Next()
+			return
+		}
+		mw(c)
+	}
+}
+
+// BearerToken returns the token of an "Authorization: Bearer" header.
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// CurrentUser returns the user authenticated by Middleware. It reports false on routes without authentication.
+func CurrentUser(c *gin.Context) (Principal, bool) {
+	v, ok := c.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	p, ok := v.(Principal)
+	return p, ok
+}
+
+func unauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Basic realm="Authorization Required"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": message})
+}
@@ -0,0 +1,309 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LockedOutError is returned by a guarded Authenticator while a login or client IP has to wait
+// after failed attempts. The credentials are not checked at all then.
+type LockedOutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedOutError) Error() string {
+	return fmt.Sprintf("too many failed attempts, retry in %d seconds", seconds(e.RetryAfter))
+}
+
+// seconds rounds d up to whole seconds, as sent in Retry-After.
+func seconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// LockoutPolicy tells how long failed attempts block further ones.
+type LockoutPolicy struct {
+	// Threshold is the number of consecutive failures for a login after which it is locked for Duration.
+	// Before that every failure doubles the wait, starting from Backoff.
+	Threshold int
+	// IPThreshold is the number of failures for a client IP after which it is locked for Duration.
+	// A client IP may be shared by many users, so its failures don't make it wait before that.
+	IPThreshold int
+	Backoff     time.Duration
+	Duration    time.Duration
+}
+
+// sweepInterval is how often the attempts which no longer block anything are dropped.
+const sweepInterval = time.Minute
+
+// attempts are the recent failures of a login or client IP.
+type attempts struct {
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	// BlockedUntil is when the next attempt is accepted.
+	BlockedUntil time.Time `json:"blocked_until"`
+}
+
+// AuditLog records the changes made by requests. audit.Log implements it; the audit package depends on
+// this one, so it can't be named here.
+type AuditLog interface {
+	Record(c *gin.Context, action, resource string, before, after interface{})
+}
+
+// Lockout tracks failed logins per login and per client IP in process memory.
+type Lockout struct {
+	policy   LockoutPolicy
+	auditLog AuditLog
+
+	mu     sync.Mutex
+	logins map[string]*attempts
+	ips    map[string]*attempts
+	// lastSweep is when expired attempts were last dropped.
+	lastSweep time.Time
+}
+
+// NewLockout returns a Lockout applying policy which records the lockouts cleared by ClearLockout in auditLog.
+func NewLockout(policy LockoutPolicy, auditLog AuditLog) *Lockout {
+	return &Lockout{
+		policy:   policy,
+		auditLog: auditLog,
+		logins:   make(map[string]*attempts),
+		ips:      make(map[string]*attempts),
+	}
+}
+
+// expired reports whether a no longer counts: it is older than the lockout duration and blocks nothing.
+func (l *Lockout) expired(a *attempts, now time.Time) bool {
+	return now.Sub(a.LastFailure) > l.policy.Duration && now.After(a.BlockedUntil)
+}
+
+// current returns the attempts of key, dropping them once they are expired. The caller must hold l.mu.
+func (l *Lockout) current(m map[string]*attempts, key string, now time.Time) *attempts {
+	a, ok := m[key]
+	if ok && l.expired(a, now) {
+		delete(m, key)
+		ok = false
+	}
+	if !ok {
+		return nil
+	}
+	return a
+}
+
+// sweep drops the expired attempts of every login and client IP, so that clients failing once with
+// many logins don't fill the memory. It runs at most once per sweepInterval. The caller must hold l.mu.
+func (l *Lockout) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for _, m := range []map[string]*attempts{l.logins, l.ips} {
+		for key, a := range m {
+			if l.expired(a, now) {
+				delete(m, key)
+			}
+		}
+	}
+}
+
+// wait returns how long the caller has to wait after now before trying login from ip again.
+func (l *Lockout) wait(login, ip string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var wait time.Duration
+	for _, a := range []*attempts{l.current(l.logins, login, now), l.current(l.ips, ip, now)} {
+		if a != nil && a.BlockedUntil.Sub(now) > wait {
+			wait = a.BlockedUntil.Sub(now)
+		}
+	}
+	return wait
+}
+
+// fail records an attempt failed at now and returns how long the caller has to wait before the next one.
+func (l *Lockout) fail(login, ip string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	loginWait := l.record(l.logins, login, l.policy.Threshold, l.policy.Backoff, now)
+	ipWait := l.record(l.ips, ip, l.policy.IPThreshold, 0, now)
+	if ipWait > loginWait {
+		return ipWait
+	}
+	return loginWait
+}
+
+// record adds a failure to the attempts of key, which is locked once it reaches threshold. Before that
+// every failure doubles the wait, starting from backoff; with a zero backoff there is no wait at all.
+// The caller must hold l.mu.
+func (l *Lockout) record(m map[string]*attempts, key string, threshold int, backoff time.Duration, now time.Time) time.Duration {
+	a := l.current(m, key, now)
+	if a == nil {
+		a = &attempts{}
+		m[key] = a
+	}
+	a.Failures++
+	a.LastFailure = now
+
+	wait := l.policy.Duration
+	if a.Failures < threshold {
+		// backoff << (Failures-1) without overflowing
+		doubled := float64(backoff) * math.Pow(2, float64(a.Failures-1))
+		if doubled < float64(wait) {
+			wait = time.Duration(doubled)
+		}
+	}
+	a.BlockedUntil = now.Add(wait)
+	return wait
+}
+
+// Forget clears the lockout of login once it has logged in. Requests clearing it use Clear, which audits it.
+func (l *Lockout) Forget(login string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.logins, login)
+}
+
+// Guard returns an Authenticator that refuses with a *LockedOutError the attempts a login or client IP
+// makes too soon after failures, without checking them with a. The client IP is taken from the context,
+// see WithClientIP.
+func Guard(a Authenticator, l *Lockout) Authenticator {
+	return &guard{next: a, lockout: l}
+}
+
+type guard struct {
+	next    Authenticator
+	lockout *Lockout
+}
+
+func (g *guard) Authenticate(ctx context.Context, login, password string) (Principal, error) {
+	ip := ClientIP(ctx)
+	if wait := g.lockout.wait(login, ip, time.Now()); wait > 0 {
+		return Principal{}, &LockedOutError{RetryAfter: wait}
+	}
+
+	p, err := g.next.Authenticate(ctx, login, password)
+	if errors.Is(err, ErrInvalidCredentials) {
+		return Principal{}, &invalidCredentialsError{retryAfter: g.lockout.fail(login, ip, time.Now())}
+	}
+	if err == nil {
+		// the failures of the client IP are kept, so that logging in to one account
+		// doesn't allow guessing the passwords of others
+		g.lockout.Forget(login)
+	}
+	return p, err
+}
+
+// invalidCredentialsError is ErrInvalidCredentials telling how long to wait before the next attempt.
+type invalidCredentialsError struct {
+	retryAfter time.Duration
+}
+
+func (e *invalidCredentialsError) Error() string { return ErrInvalidCredentials.Error() }
+
+func (e *invalidCredentialsError) Is(target error) bool { return target == ErrInvalidCredentials }
+
+// RetryAfter returns how long a client has to wait after err, or 0 when it can retry at once.
+func RetryAfter(err error) time.Duration {
+	var locked *LockedOutError
+	if errors.As(err, &locked) {
+		return locked.RetryAfter
+	}
+	var invalid *invalidCredentialsError
+	if errors.As(err, &invalid) {
+		return invalid.retryAfter
+	}
+	return 0
+}
+
+// SetRetryAfter sets the Retry-After header, in whole seconds rounded up, when err asks the client to wait.
+func SetRetryAfter(c *gin.Context, err error) {
+	if wait := RetryAfter(err); wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(seconds(wait)))
+	}
+}
+
+type clientIPKey struct{}
+
+// WithClientIP returns a copy of ctx carrying the IP of the client, used by Guard.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIP returns the IP stored by WithClientIP or an empty string.
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// lockoutStatus is returned by GetLockout.
+type lockoutStatus struct {
+	Login string `json:"login" example:"admin"`
+	attempts
+	Locked bool `json:"locked"`
+	// RetryAfter is in seconds.
+	RetryAfter int `json:"retry_after"`
+}
+
+// GetLockout godoc
+// @title        Get Lockout
+// @summary      Inspect the failed logins of a user
+// @description  Get the recent failed login attempts of a user and whether the user has to wait before the next attempt.
+// @produce      json
+// @param        id  path  string  true  "login of the user"
+// @success      200 {object} lockoutStatus
+// @router       /users/{id}/lockout [get]
+func (l *Lockout) GetLockout(c *gin.Context) {
+	status, _ := l.status(c.Param("id"))
+	c.IndentedJSON(http.StatusOK, status)
+}
+
+// status returns the lockout status of login and whether it has recent failures.
+func (l *Lockout) status(login string) (lockoutStatus, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	status := lockoutStatus{Login: login}
+	a := l.current(l.logins, login, now)
+	if a != nil {
+		status.attempts = *a
+		if wait := a.BlockedUntil.Sub(now); wait > 0 {
+			status.Locked = true
+			status.RetryAfter = seconds(wait)
+		}
+	}
+	return status, a != nil
+}
+
+// ClearLockout godoc
+// @title        Clear Lockout
+// @summary      Clear the failed logins of a user
+// @description  Forget the failed login attempts of a user, so the user can log in again at once. Lockouts of client IPs are kept.
+// @produce      json
+// @param        id  path  string  true  "login of the user"
+// @success      200 {string} string "lockout was cleared"
+// @router       /users/{id}/lockout [delete]
+func (l *Lockout) ClearLockout(c *gin.Context) {
+	l.Clear(c, c.Param("id"))
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "lockout was cleared"})
+}
+
+// Clear forgets the failed attempts of login on behalf of the request of c and records it in the audit log
+// when there were any.
+func (l *Lockout) Clear(c *gin.Context, login string) {
+	before, failed := l.status(login)
+	l.Forget(login)
+	if failed {
+		// the action is audit.ActionDelete
+		l.auditLog.Record(c, "delete", "users/"+login+"/lockout", before, nil)
+	}
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var testPolicy = LockoutPolicy{Threshold: 5, IPThreshold: 50, Backoff: time.Second, Duration: 15 * time.Minute}
+
+func TestLockoutFail(t *testing.T) {
+	tests := []struct {
+		name string
+		// failures are the failed logins, made one second apart from the same client IP.
+		failures []string
+		// wait is what the last failure returns.
+		wait time.Duration
+	}{
+		{"first failure", []string{"ash"}, time.Second},
+		{"second failure", []string{"ash", "ash"}, 2 * time.Second},
+		{"fourth failure", []string{"ash", "ash", "ash", "ash"}, 8 * time.Second},
+		{"login locked", []string{"ash", "ash", "ash", "ash", "ash"}, testPolicy.Duration},
+		{"other login", []string{"ash", "ash", "ash", "misty"}, time.Second},
+		{"many logins below the ip threshold", logins(49), time.Second},
+		{"ip locked", logins(50), testPolicy.Duration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLockout(testPolicy, nil)
+			now := time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+			var wait time.Duration
+			for _, login := range tt.failures {
+				now = now.Add(time.Second)
+				wait = l.fail(login, "10.0.0.1", now)
+			}
+			if wait != tt.wait {
+				t.Errorf("wait = %v, want %v", wait, tt.wait)
+			}
+		})
+	}
+}
+
+// logins returns n distinct logins.
+func logins(n int) []string {
+	l := make([]string, n)
+	for i := range l {
+		l[i] = fmt.Sprintf("user%d", i)
+	}
+	return l
+}
+
+func TestLockoutWait(t *testing.T) {
+	start := time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		login string
+		ip    string
+		after time.Duration
+		want  time.Duration
+	}{
+		{"same login", "ash", "10.0.0.2", 0, time.Second},
+		{"same ip, other login", "misty", "10.0.0.1", 0, 0},
+		{"after the backoff", "ash", "10.0.0.1", time.Second, 0},
+		{"other client", "misty", "10.0.0.2", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLockout(testPolicy, nil)
+			l.fail("ash", "10.0.0.1", start)
+			if got := l.wait(tt.login, tt.ip, start.Add(tt.after)); got != tt.want {
+				t.Errorf("wait = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockoutSweep(t *testing.T) {
+	l := NewLockout(testPolicy, nil)
+	start := time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+	for i, login := range logins(10) {
+		l.fail(login, fmt.Sprintf("10.0.0.%d", i), start)
+	}
+	l.fail("ash", "10.0.1.1", start.Add(testPolicy.Duration+time.Minute))
+
+	if len(l.logins) != 1 || len(l.ips) != 1 {
+		t.Errorf("%d logins and %d ips are kept, want only those of the last failure", len(l.logins), len(l.ips))
+	}
+}
+
+// recordedChanges is an AuditLog keeping the resources of the changes it is told about.
+type recordedChanges []string
+
+func (r *recordedChanges) Record(c *gin.Context, action, resource string, before, after interface{}) {
+	*r = append(*r, action+" "+resource)
+}
+
+func TestLockoutClear(t *testing.T) {
+	tests := []struct {
+		name    string
+		failed  bool
+		audited []string
+	}{
+		{"recent failures", true, []string{"delete users/ash/lockout"}},
+		{"no failures", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var auditLog recordedChanges
+			l := NewLockout(testPolicy, &auditLog)
+			if tt.failed {
+				l.fail("ash", "10.0.0.1", time.Now())
+			}
+
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			l.Clear(c, "ash")
+			if wait := l.wait("ash", "10.0.0.2", time.Now()); wait != 0 {
+				t.Errorf("the login still waits %v", wait)
+			}
+			if !reflect.DeepEqual([]string(auditLog), tt.audited) {
+				t.Errorf("audited %v, want %v", auditLog, tt.audited)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Roles a user can have.
+const (
+	// Viewer can read everything public and nothing more.
+	Viewer = "viewer"
+	// Editor can also change pokemons and evolution chains.
+	Editor = "editor"
+	// Admin can do anything, including managing users.
+	Admin = "admin"
+)
+
+// Permission is what a user needs to call a protected route.
+type Permission string
+
+// Permissions granted through roles.
+const (
+	// WritePokemons allows creating, updating and deleting single pokemons and evolution chains.
+	WritePokemons Permission = "pokemons:write"
+	// DeleteAllPokemons allows wiping the whole pokemon collection.
+	DeleteAllPokemons Permission = "pokemons:delete_all"
+	// PurgePokemons allows deleting pokemons in the trash for good.
+	PurgePokemons Permission = "pokemons:purge"
+	// ManageUsers allows every operation on /users.
+	ManageUsers Permission = "users:manage"
+	// ReadAudit allows reading the audit log.
+	ReadAudit Permission = "audit:read"
+	// ManageAPIKeys allows users to manage their own API keys.
+	ManageAPIKeys Permission = "apikeys:manage"
+)
+
+// rolePermissions is the permission matrix: the permissions of each role.
+var rolePermissions = map[string][]Permission{
+	Viewer: {ManageAPIKeys},
+	Editor: {ManageAPIKeys, WritePokemons},
+	Admin:  {ManageAPIKeys, WritePokemons, DeleteAllPokemons, PurgePokemons, ManageUsers, ReadAudit},
+}
+
+// Scopes limit what a user can do with an API key.
+const (
+	// ScopeReadPokemons allows only reading, which needs no permission.
+	ScopeReadPokemons = "pokemons:read"
+	// ScopeWritePokemons allows changing pokemons and evolution chains.
+	ScopeWritePokemons = "pokemons:write"
+	// ScopeManageUsers allows managing users.
+	ScopeManageUsers = "users:manage"
+)
+
+// scopePermissions holds the permissions that each scope lets through. No scope lets API keys manage API keys.
+var scopePermissions = map[string][]Permission{
+	ScopeReadPokemons:  {},
+	ScopeWritePokemons: {WritePokemons, DeleteAllPokemons, PurgePokemons},
+	ScopeManageUsers:   {ManageUsers},
+}
+
+// Scopes returns the known scopes.
+func Scopes() []string {
+	return []string{ScopeReadPokemons, ScopeWritePokemons, ScopeManageUsers}
+}
+
+// IsScope reports whether scope is one of the known scopes.
+func IsScope(scope string) bool {
+	_, ok := scopePermissions[scope]
+	return ok
+}
+
+// Roles returns the known roles.
+func Roles() []string {
+	return []string{Viewer, Editor, Admin}
+}
+
+// IsRole reports whether role is one of the known roles.
+func IsRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Can reports whether the role of p grants perm and, when p is limited to scopes, one of them lets it through.
+// Unknown roles and scopes grant nothing.
+func (p Principal) Can(perm Permission) bool {
+	if !contains(rolePermissions[p.Role], perm) {
+		return false
+	}
+	if p.Scopes == nil {
+		return true
+	}
+	for _, scope := range p.Scopes {
+		if contains(scopePermissions[scope], perm) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(permissions []Permission, perm Permission) bool {
+	for _, p := range permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Require returns a handler that lets through only users having perm.
+// It must come after Middleware: requests without a user get 401, users without perm get 403.
+func Require(perm Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := CurrentUser(c)
+		if !ok {
+			unauthorized(c, "authentication required")
+			return
+		}
+		if !p.Can(perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "you don't have the " + string(perm) + " permission"})
+			return
+		}
+		c.Next()
+	}
+}
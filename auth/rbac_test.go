@@ -0,0 +1,37 @@
+package auth
+
+import "testing"
+
+func TestPrincipalCan(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		perm      Permission
+		want      bool
+	}{
+		{"viewer manages api keys", Principal{Role: Viewer}, ManageAPIKeys, true},
+		{"viewer writes pokemons", Principal{Role: Viewer}, WritePokemons, false},
+		{"editor writes pokemons", Principal{Role: Editor}, WritePokemons, true},
+		{"editor deletes all pokemons", Principal{Role: Editor}, DeleteAllPokemons, false},
+		{"editor manages users", Principal{Role: Editor}, ManageUsers, false},
+		{"admin deletes all pokemons", Principal{Role: Admin}, DeleteAllPokemons, true},
+		{"admin purges pokemons", Principal{Role: Admin}, PurgePokemons, true},
+		{"admin reads the audit log", Principal{Role: Admin}, ReadAudit, true},
+		{"unknown role", Principal{Role: "superuser"}, ManageAPIKeys, false},
+		{"no role", Principal{}, ManageAPIKeys, false},
+		{"scope within the role", Principal{Role: Editor, Scopes: []string{ScopeWritePokemons}}, WritePokemons, true},
+		{"scope beyond the role", Principal{Role: Viewer, Scopes: []string{ScopeWritePokemons}}, WritePokemons, false},
+		{"read scope", Principal{Role: Admin, Scopes: []string{ScopeReadPokemons}}, WritePokemons, false},
+		{"one of several scopes", Principal{Role: Admin, Scopes: []string{ScopeReadPokemons, ScopeManageUsers}}, ManageUsers, true},
+		{"no scope manages api keys", Principal{Role: Admin, Scopes: []string{ScopeWritePokemons, ScopeManageUsers}}, ManageAPIKeys, false},
+		{"empty scopes", Principal{Role: Admin, Scopes: []string{}}, WritePokemons, false},
+		{"unknown scope", Principal{Role: Admin, Scopes: []string{"everything"}}, WritePokemons, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.Can(tt.perm); got != tt.want {
+				t.Errorf("%+v.Can(%s) = %v, want %v", tt.principal, tt.perm, got, tt.want)
+			}
+		})
+	}
+}
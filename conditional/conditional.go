@@ -0,0 +1,88 @@
+// Package conditional implements conditional requests (RFC 7232) for resources that keep a version counter:
+// the version is the entity tag and the time of the last change is sent as Last-Modified.
+package conditional
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag returns the entity tag of a resource at the given version.
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// SetHeaders sets the ETag and Last-Modified headers of a resource. Resources stored before versions
+// were counted have neither.
+func SetHeaders(c *gin.Context, version int64, modified time.Time) {
+	if version > 0 {
+		c.Header("ETag", ETag(version))
+	}
+	if !modified.IsZero() {
+		c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// NotModified sets the headers of a resource read by a GET request and checks its If-None-Match header or,
+// when there is none, its If-Modified-Since header. When they show that the client already has the current
+// representation it responds 304 Not Modified and returns true.
+func NotModified(c *gin.Context, version int64, modified time.Time) bool {
+	SetHeaders(c, version, modified)
+	if header := c.GetHeader("If-None-Match"); header != "" {
+		if version == 0 || !matches(header, version, true) {
+			return false
+		}
+	} else {
+		since, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+		// Last-Modified is sent in whole seconds
+		if err != nil || modified.IsZero() || modified.Truncate(time.Second).After(since) {
+			return false
+		}
+	}
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// Match checks the If-Match header of a request changing a resource, which is at the given version
+// when it exists. It returns the version the change must be applied to, 0 when the request has no
+// If-Match header. When it returns false the header doesn't match and the 412 response has already been written.
+func Match(c *gin.Context, version int64, exists bool) (int64, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return 0, true
+	}
+	if exists && (strings.TrimSpace(header) == "*" || matches(header, version, false)) {
+		return version, true
+	}
+	Failed(c)
+	return 0, false
+}
+
+// Failed responds 412 Precondition Failed, for changes whose resource is no longer at the version
+// the client asked for.
+func Failed(c *gin.Context) {
+	c.IndentedJSON(http.StatusPreconditionFailed, gin.H{"message": "the resource has been changed since it was read"})
+}
+
+// matches reports whether a list of entity tags, or *, contains the tag of version. Weak tags only
+// match when weak is set, as If-Match uses the strong comparison and If-None-Match the weak one.
+func matches(header string, version int64, weak bool) bool {
+	etag := ETag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
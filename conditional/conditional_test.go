@@ -0,0 +1,95 @@
+package conditional
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newContext(header http.Header) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Header = header
+	return c, w
+}
+
+func TestNotModified(t *testing.T) {
+	modified := time.Date(2022, 7, 1, 12, 0, 0, 500, time.UTC)
+	tests := []struct {
+		name    string
+		header  http.Header
+		version int64
+		want    bool
+	}{
+		{"no condition", http.Header{}, 3, false},
+		{"matching tag", http.Header{"If-None-Match": {`"3"`}}, 3, true},
+		{"matching weak tag", http.Header{"If-None-Match": {`W/"3"`}}, 3, true},
+		{"tag in a list", http.Header{"If-None-Match": {`"1", "3"`}}, 3, true},
+		{"star", http.Header{"If-None-Match": {"*"}}, 3, true},
+		{"other tag", http.Header{"If-None-Match": {`"2"`}}, 3, false},
+		{"unversioned resource", http.Header{"If-None-Match": {"*"}}, 0, false},
+		{"not modified since", http.Header{"If-Modified-Since": {modified.Format(http.TimeFormat)}}, 3, true},
+		{"modified since", http.Header{"If-Modified-Since": {modified.Add(-time.Second).Format(http.TimeFormat)}}, 3, false},
+		{"invalid date", http.Header{"If-Modified-Since": {"yesterday"}}, 3, false},
+		{"tag takes precedence", http.Header{"If-None-Match": {`"2"`}, "If-Modified-Since": {modified.Format(http.TimeFormat)}}, 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newContext(tt.header)
+			if got := NotModified(c, tt.version, modified); got != tt.want {
+				t.Fatalf("NotModified() = %v, want %v", got, tt.want)
+			}
+			c.Writer.WriteHeaderNow()
+			if tt.want && w.Code != http.StatusNotModified {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusNotModified)
+			}
+			if tt.version > 0 && w.Header().Get("ETag") != ETag(tt.version) {
+				t.Errorf("ETag = %s, want %s", w.Header().Get("ETag"), ETag(tt.version))
+			}
+			if w.Header().Get("Last-Modified") != modified.Format(http.TimeFormat) {
+				t.Errorf("Last-Modified = %s", w.Header().Get("Last-Modified"))
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		exists  bool
+		version int64
+		ok      bool
+	}{
+		{"no condition", "", true, 0, true},
+		{"no condition on a missing resource", "", false, 0, true},
+		{"matching tag", `"3"`, true, 3, true},
+		{"tag in a list", `"1", "3"`, true, 3, true},
+		{"star", "*", true, 3, true},
+		{"star on a missing resource", "*", false, 0, false},
+		{"other tag", `"2"`, true, 0, false},
+		{"weak tag", `W/"3"`, true, 0, false},
+		{"tag of a missing resource", `"3"`, false, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.ifMatch != "" {
+				header.Set("If-Match", tt.ifMatch)
+			}
+			c, w := newContext(header)
+			version, ok := Match(c, 3, tt.exists)
+			if ok != tt.ok || version != tt.version {
+				t.Fatalf("Match() = %d, %v, want %d, %v", version, ok, tt.version, tt.ok)
+			}
+			if !ok && w.Code != http.StatusPreconditionFailed {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusPreconditionFailed)
+			}
+		})
+	}
+}
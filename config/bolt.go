@@ -0,0 +1,66 @@
+package config
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// BoltFormatVersion is the version of the on-disk data layout written by the bolt backend.
+// Bump it whenever the layout of the buckets changes in an incompatible way.
+//
+// Version 2 added the slugs, types, stats, trash and version fields of pokemons, the roles and password
+// hashes of users and the buckets of tokens, API keys, history and the audit log. Files at version 1 are
+// upgraded when they are opened: the new buckets are created empty, the repositories fill in missing
+// slugs, roles and hashes at startup, and missing fields otherwise mean unknown.
+const BoltFormatVersion = 2
+
+// boltOldestFormatVersion is the oldest data layout OpenBoltDB upgrades.
+const boltOldestFormatVersion = 1
+
+var (
+	boltMetaBucket = []byte("meta")
+	boltVersionKey = []byte("format_version")
+)
+
+// OpenBoltDB opens (or creates) the bolt database file at path and checks its data format version,
+// upgrading files written by older versions.
+// Every write to the file is done in a bolt transaction which is fsynced on commit,
+// so a crash leaves the file either before or after the write.
+func OpenBoltDB(path string) (*bbolt.DB, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(boltMetaBucket)
+		if err != nil {
+			return err
+		}
+
+		stored := meta.Get(boltVersionKey)
+		if stored != nil {
+			if len(stored) != 8 {
+				return fmt.Errorf("%s: unsupported data format version %x, want %d", path, stored, BoltFormatVersion)
+			}
+			v := binary.BigEndian.Uint64(stored)
+			if v == BoltFormatVersion {
+				return nil
+			}
+			if v < boltOldestFormatVersion || v > BoltFormatVersion {
+				return fmt.Errorf("%s: unsupported data format version %d, want %d", path, v, BoltFormatVersion)
+			}
+		}
+		version := make([]byte, 8)
+		binary.BigEndian.PutUint64(version, BoltFormatVersion)
+		return meta.Put(boltVersionKey, version)
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+
+	"go.etcd.io/bbolt"
+)
+
+func TestOpenBoltDB(t *testing.T) {
+	version := func(v uint64) []byte {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, v)
+		return b
+	}
+	tests := []struct {
+		name   string
+		stored []byte
+		ok     bool
+	}{
+		{"new file", nil, true},
+		{"current version", version(BoltFormatVersion), true},
+		{"upgraded version", version(boltOldestFormatVersion), true},
+		{"newer version", version(BoltFormatVersion + 1), false},
+		{"zero version", version(0), false},
+		{"malformed version", []byte("2"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.db")
+			if tt.stored != nil {
+				db, err := bbolt.Open(path, 0600, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = db.Update(func(tx *bbolt.Tx) error {
+					meta, err := tx.CreateBucket(boltMetaBucket)
+					if err != nil {
+						return err
+					}
+					return meta.Put(boltVersionKey, tt.stored)
+				})
+				db.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			db, err := OpenBoltDB(path)
+			if (err == nil) != tt.ok {
+				t.Fatalf("OpenBoltDB() error = %v, want ok %v", err, tt.ok)
+			}
+			if err != nil {
+				return
+			}
+			defer db.Close()
+			err = db.View(func(tx *bbolt.Tx) error {
+				stored := tx.Bucket(boltMetaBucket).Get(boltVersionKey)
+				if got := binary.BigEndian.Uint64(stored); got != BoltFormatVersion {
+					t.Errorf("stored version = %d, want %d", got, BoltFormatVersion)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -8,23 +8,94 @@ import (
 	"time"
 
 	"github.com/BurntSushi/toml"
+	"golang.org/x/crypto/bcrypt"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Storage backends that can be selected with the StorageBackend key.
+const (
+	MongoBackend  = "mongo"
+	MemoryBackend = "memory"
+	BoltBackend   = "bolt"
+)
+
 type Config struct {
+	StorageBackend string
+	BoltPath       string
 	DatabaseURL    string
 	DatabaseName   string
 	CollectionName string
 	UserCollecName string
-	URL            string
-	UserName       string
-	Password       string
-	UserName1      string
-	Password1      string
+	// EvolutionCollecName defaults to "evolutions".
+	EvolutionCollecName string
+	// MongoMaxPoolSize limits the connections kept open by the shared MongoDB client.
+	MongoMaxPoolSize uint64
+	// MongoConnectTimeout and MongoServerSelectionTimeout are in seconds.
+	MongoConnectTimeout         int
+	MongoServerSelectionTimeout int
+	URL                         string
+	// BcryptCost is the cost of the password hashes, 0 keeps the bcrypt default.
+	BcryptCost int
+	// AuthCacheTTL is how many seconds verified credentials are remembered, 0 disables the cache.
+	AuthCacheTTL int
+	// LockoutThreshold and IPLockoutThreshold are the consecutive failed logins after which a login or a client IP
+	// is locked for LockoutDuration seconds. Before that every failure of a login doubles a wait starting at
+	// LoginBackoff seconds, while a client IP doesn't wait.
+	LockoutThreshold   int
+	IPLockoutThreshold int
+	LockoutDuration    int
+	LoginBackoff       int
+	// RateLimits are the quotas of the routes, keyed by method and route as registered, like "GET /pokemons/:id".
+	// The DefaultRateLimit entry applies to the routes without their own quota, without it they are not limited.
+	RateLimits map[string]RateLimit
+	// JWTKeys sign and verify the access tokens. The first key signs new tokens, the others are
+	// still accepted so that a key can be rotated out without logging everybody out.
+	JWTKeys []JWTKey
+	// AccessTokenTTL and RefreshTokenTTL are in seconds and default to 15 minutes and 30 days.
+	AccessTokenTTL  int
+	RefreshTokenTTL int
+	// TokenCollecName and RevokedTokenCollecName default to "refresh_tokens" and "revoked_tokens".
+	TokenCollecName        string
+	RevokedTokenCollecName string
+	// APIKeyCollecName defaults to "api_keys".
+	APIKeyCollecName string
+	// AuditCollecName defaults to "audit".
+	AuditCollecName string
+	// HistoryCollecName defaults to "pokemon_history".
+	HistoryCollecName string
+	// TrashRetention is how many seconds deleted pokemons stay in the trash before they are purged,
+	// checked every TrashPurgeInterval seconds. They default to 30 days and an hour.
+	TrashRetention     int
+	TrashPurgeInterval int
+	// UserName and Password are the admin account created when there is no admin in the users collection.
+	UserName string
+	Password string
+}
+
+// DefaultRateLimit is the key of RateLimits applying to the routes not listed there.
+const DefaultRateLimit = "default"
+
+// RateLimit lets every client make PerMinute requests a minute to a route, and up to Burst of them at once.
+// Burst defaults to PerMinute.
+type RateLimit struct {
+	PerMinute int
+	Burst     int
 }
 
+// JWTKey is an HMAC key for the access tokens. ID is sent in the "kid" header of the tokens it signs.
+type JWTKey struct {
+	ID     string
+	Secret string
+}
+
+// minJWTSecretLen is the shortest secret accepted for HS256.
+const minJWTSecretLen = 32
+
+// exampleJWTSecret is the secret of properties.example.ini. It is public, so it is refused.
+const exampleJWTSecret = "change me to a long random string of at least 32 bytes"
+
 var Conf Config
 
 func ReadConfig() Config {
@@ -37,20 +108,104 @@ func ReadConfig() Config {
 	if _, err := toml.DecodeFile(configFile, &Conf); err != nil {
 		log.Fatal(err)
 	}
+	if Conf.StorageBackend == "" {
+		Conf.StorageBackend = MongoBackend
+	}
+	if Conf.BcryptCost != 0 && (Conf.BcryptCost < bcrypt.MinCost || Conf.BcryptCost > bcrypt.MaxCost) {
+		log.Fatalf("BcryptCost must be from %d to %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	if Conf.EvolutionCollecName == "" {
+		Conf.EvolutionCollecName = "evolutions"
+	}
+	if Conf.TokenCollecName == "" {
+		Conf.TokenCollecName = "refresh_tokens"
+	}
+	if Conf.RevokedTokenCollecName == "" {
+		Conf.RevokedTokenCollecName = "revoked_tokens"
+	}
+	if Conf.APIKeyCollecName == "" {
+		Conf.APIKeyCollecName = "api_keys"
+	}
+	if Conf.AuditCollecName == "" {
+		Conf.AuditCollecName = "audit"
+	}
+	if Conf.HistoryCollecName == "" {
+		Conf.HistoryCollecName = "pokemon_history"
+	}
+	if Conf.TrashRetention == 0 {
+		Conf.TrashRetention = 30 * 24 * 60 * 60
+	}
+	if Conf.TrashPurgeInterval == 0 {
+		Conf.TrashPurgeInterval = 60 * 60
+	}
+	if Conf.LockoutThreshold == 0 {
+		Conf.LockoutThreshold = 5
+	}
+	if Conf.IPLockoutThreshold == 0 {
+		Conf.IPLockoutThreshold = 50
+	}
+	if Conf.LockoutDuration == 0 {
+		Conf.LockoutDuration = 15 * 60
+	}
+	if Conf.LoginBackoff == 0 {
+		Conf.LoginBackoff = 1
+	}
+	if Conf.AccessTokenTTL == 0 {
+		Conf.AccessTokenTTL = 15 * 60
+	}
+	if Conf.RefreshTokenTTL == 0 {
+		Conf.RefreshTokenTTL = 30 * 24 * 60 * 60
+	}
+	for route, limit := range Conf.RateLimits {
+		if limit.PerMinute <= 0 || limit.Burst < 0 {
+			log.Fatalf("The rate limit of %q needs a positive PerMinute and Burst", route)
+		}
+		if limit.Burst == 0 {
+			limit.Burst = limit.PerMinute
+			Conf.RateLimits[route] = limit
+		}
+	}
+	keyIDs := make(map[string]bool)
+	for _, key := range Conf.JWTKeys {
+		if key.ID == "" || keyIDs[key.ID] {
+			log.Fatalf("Every JWTKeys entry needs a unique ID, got %q", key.ID)
+		}
+		if len(key.Secret) < minJWTSecretLen {
+			log.Fatalf("The secret of JWT key %q must be at least %d bytes long", key.ID, minJWTSecretLen)
+		}
+		if key.Secret == exampleJWTSecret {
+			log.Fatalf("The secret of JWT key %q is the one of the example config, set a random one", key.ID)
+		}
+		keyIDs[key.ID] = true
+	}
 	return Conf
 }
 
-func ConnectToMongoDB(collectionName string) (*mongo.Collection, context.CancelFunc, error) {
+// NewMongoClient connects to the MongoDB server at Conf.DatabaseURL.
+// The client keeps a connection pool and is meant to be created once at startup,
+// shared by all repositories and disconnected on shutdown.
+func NewMongoClient() (*mongo.Client, error) {
+	opts := options.Client().ApplyURI(Conf.DatabaseURL)
+	if Conf.MongoMaxPoolSize != 0 {
+		opts.SetMaxPoolSize(Conf.MongoMaxPoolSize)
+	}
+	if Conf.MongoConnectTimeout != 0 {
+		opts.SetConnectTimeout(time.Duration(Conf.MongoConnectTimeout) * time.Second)
+	}
+	if Conf.MongoServerSelectionTimeout != 0 {
+		opts.SetServerSelectionTimeout(time.Duration(Conf.MongoServerSelectionTimeout) * time.Second)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(Conf.DatabaseURL))
-	var collection *mongo.Collection
-	if err == nil {
-		fmt.Printf("Client value %v\n", client)
-
-		collection = client.Database(Conf.DatabaseName).Collection(collectionName)
-		fmt.Printf("Collection value %v\n", collection)
+	defer cancel()
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, err
 	}
-
-	return collection, cancel, err
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+	fmt.Printf("Connected to MongoDB at %v\n", Conf.DatabaseURL)
+	return client, nil
 }
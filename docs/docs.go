@@ -25,48 +25,94 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/pokemons": {
+        "/apikeys": {
             "get": {
-                "description": "Get all pokemons from the MongoDB. Pass values in json format.",
+                "description": "Get the API keys of the authenticated user, oldest first. The keys themselves are not returned.",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Retrieves all pokemons from the MongoDB",
+                "summary": "List the API keys of the authenticated user",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/pokemons.pokemon"
+                                "$ref": "#/definitions/apikeys.apiKey"
                             }
                         }
-                    },
-                    "400": {
-                        "description": "object can't be parsed into JSON",
+                    }
+                }
+            },
+            "post": {
+                "description": "Create an API key for the authenticated user. The key is returned only in this response, only its hash is stored. Send it in the X-API-Key header; it allows what both the role of the user and one of its scopes allow.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create an API key",
+                "parameters": [
+                    {
+                        "description": "label, scopes and optional expiry of the key",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/apikeys.newKey"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apikeys.createdKey"
                         }
                     },
-                    "404": {
-                        "description": "Error: Not Found",
+                    "400": {
+                        "description": "unknown scope",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Post a pokemon to the MongoDB. If the database doesn't exist, create and insert a new value. Pass values in json format.",
+            }
+        },
+        "/apikeys/{id}": {
+            "put": {
+                "description": "Change the label of an API key of the authenticated user.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Post pokemon to the MongoDB",
+                "summary": "Change the label of an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "id of the key",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "the new label",
+                        "name": "label",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apikeys.labelUpdate"
+                        }
+                    }
+                ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/pokemons.pokemon"
+                            "$ref": "#/definitions/apikeys.apiKey"
                         }
                     },
                     "400": {
@@ -75,8 +121,8 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
-                    "409": {
-                        "description": "a pokemon with such id already exists",
+                    "404": {
+                        "description": "api key not found",
                         "schema": {
                             "type": "string"
                         }
@@ -84,20 +130,29 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete all existing pokemons in the MongoDB and gives a message \"all pokemons are deleted\". Pass values in json format. If there aren't pokemons in the database gives a message \"pokemons not found\".",
+                "description": "Delete an API key of the authenticated user. Requests with the key are refused from then on.",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Delete all pokemons in the MongoDB",
+                "summary": "Revoke an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "id of the key",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "all pokemons was deleted",
+                        "description": "api key was revoked",
                         "schema": {
-                            "$ref": "#/definitions/pokemons.pokemon"
+                            "type": "string"
                         }
                     },
                     "404": {
-                        "description": "pokemons not found",
+                        "description": "api key not found",
                         "schema": {
                             "type": "string"
                         }
@@ -105,88 +160,149 @@ const docTemplate = `{
                 }
             }
         },
-        "/pokemons/{id}": {
+        "/audit": {
             "get": {
-                "description": "Get a pokemon from the MongoDB by ID. Pass values in json format. If there aren't any pokemon with the ID gives a message \"pokemon not found\".",
+                "description": "Get the recorded changes to pokemons, users, their lockouts and API keys, newest first.",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Retrieve pokemon from the MongoDB based on given ID",
+                "summary": "Retrieve the audit log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "only changes made by this login",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only changes to this resource, e.g. pokemons/25, or below it, e.g. pokemons",
+                        "name": "resource",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only changes made at or after this RFC 3339 time",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only changes made before this RFC 3339 time",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "maximum number of entries to return (1-1000)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of entries to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/pokemons.pokemon"
-                        }
-                    },
-                    "404": {
-                        "description": "pokemon not found",
-                        "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/audit.entry"
+                            }
                         }
                     },
-                    "406": {
-                        "description": "must be a number",
+                    "400": {
+                        "description": "invalid query parameters",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "Update an existing pokemon in the MongoDB by ID. Pass values in json format. If there isn't pokemon with the ID creates a new pokemon.",
+            }
+        },
+        "/auth/login": {
+            "post": {
+                "description": "Check the login and password against the users collection and return a short-lived access token to send as \"Authorization: Bearer \u003ctoken\u003e\" and a refresh token for POST /auth/refresh.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Update pokemon's data in the MongoDB based on given ID",
+                "summary": "Exchange a login and password for tokens",
+                "parameters": [
+                    {
+                        "description": "login and password of the user",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tokens.credentials"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "pokemon was updated",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/tokens.tokenPair"
                         }
                     },
-                    "201": {
-                        "description": "Created",
+                    "400": {
+                        "description": "object can't be parsed into JSON",
                         "schema": {
-                            "$ref": "#/definitions/pokemons.pokemon"
+                            "type": "string"
                         }
                     },
-                    "400": {
-                        "description": "object can't be parsed into JSON",
+                    "401": {
+                        "description": "invalid login or password",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "406": {
-                        "description": "pokemon's id cannot be changed",
+                    "429": {
+                        "description": "too many failed attempts",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete an existing pokemon in the MongoDB by ID and gives a message. Pass values in json format. If there isn't pokemon with the ID gives a message.",
+            }
+        },
+        "/auth/logout": {
+            "post": {
+                "description": "Revoke the access token sent as \"Authorization: Bearer \u003ctoken\u003e\" and the refresh token in the body. Either of them may be left out.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Delete pokemon in the MongoDB based on given ID",
-                "responses": {
-                    "200": {
-                        "description": "pokemon was deleted",
+                "summary": "Revoke tokens",
+                "parameters": [
+                    {
+                        "description": "the refresh token",
+                        "name": "token",
+                        "in": "body",
                         "schema": {
-                            "$ref": "#/definitions/pokemons.pokemon"
+                            "$ref": "#/definitions/tokens.refreshRequest"
                         }
-                    },
-                    "201": {
-                        "description": "pokemon not found",
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "logged out",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "406": {
-                        "description": "must be a number",
+                    "400": {
+                        "description": "no token to revoke",
                         "schema": {
                             "type": "string"
                         }
@@ -194,21 +310,32 @@ const docTemplate = `{
                 }
             }
         },
-        "/users": {
-            "get": {
-                "description": "Get all users from the MongoDB. Pass values in json format.",
+        "/auth/refresh": {
+            "post": {
+                "description": "Return a new access token and refresh token. The refresh token sent can't be used again.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Retrieves all users from the MongoDB",
+                "summary": "Exchange a refresh token for new tokens",
+                "parameters": [
+                    {
+                        "description": "the refresh token",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tokens.refreshRequest"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/users.user"
-                            }
+                            "$ref": "#/definitions/tokens.tokenPair"
                         }
                     },
                     "400": {
@@ -217,29 +344,66 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Error: Not Found",
+                    "401": {
+                        "description": "invalid or expired token",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
+            }
+        },
+        "/evolutions": {
+            "get": {
+                "description": "Get all evolution chains sorted by ID. Pass values in json format.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Retrieves all evolution chains from the MongoDB",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/evolutions.chain"
+                            }
+                        }
+                    }
+                }
             },
             "post": {
-                "description": "Post a user to the MongoDB. If the database doesn't exist, create and insert a new value. Pass values in json format.",
+                "description": "Post an evolution tree linking stored pokemons. Every pokemon can belong to one chain only. Pass values in json format.",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Post user to the MongoDB",
+                "summary": "Post an evolution chain to the MongoDB",
+                "parameters": [
+                    {
+                        "description": "the new evolution chain",
+                        "name": "chain",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/evolutions.chain"
+                        }
+                    }
+                ],
                 "responses": {
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/users.user"
+                            "$ref": "#/definitions/evolutions.chain"
                         }
                     },
                     "400": {
-                        "description": "object can't be parsed into JSON",
+                        "description": "the chain doesn't pass validation, e.g. an evolution has no trigger",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "a pokemon of the chain already belongs to another evolution chain",
                         "schema": {
                             "type": "string"
                         }
@@ -247,22 +411,37 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{id}": {
+        "/evolutions/{id}": {
             "get": {
-                "description": "Get a user from the MongoDB by given login. Pass values in json format. If there aren't any users with the login gives a message \"user not found\".",
+                "description": "Get an evolution chain by ID. Pass values in json format.",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Retrieve user from the MongoDB based on given Login",
+                "summary": "Retrieve an evolution chain based on given ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "evolution chain id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/users.user"
+                            "$ref": "#/definitions/evolutions.chain"
                         }
                     },
                     "404": {
-                        "description": "user not found",
+                        "description": "evolution chain not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "must be a number",
                         "schema": {
                             "type": "string"
                         }
@@ -270,20 +449,56 @@ const docTemplate = `{
                 }
             },
             "put": {
-                "description": "Update an existing user in the MongoDB by ID. Pass values in json format. If there isn't user with the ID creates a new user.",
+                "description": "Replace the tree of an existing evolution chain by ID. If there isn't a chain with the ID creates a new chain. Pass values in json format.",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Update user's data in the MongoDB based on given ID",
+                "summary": "Update an evolution chain based on given ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "evolution chain id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "the new state of the evolution chain",
+                        "name": "chain",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/evolutions.chain"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "evolution chain was updated",
                         "schema": {
-                            "$ref": "#/definitions/users.user"
+                            "type": "string"
                         }
                     },
                     "201": {
                         "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/evolutions.chain"
+                        }
+                    },
+                    "400": {
+                        "description": "the chain doesn't pass validation, e.g. an evolution has no trigger",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "evolution chain's id cannot be changed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "a pokemon of the chain already belongs to another evolution chain",
                         "schema": {
                             "type": "string"
                         }
@@ -291,64 +506,2421 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete an existing user in the MongoDB by login and gives a message. Pass values in json format. If there isn't user with the login gives a message.",
+                "description": "Delete an existing evolution chain by ID. The pokemons it links are kept.",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Delete user in the MongoDB based on given login",
+                "summary": "Delete an evolution chain based on given ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "evolution chain id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "user was deleted",
+                        "description": "evolution chain was deleted",
                         "schema": {
-                            "$ref": "#/definitions/users.user"
+                            "type": "string"
                         }
                     },
-                    "201": {
-                        "description": "user not found",
+                    "404": {
+                        "description": "evolution chain not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "must be a number",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "pokemons.pokemon": {
-            "type": "object",
-            "properties": {
-                "color": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "is_legendary": {
-                    "type": "boolean"
-                },
-                "name": {
-                    "type": "string"
-                }
-            }
         },
-        "users.user": {
-            "type": "object",
-            "properties": {
-                "login": {
-                    "type": "string"
-                },
-                "password": {
-                    "type": "string"
-                },
-                "role": {
-                    "type": "string"
-                }
-            }
-        }
-    },
-    "securityDefinitions": {
-        "BasicAuth": {
-            "type": "basic"
+        "/pokemons": {
+            "get": {
+                "description": "Get pokemons from the MongoDB, optionally filtered, sorted and split into pages. Pass values in json format.\nThe number of pokemons matching the filters is returned in the X-Total-Count header and the neighbouring pages in the Link header.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Retrieves pokemons from the MongoDB",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "maximum number of pokemons to return (1-1000)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of pokemons to skip; pages are linked by offset when it is set",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "opaque cursor taken from a Link header",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "id",
+                        "description": "field to sort by, prefix with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "only legendary or only non-legendary pokemons",
+                        "name": "is_legendary",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only pokemons of this color",
+                        "name": "color",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only pokemons whose name starts with this prefix, case-insensitive",
+                        "name": "name_prefix",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/pokemons.pokemon"
+                            }
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "links to the next and previous pages"
+                            },
+                            "X-Total-Count": {
+                                "type": "int",
+                                "description": "number of pokemons matching the filters"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid query parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Post a pokemon to the MongoDB. If the database doesn't exist, create and insert a new value. Pass values in json format.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Post pokemon to the MongoDB",
+                "parameters": [
+                    {
+                        "description": "the new pokemon",
+                        "name": "pokemon",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.pokemon"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.pokemon"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the pokemon"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "the pokemon doesn't pass validation, e.g. unknown primary type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "a pokemon with such name already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Move all existing pokemons to the trash and gives a message \"all pokemons are deleted\". Pass values in json format. If there aren't pokemons in the database gives a message \"pokemons not found\".",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete all pokemons in the MongoDB",
+                "responses": {
+                    "200": {
+                        "description": "all pokemons was deleted",
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.pokemon"
+                        }
+                    },
+                    "404": {
+                        "description": "pokemons not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "pokemons are referred to by another resource",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/pokemons/batch": {
+            "post": {
+                "description": "Apply a list of operations, each behaving like POST /pokemons, PUT /pokemons/{id} or DELETE /pokemons/{id}. In atomic mode, the default, either all operations are applied or none: an operation that fails discards the changes of the others. MongoDB needs a replica set for that. In ordered mode the operations are applied one by one until one fails and the changes of those before it are kept.\nThe response lists the result of every operation. Its status is 200 when all operations were applied and the status of the first failed operation otherwise. Operations which were not applied or were discarded have the status 424.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create, update and delete many pokemons at once",
+                "parameters": [
+                    {
+                        "description": "the operations",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.batchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.batchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "an operation is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.batchResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "a pokemon to delete doesn't exist",
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.batchResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "an operation conflicts with a stored pokemon",
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.batchResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "the database doesn't support transactions",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/pokemons/export": {
+            "get": {
+                "description": "Stream the pokemons matching the filters, in the given order, as a file to download. Paging parameters are ignored, the whole selection is exported. CSV and xlsx files have the columns taken by POST /pokemons/import.",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "summary": "Download the pokemons",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson",
+                            "json",
+                            "xlsx"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "format of the file",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "id",
+                        "description": "field to sort by, prefix with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "only legendary or only non-legendary pokemons",
+                        "name": "is_legendary",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only pokemons of this color",
+                        "name": "color",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only pokemons whose name starts with this prefix, case-insensitive",
+                        "name": "name_prefix",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/pokemons.pokemon"
+                            }
+                        },
+                        "headers": {
+                            "Content-Disposition": {
+                                "type": "string",
+                                "description": "attachment; filename=\\\"pokemons-20060102-150405.json\\"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid query parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/pokemons/import": {
+            "post": {
+                "description": "Create pokemons from CSV, a JSON array or NDJSON, as told by the format parameter or the Content-Type header. CSV input starts with a header naming some of the columns id, name, is_legendary, color, primary_type, secondary_type, hp, attack, defense, special_attack, special_defense, speed, abilities (separated by semicolons), hidden_ability, height, weight, generation and genus. The six base stat columns are given together or not at all, and their fields in a row are all filled or all empty.\nEvery row is validated like the body of POST /pokemons. Invalid rows are reported and skipped, the others are written in batches. Pokemons whose id or name is already stored are skipped, overwritten or make the import stop, as on_duplicate tells; rows imported before the import stops are kept.\nA dry run reads and validates the whole input without storing anything. It doesn't see the ids and names held by pokemons in the trash.",
+                "consumes": [
+                    "text/csv",
+                    "application/json",
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Import many pokemons at once",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "json",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "format of the body, by default taken from Content-Type",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "skip",
+                            "overwrite",
+                            "fail"
+                        ],
+                        "type": "string",
+                        "default": "fail",
+                        "description": "what to do with pokemons already stored",
+                        "name": "on_duplicate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "only validate the input",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.importReport"
+                        }
+                    },
+                    "400": {
+                        "description": "on_duplicate must be skip, overwrite or fail",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "the import stopped at a duplicate",
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.importReport"
+                        }
+                    },
+                    "415": {
+                        "description": "unsupported format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/pokemons/search": {
+            "get": {
+                "description": "Find pokemons whose name or genus is similar to the query, ignoring case, punctuation and small typos. The best matches come first.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Search pokemons by name and genus",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "text to search for, at most 64 characters",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "maximum number of results (1-100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/pokemons.searchResult"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "query must be at most 64 characters long",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/pokemons/{id}": {
+            "get": {
+                "description": "Get a pokemon from the MongoDB by ID or by the slug of its name, e.g. \"mr-mime\". Pass values in json format. If there aren't any pokemon with the ID gives a message \"pokemon not found\".\nThe ETag and Last-Modified headers tell the version of the pokemon. Sending them back in If-None-Match or If-Modified-Since gets a 304 response while the pokemon is unchanged.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Retrieve pokemon from the MongoDB based on given ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pokemon id or name slug",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the pokemon the client has",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last-Modified of the pokemon the client has",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.pokemon"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the pokemon"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "time of the last change of the pokemon"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "the pokemon is unchanged",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "pokemon not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "must be a number or a name",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update an existing pokemon in the MongoDB by ID or by the slug of its name. Pass values in json format. If there isn't pokemon with the ID creates a new pokemon.\nWith an If-Match header the pokemon is only replaced while it is at the version of that ETag.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update pokemon's data in the MongoDB based on given ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pokemon id or name slug",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the pokemon the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "the new state of the pokemon",
+                        "name": "pokemon",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.pokemon"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "pokemon was updated",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "new version of the pokemon"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.pokemon"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "new version of the pokemon"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "the pokemon doesn't pass validation, e.g. unknown primary type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "pokemon not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "pokemon's id cannot be changed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "a pokemon with such name already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "the pokemon has been changed since it was read",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Move an existing pokemon to the trash by ID or by the slug of its name and gives a message. Pass values in json format. If there isn't pokemon with the ID gives a message. Pokemons stay in the trash, see GET /trash, until they are restored or purged.\nWith an If-Match header the pokemon is only deleted while it is at the version of that ETag.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete pokemon in the MongoDB based on given ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pokemon id or name slug",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the pokemon the deletion is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "pokemon was deleted",
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.pokemon"
+                        }
+                    },
+                    "404": {
+                        "description": "pokemon not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "must be a number or a name",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "the pokemon is referred to by another resource",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "the pokemon has been changed since it was read",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Update an existing pokemon by ID or by the slug of its name with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902), told apart by the Content-Type. Fields the patch doesn't mention keep their values. The patched pokemon is validated like a PUT one and returned.\nWith an If-Match header the pokemon is only patched while it is at the version of that ETag.",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Change some fields of a pokemon",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pokemon id or name slug",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the pokemon the patch is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "the patch, e.g. {\\",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.pokemon"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "new version of the pokemon"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "the pokemon doesn't pass validation, e.g. unknown primary type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "pokemon not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "pokemon's id cannot be changed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "the pokemon was changed or deleted while it was patched",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "the pokemon has been changed since it was read",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "the patch must be sent as application/merge-patch+json or application/json-patch+json",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "the patch can't be applied",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/pokemons/{id}/diff": {
+            "get": {
+                "description": "Get the fields that differ between two revisions of the pokemon with the given ID or name slug. Revision 0 is the pokemon before it was created.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Compare two revisions of a pokemon",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pokemon id or name slug",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "older revision, the one before to by default",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "newer revision, the latest by default",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.revisionDiff"
+                        }
+                    },
+                    "400": {
+                        "description": "revision must be a positive number",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "revision not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "must be a number or a name",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/pokemons/{id}/evolutions": {
+            "get": {
+                "description": "Get the whole evolution tree the pokemon with the given ID or name slug belongs to.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Retrieve the evolution chain of a pokemon",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pokemon id or name slug",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/evolutions.chain"
+                        }
+                    },
+                    "404": {
+                        "description": "evolution chain not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "must be a number or a name",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/pokemons/{id}/history": {
+            "get": {
+                "description": "Get every change made to the pokemon with the given ID or name slug, oldest first. Deleted pokemons keep their history and can only be named by ID.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List the revisions of a pokemon",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pokemon id or name slug",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/pokemons.revisionInfo"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "pokemon has no history",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "must be a number or a name",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/pokemons/{id}/history/{rev}": {
+            "get": {
+                "description": "Get a change made to the pokemon with the given ID or name slug together with the pokemon as it was after the change, null after a deletion.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Retrieve a revision of a pokemon",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pokemon id or name slug",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "revision number",
+                        "name": "rev",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.revision"
+                        }
+                    },
+                    "400": {
+                        "description": "revision must be a positive number",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "revision not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "must be a number or a name",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/pokemons/{id}/revert/{rev}": {
+            "post": {
+                "description": "Store the pokemon with the given ID as it was after the given revision. This is recorded as a new revision; a deleted pokemon is created again.\nWith an If-Match header the pokemon is only reverted while it is at the version of that ETag.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Restore a revision of a pokemon",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pokemon id or name slug",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "revision number to restore",
+                        "name": "rev",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the pokemon the revert is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.pokemon"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "new version of the pokemon"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "revision must be a positive number",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "revision not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "must be a number or a name",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "a pokemon with such name already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "the pokemon has been changed since it was read",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "the revision deleted the pokemon",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/pokemons/{id}/weaknesses": {
+            "get": {
+                "description": "Get the damage multiplier of every attacking type against the stored types of the pokemon with the given ID or name slug.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Retrieve the weaknesses, resistances and immunities of a pokemon",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pokemon id or name slug",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.typeProfile"
+                        }
+                    },
+                    "404": {
+                        "description": "pokemon not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "must be a number or a name",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "pokemon's types are unknown",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/trash": {
+            "get": {
+                "description": "Get the pokemons in the trash, most recently deleted first. They keep their id and name, so neither can be reused, until they are restored or purged. Pokemons are purged automatically once they have been in the trash for the configured retention period.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List deleted pokemons",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/pokemons.pokemon"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove all the deleted pokemons from the trash. They can't be restored afterwards.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete every pokemon in the trash for good",
+                "responses": {
+                    "200": {
+                        "description": "trash was emptied",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/trash/{id}": {
+            "delete": {
+                "description": "Remove the deleted pokemon with the given ID from the trash. It can't be restored afterwards, though its revisions are kept and it can still be reverted to one of them.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete a pokemon in the trash for good",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "pokemon id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "pokemon was purged",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "pokemon not found in the trash",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "must be a number",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/trash/{id}/restore": {
+            "post": {
+                "description": "Restore the deleted pokemon with the given ID as it was when it was deleted.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Take a pokemon out of the trash",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "pokemon id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pokemons.pokemon"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "new version of the pokemon"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "pokemon not found in the trash",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "must be a number",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/types": {
+            "get": {
+                "description": "Get the 18 elemental types a pokemon can have.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Retrieve the elemental types",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/types/matchup": {
+            "get": {
+                "description": "Get the damage multiplier of an attack of one type against a pokemon of one or two types.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Compute the effectiveness of an attack type",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "fire",
+                        "description": "attacking type",
+                        "name": "attack",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "grass,steel",
+                        "description": "comma-separated defending types",
+                        "name": "defend",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/typechart.matchup"
+                        }
+                    },
+                    "400": {
+                        "description": "unknown type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "get": {
+                "description": "Get all users from the MongoDB. Pass values in json format.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Retrieves all users from the MongoDB",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/users.user"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "object can't be parsed into JSON",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Error: Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Post a user to the MongoDB. If the database doesn't exist, create and insert a new value. Pass values in json format.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Post user to the MongoDB",
+                "parameters": [
+                    {
+                        "description": "the new user; the password is never returned",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/users.user"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/users.user"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the user"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "unknown role",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "a user with such login already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}": {
+            "get": {
+                "description": "Get a user from the MongoDB by given login. Pass values in json format. If there aren't any users with the login gives a message \"user not found\".\nThe ETag and Last-Modified headers tell the version of the user. Sending them back in If-None-Match or If-Modified-Since gets a 304 response while the user is unchanged.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Retrieve user from the MongoDB based on given Login",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the user the client has",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last-Modified of the user the client has",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/users.user"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the user"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "time of the last change of the user"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "the user is unchanged",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "user not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update an existing user in the MongoDB by ID. Pass values in json format. If there isn't user with the ID creates a new user.\nAn empty password keeps the current one. With an If-Match header the user is only replaced while it is at the version of that ETag.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update user's data in the MongoDB based on given ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the user the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "the new state of the user; the password is never returned",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/users.user"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "user was updated",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "new version of the user"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/users.user"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "new version of the user"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "unknown role",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "user's login cannot be changed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "the user has been changed since it was read",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete an existing user in the MongoDB by login and gives a message. Pass values in json format. If there isn't user with the login gives a message. The API keys of the user are revoked.\nWith an If-Match header the user is only deleted while it is at the version of that ETag.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete user in the MongoDB based on given login",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the user the deletion is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "user was deleted",
+                        "schema": {
+                            "$ref": "#/definitions/users.user"
+                        }
+                    },
+                    "404": {
+                        "description": "user not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "the user has been changed since it was read",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Update an existing user by login with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902), told apart by the Content-Type. The patched document is the user as returned by GET /users/{id}, without the password: add a password member to change it.\nWith an If-Match header the user is only patched while it is at the version of that ETag.",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Change some fields of a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the user the patch is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "the patch, e.g. {\\",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/users.user"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "new version of the user"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "unknown role",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "user not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "user's login cannot be changed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "the user was changed or deleted while it was patched",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "the user has been changed since it was read",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "the patch must be sent as application/merge-patch+json or application/json-patch+json",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "the patch can't be applied",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/lockout": {
+            "get": {
+                "description": "Get the recent failed login attempts of a user and whether the user has to wait before the next attempt.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Inspect the failed logins of a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "login of the user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/auth.lockoutStatus"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Forget the failed login attempts of a user, so the user can log in again at once. Lockouts of client IPs are kept.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Clear the failed logins of a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "login of the user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "lockout was cleared",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "apikeys.apiKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "9f86d081884c7d65"
+                },
+                "label": {
+                    "type": "string",
+                    "example": "nightly import"
+                },
+                "login": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string",
+                        "enum": [
+                            "pokemons:read",
+                            "pokemons:write",
+                            "users:manage"
+                        ]
+                    }
+                }
+            }
+        },
+        "apikeys.createdKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "9f86d081884c7d65"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "phk_9f86d081884c7d65_..."
+                },
+                "label": {
+                    "type": "string",
+                    "example": "nightly import"
+                },
+                "login": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string",
+                        "enum": [
+                            "pokemons:read",
+                            "pokemons:write",
+                            "users:manage"
+                        ]
+                    }
+                }
+            }
+        },
+        "apikeys.labelUpdate": {
+            "type": "object",
+            "properties": {
+                "label": {
+                    "type": "string",
+                    "example": "nightly import"
+                }
+            }
+        },
+        "apikeys.newKey": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "description": "ExpiresAt is optional, keys without it don't expire.",
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string",
+                    "example": "nightly import"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string",
+                        "enum": [
+                            "pokemons:read",
+                            "pokemons:write",
+                            "users:manage"
+                        ]
+                    }
+                }
+            }
+        },
+        "audit.entry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete",
+                        "delete_all",
+                        "revert",
+                        "restore",
+                        "purge",
+                        "import"
+                    ]
+                },
+                "actor": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "after": {
+                    "type": "object"
+                },
+                "before": {
+                    "description": "Before and After are the JSON of the resource as the API returns it, null when it didn't or doesn't exist.",
+                    "type": "object"
+                },
+                "client_ip": {
+                    "type": "string",
+                    "example": "127.0.0.1"
+                },
+                "id": {
+                    "description": "ID grows with Time, so entries are ordered by it.",
+                    "type": "string",
+                    "example": "62d6a5c1f1e4a3b2c1d0e9f8"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "resource": {
+                    "type": "string",
+                    "example": "pokemons/25"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.lockoutStatus": {
+            "type": "object",
+            "properties": {
+                "blocked_until": {
+                    "description": "BlockedUntil is when the next attempt is accepted.",
+                    "type": "string"
+                },
+                "failures": {
+                    "type": "integer"
+                },
+                "last_failure": {
+                    "type": "string"
+                },
+                "locked": {
+                    "type": "boolean"
+                },
+                "login": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "retry_after": {
+                    "description": "RetryAfter is in seconds.",
+                    "type": "integer"
+                }
+            }
+        },
+        "evolutions.chain": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "root": {
+                    "$ref": "#/definitions/evolutions.node"
+                }
+            }
+        },
+        "evolutions.node": {
+            "type": "object",
+            "properties": {
+                "evolves_to": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/evolutions.node"
+                    }
+                },
+                "pokemon_id": {
+                    "type": "integer",
+                    "example": 25
+                },
+                "trigger": {
+                    "description": "Trigger describes how the previous pokemon evolves into this one. The root of a chain has none.",
+                    "$ref": "#/definitions/evolutions.trigger"
+                }
+            }
+        },
+        "evolutions.trigger": {
+            "type": "object",
+            "properties": {
+                "item": {
+                    "type": "string",
+                    "example": "thunder-stone"
+                },
+                "min_level": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 1
+                },
+                "time_of_day": {
+                    "type": "string",
+                    "enum": [
+                        "day",
+                        "night"
+                    ]
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "level",
+                        "item",
+                        "trade",
+                        "friendship",
+                        "time_of_day"
+                    ],
+                    "example": "item"
+                }
+            }
+        },
+        "pokemons.ability": {
+            "type": "object",
+            "properties": {
+                "hidden": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Static"
+                }
+            }
+        },
+        "pokemons.baseStats": {
+            "type": "object",
+            "properties": {
+                "attack": {
+                    "type": "integer",
+                    "maximum": 255,
+                    "minimum": 1,
+                    "example": 55
+                },
+                "defense": {
+                    "type": "integer",
+                    "maximum": 255,
+                    "minimum": 1,
+                    "example": 40
+                },
+                "hp": {
+                    "type": "integer",
+                    "maximum": 255,
+                    "minimum": 1,
+                    "example": 35
+                },
+                "special_attack": {
+                    "type": "integer",
+                    "maximum": 255,
+                    "minimum": 1,
+                    "example": 50
+                },
+                "special_defense": {
+                    "type": "integer",
+                    "maximum": 255,
+                    "minimum": 1,
+                    "example": 50
+                },
+                "speed": {
+                    "type": "integer",
+                    "maximum": 255,
+                    "minimum": 1,
+                    "example": 90
+                }
+            }
+        },
+        "pokemons.batchOperation": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "ID names the pokemon to update or delete.",
+                    "type": "integer",
+                    "example": 25
+                },
+                "op": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete"
+                    ]
+                },
+                "pokemon": {
+                    "description": "Pokemon is the new pokemon or the new state of the updated one.",
+                    "$ref": "#/definitions/pokemons.pokemon"
+                }
+            }
+        },
+        "pokemons.batchRequest": {
+            "type": "object",
+            "properties": {
+                "mode": {
+                    "type": "string",
+                    "default": "atomic",
+                    "enum": [
+                        "atomic",
+                        "ordered"
+                    ]
+                },
+                "operations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pokemons.batchOperation"
+                    }
+                }
+            }
+        },
+        "pokemons.batchResponse": {
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "description": "Applied is the number of operations whose changes were kept.",
+                    "type": "integer",
+                    "example": 3
+                },
+                "mode": {
+                    "type": "string",
+                    "enum": [
+                        "atomic",
+                        "ordered"
+                    ]
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pokemons.batchResult"
+                    }
+                }
+            }
+        },
+        "pokemons.batchResult": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer",
+                    "example": 25
+                },
+                "index": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "message": {
+                    "type": "string",
+                    "example": "pokemon was updated"
+                },
+                "op": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete"
+                    ]
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 200
+                }
+            }
+        },
+        "pokemons.change": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "description": "Field is the json path of the field, with nested fields separated by dots.",
+                    "type": "string",
+                    "example": "base_stats.hp"
+                },
+                "from": {},
+                "to": {}
+            }
+        },
+        "pokemons.importReport": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer",
+                    "example": 150
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pokemons.rowError"
+                    }
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "message": {
+                    "description": "Message tells why the import stopped before the end of the input.",
+                    "type": "string"
+                },
+                "rows": {
+                    "description": "Rows is the number of rows read.",
+                    "type": "integer",
+                    "example": 151
+                },
+                "skipped": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "updated": {
+                    "type": "integer",
+                    "example": 0
+                }
+            }
+        },
+        "pokemons.pokemon": {
+            "type": "object",
+            "properties": {
+                "abilities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pokemons.ability"
+                    }
+                },
+                "base_stats": {
+                    "$ref": "#/definitions/pokemons.baseStats"
+                },
+                "color": {
+                    "type": "string",
+                    "example": "yellow"
+                },
+                "deleted_at": {
+                    "description": "DeletedAt is set by the server when the pokemon is moved to the trash.",
+                    "type": "string"
+                },
+                "generation": {
+                    "type": "integer",
+                    "maximum": 9,
+                    "minimum": 1,
+                    "example": 1
+                },
+                "genus": {
+                    "type": "string",
+                    "example": "Mouse Pokémon"
+                },
+                "height": {
+                    "description": "Height is in metres and Weight in kilograms.",
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 0.4
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 25
+                },
+                "is_legendary": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Pikachu"
+                },
+                "primary_type": {
+                    "type": "string",
+                    "enum": [
+                        "normal",
+                        "fire",
+                        "water",
+                        "electric",
+                        "grass",
+                        "ice",
+                        "fighting",
+                        "poison",
+                        "ground",
+                        "flying",
+                        "psychic",
+                        "bug",
+                        "rock",
+                        "ghost",
+                        "dragon",
+                        "dark",
+                        "steel",
+                        "fairy"
+                    ],
+                    "example": "electric"
+                },
+                "secondary_type": {
+                    "type": "string",
+                    "enum": [
+                        "normal",
+                        "fire",
+                        "water",
+                        "electric",
+                        "grass",
+                        "ice",
+                        "fighting",
+                        "poison",
+                        "ground",
+                        "flying",
+                        "psychic",
+                        "bug",
+                        "rock",
+                        "ghost",
+                        "dragon",
+                        "dark",
+                        "steel",
+                        "fairy"
+                    ]
+                },
+                "slug": {
+                    "description": "Slug is the URL-safe form of Name, set by the server.",
+                    "type": "string",
+                    "example": "pikachu"
+                },
+                "weight": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 6
+                }
+            }
+        },
+        "pokemons.revision": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete",
+                        "revert",
+                        "restore"
+                    ]
+                },
+                "actor": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "pokemon": {
+                    "description": "Pokemon is nil after a deletion.",
+                    "$ref": "#/definitions/pokemons.pokemon"
+                },
+                "pokemon_id": {
+                    "type": "integer",
+                    "example": 25
+                },
+                "rev": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "reverted_to": {
+                    "type": "integer"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "pokemons.revisionDiff": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pokemons.change"
+                    }
+                },
+                "from": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "pokemon_id": {
+                    "type": "integer",
+                    "example": 25
+                },
+                "to": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "pokemons.revisionInfo": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete",
+                        "revert",
+                        "restore"
+                    ]
+                },
+                "actor": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "pokemon_id": {
+                    "type": "integer",
+                    "example": 25
+                },
+                "rev": {
+                    "description": "Rev numbers the revisions of a pokemon from 1.",
+                    "type": "integer",
+                    "example": 1
+                },
+                "reverted_to": {
+                    "description": "RevertedTo is the revision restored by a revert.",
+                    "type": "integer"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "pokemons.rowError": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "ID is left out when the row couldn't be decoded.",
+                    "type": "integer",
+                    "example": 25
+                },
+                "message": {
+                    "type": "string",
+                    "example": "a pokemon with such id already exists"
+                },
+                "row": {
+                    "description": "Row is the line of the row in CSV and NDJSON input and its position in a JSON array, counting from 1.",
+                    "type": "integer",
+                    "example": 42
+                },
+                "status": {
+                    "description": "Status is the status PostPokemon would respond with.",
+                    "type": "integer",
+                    "example": 409
+                }
+            }
+        },
+        "pokemons.searchResult": {
+            "type": "object",
+            "properties": {
+                "pokemon": {
+                    "$ref": "#/definitions/pokemons.pokemon"
+                },
+                "score": {
+                    "description": "Score is the similarity between the query and the pokemon, from 0 to 1.",
+                    "type": "number"
+                }
+            }
+        },
+        "pokemons.typeProfile": {
+            "type": "object",
+            "properties": {
+                "immunities": {
+                    "description": "Immunities lists the attacking types that deal no damage.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "neutral": {
+                    "description": "Neutral lists the attacking types that deal normal damage.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "pokemon_id": {
+                    "type": "integer",
+                    "example": 25
+                },
+                "resistances": {
+                    "description": "Resistances maps the attacking types that deal less than normal damage to their multiplier.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    }
+                },
+                "types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "electric"
+                    ]
+                },
+                "weaknesses": {
+                    "description": "Weaknesses maps the attacking types that deal more than normal damage to their multiplier.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    }
+                }
+            }
+        },
+        "tokens.credentials": {
+            "type": "object",
+            "properties": {
+                "login": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "admin"
+                }
+            }
+        },
+        "tokens.refreshRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "tokens.tokenPair": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "type": "integer",
+                    "example": 900
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string",
+                    "example": "Bearer"
+                }
+            }
+        },
+        "typechart.matchup": {
+            "type": "object",
+            "properties": {
+                "attack": {
+                    "type": "string",
+                    "example": "fire"
+                },
+                "defend": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "grass",
+                        "steel"
+                    ]
+                },
+                "effect": {
+                    "type": "string",
+                    "enum": [
+                        "no effect",
+                        "not very effective",
+                        "normal",
+                        "super effective"
+                    ],
+                    "example": "super effective"
+                },
+                "multiplier": {
+                    "type": "number",
+                    "example": 4
+                }
+            }
+        },
+        "users.user": {
+            "type": "object",
+            "properties": {
+                "login": {
+                    "type": "string"
+                },
+                "password": {
+                    "description": "Password is accepted in requests but never sent back. It is stored as a bcrypt hash.",
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "viewer",
+                        "editor",
+                        "admin"
+                    ]
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "APIKeyAuth": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BasicAuth": {
+            "type": "basic"
+        },
+        "BearerAuth": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -0,0 +1,160 @@
+package evolutions
+
+import (
+	"context"
+	"encoding/binary"
+
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type boltRepository struct {
+	db     *bbolt.DB
+	bucket []byte
+}
+
+// NewBoltRepository returns a ChainRepository that stores chains in the given bucket of a bolt database file.
+// Documents are encoded as BSON and keyed by id.
+func NewBoltRepository(db *bbolt.DB, bucket string) (ChainRepository, error) {
+	r := &boltRepository{db: db, bucket: []byte(bucket)}
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(r.bucket)
+		return err
+	})
+	return r, err
+}
+
+// boltKey encodes id so that the byte order of keys matches the numeric order of ids.
+func boltKey(id int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(id)^(1<<63))
+	return key
+}
+
+// forEach decodes every chain of the bucket in id order until fn returns false.
+func forEach(b *bbolt.Bucket, fn func(ch chain) bool) error {
+	c := b.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		ch := chain{}
+		if err := bson.Unmarshal(v, &ch); err != nil {
+			return err
+		}
+		if !fn(ch) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// inOtherChain reports whether a pokemon of ch belongs to a stored chain other than ch.
+func inOtherChain(b *bbolt.Bucket, ch chain) (bool, error) {
+	found := false
+	err := forEach(b, func(other chain) bool {
+		found = other.ID != ch.ID && sharePokemon(ch, other)
+		return !found
+	})
+	return found, err
+}
+
+func put(b *bbolt.Bucket, ch chain) error {
+	data, err := bson.Marshal(ch)
+	if err != nil {
+		return err
+	}
+	return b.Put(boltKey(ch.ID), data)
+}
+
+func (r *boltRepository) Create(ctx context.Context, ch chain) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		if b.Get(boltKey(ch.ID)) != nil {
+			return ErrDuplicateID
+		}
+		conflict, err := inOtherChain(b, ch)
+		if err != nil {
+			return err
+		}
+		if conflict {
+			return ErrPokemonInOtherChain
+		}
+		return put(b, ch)
+	})
+}
+
+func (r *boltRepository) Get(ctx context.Context, id int64) (chain, error) {
+	result := chain{}
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(r.bucket).Get(boltKey(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		return bson.Unmarshal(data, &result)
+	})
+	return result, err
+}
+
+func (r *boltRepository) GetByPokemon(ctx context.Context, pokemonID int64) (chain, error) {
+	result := chain{}
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		found := false
+		err := forEach(tx.Bucket(r.bucket), func(ch chain) bool {
+			if ch.contains(pokemonID) {
+				result, found = ch, true
+			}
+			return !found
+		})
+		if err == nil && !found {
+			return ErrNotFound
+		}
+		return err
+	})
+	return result, err
+}
+
+func (r *boltRepository) List(ctx context.Context) ([]chain, error) {
+	var chains = []chain{}
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		return forEach(tx.Bucket(r.bucket), func(ch chain) bool {
+			chains = append(chains, ch)
+			return true
+		})
+	})
+	return chains, err
+}
+
+func (r *boltRepository) Upsert(ctx context.Context, ch chain) (bool, error) {
+	created := false
+	err := r.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		conflict, err := inOtherChain(b, ch)
+		if err != nil {
+			return err
+		}
+		if conflict {
+			return ErrPokemonInOtherChain
+		}
+		created = b.Get(boltKey(ch.ID)) == nil
+		return put(b, ch)
+	})
+	return created, err
+}
+
+func (r *boltRepository) Delete(ctx context.Context, id int64) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		if b.Get(boltKey(id)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete(boltKey(id))
+	})
+}
+
+func (r *boltRepository) Any(ctx context.Context) (bool, error) {
+	found := false
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		k, _ := tx.Bucket(r.bucket).Cursor().First()
+		found = k != nil
+		return nil
+	})
+	return found, err
+}
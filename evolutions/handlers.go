@@ -0,0 +1,290 @@
+package evolutions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/pokemon-handbook/pokemons"
+)
+
+type chain struct {
+	ID   int64 `bson:"_id" json:"id" example:"10"`
+	Root node  `bson:"root" json:"root"`
+	// PokemonIDs lists every pokemon of the tree, so that chains can be looked up by pokemon.
+	PokemonIDs []int64 `bson:"pokemon_ids" json:"-"`
+}
+
+type node struct {
+	PokemonID int64 `bson:"pokemon_id" json:"pokemon_id" example:"25"`
+	// Trigger describes how the previous pokemon evolves into this one. The root of a chain has none.
+	Trigger   *trigger `bson:"trigger,omitempty" json:"trigger,omitempty"`
+	EvolvesTo []node   `bson:"evolves_to" json:"evolves_to"`
+}
+
+type trigger struct {
+	Type      string `bson:"type" json:"type" enums:"level,item,trade,friendship,time_of_day" example:"item"`
+	MinLevel  int    `bson:"min_level,omitempty" json:"min_level,omitempty" minimum:"1" maximum:"100"`
+	Item      string `bson:"item,omitempty" json:"item,omitempty" example:"thunder-stone"`
+	TimeOfDay string `bson:"time_of_day,omitempty" json:"time_of_day,omitempty" enums:"day,night"`
+}
+
+// PokemonLookup gives the evolution handlers access to the stored pokemons.
+type PokemonLookup interface {
+	// ResolveID returns the id of the pokemon named by a numeric id or a name slug.
+	ResolveID(ctx context.Context, param string) (int64, error)
+	// PokemonExists reports whether a pokemon with the given id is stored.
+	PokemonExists(ctx context.Context, id int64) (bool, error)
+}
+
+// Handler serves the evolution chain routes on top of a ChainRepository.
+type Handler struct {
+	repo     ChainRepository
+	pokemons PokemonLookup
+}
+
+// NewHandler returns a Handler that stores chains in repo and checks the pokemons they link in pokemons.
+func NewHandler(repo ChainRepository, pokemons PokemonLookup) *Handler {
+	return &Handler{repo: repo, pokemons: pokemons}
+}
+
+type referenceChecker struct {
+	repo ChainRepository
+}
+
+// NewReferenceChecker returns a pokemons.ReferenceChecker that reports pokemons linked by the chains in repo.
+func NewReferenceChecker(repo ChainRepository) pokemons.ReferenceChecker {
+	return referenceChecker{repo: repo}
+}
+
+func (rc referenceChecker) PokemonReferenced(ctx context.Context, id int64) (bool, error) {
+	_, err := rc.repo.GetByPokemon(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (rc referenceChecker) AnyPokemonReferenced(ctx context.Context) (bool, error) {
+	return rc.repo.Any(ctx)
+}
+
+// GetPokemonEvolutions godoc
+// @title        Get Pokemon Evolutions
+// @summary      Retrieve the evolution chain of a pokemon
+// @description  Get the whole evolution tree the pokemon with the given ID or name slug belongs to.
+// @produce      json
+// @param        id  path  string  true  "pokemon id or name slug"
+// @success      200 {object} chain
+// @failure      406 {string} string "must be a number or a name"
+// @failure      404 {string} string "pokemon not found"
+// @failure      404 {string} string "evolution chain not found"
+// @router       /pokemons/{id}/evolutions [get]
+func (h *Handler) GetPokemonEvolutions(c *gin.Context) {
+	id, err := h.pokemons.ResolveID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, pokemons.ErrInvalidID):
+			c.IndentedJSON(http.StatusNotAcceptable, gin.H{"message": "must be a number or a name"})
+		case errors.Is(err, pokemons.ErrNotFound):
+			c.IndentedJSON(http.StatusNotFound, gin.H{"message": "pokemon not found"})
+		default:
+			respondWithInternalError(c, err)
+		}
+		return
+	}
+
+	result, err := h.repo.GetByPokemon(c.Request.Context(), id)
+	if err != nil {
+		respondWithRepositoryError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, result)
+}
+
+// PostChain godoc
+// @title        Post Evolution Chain
+// @summary      Post an evolution chain to the MongoDB
+// @description  Post an evolution tree linking stored pokemons. Every pokemon can belong to one chain only. Pass values in json format.
+// @produce      json
+// @param        chain  body  chain  true  "the new evolution chain"
+// @success      201 {object} chain
+// @failure      400 {string} string "object can't be parsed into JSON"
+// @failure      400 {string} string "the chain doesn't pass validation, e.g. an evolution has no trigger"
+// @failure      409 {string} string "an evolution chain with such id already exists"
+// @failure      409 {string} string "a pokemon of the chain already belongs to another evolution chain"
+// @router       /evolutions [post]
+func (h *Handler) PostChain(c *gin.Context) {
+	var newChain chain
+
+	if err := c.BindJSON(&newChain); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "object can't be parsed into JSON"})
+		return
+	}
+	if !h.prepare(c, &newChain) {
+		return
+	}
+
+	if err := h.repo.Create(c.Request.Context(), newChain); err != nil {
+		respondWithRepositoryError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusCreated, newChain)
+}
+
+// GetChains godoc
+// @title        Get Evolution Chains
+// @summary      Retrieves all evolution chains from the MongoDB
+// @description  Get all evolution chains sorted by ID. Pass values in json format.
+// @produce      json
+// @success      200 {array} chain
+// @router       /evolutions [get]
+func (h *Handler) GetChains(c *gin.Context) {
+	chains, err := h.repo.List(c.Request.Context())
+	if err != nil {
+		respondWithInternalError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, chains)
+}
+
+// GetChainByID godoc
+// @title        Get Evolution Chain By ID
+// @summary      Retrieve an evolution chain based on given ID
+// @description  Get an evolution chain by ID. Pass values in json format.
+// @produce      json
+// @param        id  path  int  true  "evolution chain id"
+// @success      200 {object} chain
+// @failure      406 {string} string "must be a number"
+// @failure      404 {string} string "evolution chain not found"
+// @router       /evolutions/{id} [get]
+func (h *Handler) GetChainByID(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.IndentedJSON(http.StatusNotAcceptable, gin.H{"message": "must be a number"})
+		return
+	}
+
+	result, err := h.repo.Get(c.Request.Context(), id)
+	if err != nil {
+		respondWithRepositoryError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, result)
+}
+
+// UpdateChainByID godoc
+// @title        Update Evolution Chain By ID
+// @summary      Update an evolution chain based on given ID
+// @description  Replace the tree of an existing evolution chain by ID. If there isn't a chain with the ID creates a new chain. Pass values in json format.
+// @produce      json
+// @param        id     path  int    true  "evolution chain id"
+// @param        chain  body  chain  true  "the new state of the evolution chain"
+// @success      200 {string} string "evolution chain was updated"
+// @success      201 {object} chain
+// @failure      406 {string} string "must be a number"
+// @failure      400 {string} string "object can't be parsed into JSON"
+// @failure      400 {string} string "the chain doesn't pass validation, e.g. an evolution has no trigger"
+// @failure      406 {string} string "evolution chain's id cannot be changed"
+// @failure      409 {string} string "a pokemon of the chain already belongs to another evolution chain"
+// @router       /evolutions/{id} [put]
+func (h *Handler) UpdateChainByID(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.IndentedJSON(http.StatusNotAcceptable, gin.H{"message": "must be a number"})
+		return
+	}
+	var newChain chain
+
+	if err := c.BindJSON(&newChain); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "object can't be parsed into JSON"})
+		return
+	}
+
+	if newChain.ID != id {
+		c.IndentedJSON(http.StatusNotAcceptable, gin.H{"message": "evolution chain's id cannot be changed"})
+		return
+	}
+	if !h.prepare(c, &newChain) {
+		return
+	}
+
+	created, err := h.repo.Upsert(c.Request.Context(), newChain)
+	if err != nil {
+		respondWithRepositoryError(c, err)
+		return
+	}
+
+	if created {
+		c.IndentedJSON(http.StatusCreated, newChain)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "evolution chain was updated"})
+}
+
+// DeleteChainByID godoc
+// @title        Delete Evolution Chain By ID
+// @summary      Delete an evolution chain based on given ID
+// @description  Delete an existing evolution chain by ID. The pokemons it links are kept.
+// @produce      json
+// @param        id  path  int  true  "evolution chain id"
+// @success      200 {string} string "evolution chain was deleted"
+// @failure      406 {string} string "must be a number"
+// @failure      404 {string} string "evolution chain not found"
+// @router       /evolutions/{id} [delete]
+func (h *Handler) DeleteChainByID(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.IndentedJSON(http.StatusNotAcceptable, gin.H{"message": "must be a number"})
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), id); err != nil {
+		respondWithRepositoryError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "evolution chain was deleted"})
+}
+
+// prepare validates a chain received from a client and checks that all its pokemons exist.
+// When it returns false the chain can't be stored and the error response has already been written.
+func (h *Handler) prepare(c *gin.Context, ch *chain) bool {
+	ch.normalize()
+	if err := ch.validate(); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return false
+	}
+
+	for _, id := range ch.PokemonIDs {
+		exists, err := h.pokemons.PokemonExists(c.Request.Context(), id)
+		if err != nil {
+			respondWithInternalError(c, err)
+			return false
+		}
+		if !exists {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("pokemon %d doesn't exist", id)})
+			return false
+		}
+	}
+	return true
+}
+
+func respondWithRepositoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": err.Error()})
+	case errors.Is(err, ErrDuplicateID), errors.Is(err, ErrPokemonInOtherChain):
+		c.IndentedJSON(http.StatusConflict, gin.H{"message": err.Error()})
+	default:
+		respondWithInternalError(c, err)
+	}
+}
+
+func respondWithInternalError(c *gin.Context, err error) {
+	fmt.Println(err)
+	c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+}
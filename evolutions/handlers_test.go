@@ -0,0 +1,111 @@
+package evolutions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// storedPokemons is a PokemonLookup of the pokemons with the given ids.
+type storedPokemons map[int64]bool
+
+func (p storedPokemons) ResolveID(ctx context.Context, param string) (int64, error) {
+	return 0, nil
+}
+
+func (p storedPokemons) PokemonExists(ctx context.Context, id int64) (bool, error) {
+	return p[id], nil
+}
+
+func TestPostChainValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		message string
+	}{
+		{"valid chain", `{"id": 2, "root": {"pokemon_id": 172, "evolves_to": [
+			{"pokemon_id": 25, "trigger": {"type": "friendship"}, "evolves_to": [
+				{"pokemon_id": 26, "trigger": {"type": "item", "item": "thunder-stone"}}]}]}}`,
+			http.StatusCreated, ""},
+		{"self-evolution", `{"id": 2, "root": {"pokemon_id": 25, "evolves_to": [
+			{"pokemon_id": 25, "trigger": {"type": "level", "min_level": 20}}]}}`,
+			http.StatusBadRequest, "pokemon 25 appears in the chain more than once"},
+		{"cycle", `{"id": 2, "root": {"pokemon_id": 172, "evolves_to": [
+			{"pokemon_id": 25, "trigger": {"type": "friendship"}, "evolves_to": [
+				{"pokemon_id": 172, "trigger": {"type": "level", "min_level": 20}}]}]}}`,
+			http.StatusBadRequest, "pokemon 172 appears in the chain more than once"},
+		{"pokemon in two branches", `{"id": 2, "root": {"pokemon_id": 172, "evolves_to": [
+			{"pokemon_id": 25, "trigger": {"type": "friendship"}},
+			{"pokemon_id": 25, "trigger": {"type": "level", "min_level": 20}}]}}`,
+			http.StatusBadRequest, "pokemon 25 appears in the chain more than once"},
+		{"unknown pokemon", `{"id": 2, "root": {"pokemon_id": 25, "evolves_to": [
+			{"pokemon_id": 9999, "trigger": {"type": "trade"}}]}}`,
+			http.StatusBadRequest, "pokemon 9999 doesn't exist"},
+		{"unknown root", `{"id": 2, "root": {"pokemon_id": 9999}}`,
+			http.StatusBadRequest, "pokemon 9999 doesn't exist"},
+		{"pokemon of another chain", `{"id": 2, "root": {"pokemon_id": 25, "evolves_to": [
+			{"pokemon_id": 2, "trigger": {"type": "level", "min_level": 16}}]}}`,
+			http.StatusConflict, ErrPokemonInOtherChain.Error()},
+		{"root with a trigger", `{"id": 2, "root": {"pokemon_id": 25, "trigger": {"type": "trade"}}}`,
+			http.StatusBadRequest, "the first pokemon of a chain can't have a trigger"},
+		{"evolution without a trigger", `{"id": 2, "root": {"pokemon_id": 25, "evolves_to": [{"pokemon_id": 26}]}}`,
+			http.StatusBadRequest, "the evolution of pokemon 25 into 26 has no trigger"},
+		{"unknown trigger", `{"id": 2, "root": {"pokemon_id": 25, "evolves_to": [
+			{"pokemon_id": 26, "trigger": {"type": "magic"}}]}}`,
+			http.StatusBadRequest, `unknown trigger type "magic"`},
+		{"level trigger without a level", `{"id": 2, "root": {"pokemon_id": 25, "evolves_to": [
+			{"pokemon_id": 26, "trigger": {"type": "level"}}]}}`,
+			http.StatusBadRequest, "a level trigger requires min_level"},
+		{"level out of range", `{"id": 2, "root": {"pokemon_id": 25, "evolves_to": [
+			{"pokemon_id": 26, "trigger": {"type": "level", "min_level": 101}}]}}`,
+			http.StatusBadRequest, "min_level must be from 1 to 100"},
+		{"item trigger without an item", `{"id": 2, "root": {"pokemon_id": 25, "evolves_to": [
+			{"pokemon_id": 26, "trigger": {"type": "item"}}]}}`,
+			http.StatusBadRequest, "an item trigger requires item"},
+		{"unknown time of day", `{"id": 2, "root": {"pokemon_id": 25, "evolves_to": [
+			{"pokemon_id": 26, "trigger": {"type": "time_of_day", "time_of_day": "dusk"}}]}}`,
+			http.StatusBadRequest, "time_of_day must be day or night"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			repo := NewMemoryRepository()
+			// bulbasaur evolving into ivysaur
+			bulbasaur := chain{ID: 1, Root: node{PokemonID: 1, EvolvesTo: []node{
+				{PokemonID: 2, Trigger: &trigger{Type: TriggerLevel, MinLevel: 16}},
+			}}}
+			bulbasaur.normalize()
+			if err := repo.Create(context.Background(), bulbasaur); err != nil {
+				t.Fatal(err)
+			}
+			h := NewHandler(repo, storedPokemons{1: true, 2: true, 25: true, 26: true, 172: true})
+			r := gin.New()
+			r.POST("/evolutions", h.PostChain)
+
+			req := httptest.NewRequest(http.MethodPost, "/evolutions", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			var res struct {
+				Message string `json:"message"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(res.Message, tt.message) {
+				t.Errorf("message = %q, want it to say %q", res.Message, tt.message)
+			}
+			if _, err := repo.Get(context.Background(), 2); (err == nil) != (tt.status == http.StatusCreated) {
+				t.Errorf("chain 2 stored: %v, want %v", err == nil, tt.status == http.StatusCreated)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package evolutions
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+type memoryRepository struct {
+	mu     sync.RWMutex
+	chains map[int64]chain
+}
+
+// NewMemoryRepository returns a ChainRepository that keeps chains in process memory.
+// It is safe for concurrent use and loses its data when the process exits.
+func NewMemoryRepository() ChainRepository {
+	return &memoryRepository{chains: make(map[int64]chain)}
+}
+
+// inOtherChain reports whether a pokemon of ch belongs to a stored chain other than ch.
+func (r *memoryRepository) inOtherChain(ch chain) bool {
+	for _, other := range r.chains {
+		if other.ID != ch.ID && sharePokemon(ch, other) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryRepository) Create(ctx context.Context, ch chain) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.chains[ch.ID]; ok {
+		return ErrDuplicateID
+	}
+	if r.inOtherChain(ch) {
+		return ErrPokemonInOtherChain
+	}
+	r.chains[ch.ID] = ch
+	return nil
+}
+
+func (r *memoryRepository) Get(ctx context.Context, id int64) (chain, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ch, ok := r.chains[id]
+	if !ok {
+		return chain{}, ErrNotFound
+	}
+	return ch, nil
+}
+
+func (r *memoryRepository) GetByPokemon(ctx context.Context, pokemonID int64) (chain, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, ch := range r.chains {
+		if ch.contains(pokemonID) {
+			return ch, nil
+		}
+	}
+	return chain{}, ErrNotFound
+}
+
+func (r *memoryRepository) List(ctx context.Context) ([]chain, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	chains := make([]chain, 0, len(r.chains))
+	for _, ch := range r.chains {
+		chains = append(chains, ch)
+	}
+	sort.Slice(chains, func(i, j int) bool { return chains[i].ID < chains[j].ID })
+	return chains, nil
+}
+
+func (r *memoryRepository) Upsert(ctx context.Context, ch chain) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.inOtherChain(ch) {
+		return false, ErrPokemonInOtherChain
+	}
+	_, exists := r.chains[ch.ID]
+	r.chains[ch.ID] = ch
+	return !exists, nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.chains[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.chains, id)
+	return nil
+}
+
+func (r *memoryRepository) Any(ctx context.Context) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.chains) > 0, nil
+}
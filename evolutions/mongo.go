@@ -0,0 +1,108 @@
+package evolutions
+
+import (
+	"context"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// pokemonIndex is the name of the unique index that keeps every pokemon in one chain.
+const pokemonIndex = "pokemon_ids_unique"
+
+type mongoRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoRepository returns a ChainRepository backed by the given MongoDB collection
+// and creates the indexes it relies on.
+func NewMongoRepository(collection *mongo.Collection) (ChainRepository, error) {
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "pokemon_ids", Value: 1}},
+		Options: options.Index().SetName(pokemonIndex).SetUnique(true),
+	})
+	return &mongoRepository{collection: collection}, err
+}
+
+// duplicateKeyError tells which unique index a duplicate key error came from.
+func duplicateKeyError(err error) error {
+	if !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	if strings.Contains(err.Error(), pokemonIndex) {
+		return ErrPokemonInOtherChain
+	}
+	return ErrDuplicateID
+}
+
+func (r *mongoRepository) Create(ctx context.Context, ch chain) error {
+	_, err := r.collection.InsertOne(ctx, ch)
+	return duplicateKeyError(err)
+}
+
+func (r *mongoRepository) findOne(ctx context.Context, filter interface{}) (chain, error) {
+	result := chain{}
+
+	err := r.collection.FindOne(ctx, filter).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		return result, ErrNotFound
+	}
+	return result, err
+}
+
+func (r *mongoRepository) Get(ctx context.Context, id int64) (chain, error) {
+	return r.findOne(ctx, bson.D{{Key: "_id", Value: id}})
+}
+
+func (r *mongoRepository) GetByPokemon(ctx context.Context, pokemonID int64) (chain, error) {
+	return r.findOne(ctx, bson.D{{Key: "pokemon_ids", Value: pokemonID}})
+}
+
+func (r *mongoRepository) List(ctx context.Context) ([]chain, error) {
+	var chains = []chain{}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cur, err := r.collection.Find(ctx, bson.D{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		result := chain{}
+		if err := cur.Decode(&result); err != nil {
+			return nil, err
+		}
+		chains = append(chains, result)
+	}
+	return chains, cur.Err()
+}
+
+func (r *mongoRepository) Upsert(ctx context.Context, ch chain) (bool, error) {
+	opts := options.Replace().SetUpsert(true)
+	result, err := r.collection.ReplaceOne(ctx, bson.D{{Key: "_id", Value: ch.ID}}, ch, opts)
+	if err != nil {
+		return false, duplicateKeyError(err)
+	}
+	return result.UpsertedCount != 0, nil
+}
+
+func (r *mongoRepository) Delete(ctx context.Context, id int64) error {
+	res, err := r.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: id}})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoRepository) Any(ctx context.Context) (bool, error) {
+	err := r.collection.FindOne(ctx, bson.D{}).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	return err == nil, err
+}
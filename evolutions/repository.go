@@ -0,0 +1,35 @@
+package evolutions
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned by a ChainRepository when there is no matching evolution chain.
+var ErrNotFound = errors.New("evolution chain not found")
+
+// ErrDuplicateID is returned by ChainRepository.Create when a chain with the same id is already stored.
+var ErrDuplicateID = errors.New("an evolution chain with such id already exists")
+
+// ErrPokemonInOtherChain is returned by ChainRepository.Create and Upsert
+// when one of the pokemons of the chain already belongs to another chain.
+var ErrPokemonInOtherChain = errors.New("a pokemon of the chain already belongs to another evolution chain")
+
+// ChainRepository is the storage used by the evolution chain handlers.
+type ChainRepository interface {
+	// Create stores a new chain or returns ErrDuplicateID or ErrPokemonInOtherChain.
+	Create(ctx context.Context, ch chain) error
+	// Get returns the chain with the given id or ErrNotFound.
+	Get(ctx context.Context, id int64) (chain, error)
+	// GetByPokemon returns the chain the pokemon with the given id belongs to or ErrNotFound.
+	GetByPokemon(ctx context.Context, pokemonID int64) (chain, error)
+	// List returns all chains sorted by id.
+	List(ctx context.Context) ([]chain, error)
+	// Upsert replaces the chain with ch.ID or inserts it, reporting whether it was created.
+	// It returns ErrPokemonInOtherChain if one of its pokemons belongs to another chain.
+	Upsert(ctx context.Context, ch chain) (created bool, err error)
+	// Delete removes the chain with the given id or returns ErrNotFound.
+	Delete(ctx context.Context, id int64) error
+	// Any reports whether at least one chain is stored.
+	Any(ctx context.Context) (bool, error)
+}
@@ -0,0 +1,123 @@
+package evolutions
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Evolution triggers.
+const (
+	TriggerLevel      = "level"
+	TriggerItem       = "item"
+	TriggerTrade      = "trade"
+	TriggerFriendship = "friendship"
+	TriggerTimeOfDay  = "time_of_day"
+)
+
+const maxLevel = 100
+
+// normalize fills the fields derived from the tree of ch.
+func (ch *chain) normalize() {
+	ch.Root.normalize()
+	ch.PokemonIDs = ch.Root.pokemonIDs(nil)
+}
+
+// normalize makes leaves list no evolutions instead of a null one.
+func (n *node) normalize() {
+	if n.EvolvesTo == nil {
+		n.EvolvesTo = []node{}
+	}
+	for i := range n.EvolvesTo {
+		n.EvolvesTo[i].normalize()
+	}
+}
+
+// pokemonIDs appends the ids of n and of all its evolutions to ids.
+func (n node) pokemonIDs(ids []int64) []int64 {
+	ids = append(ids, n.PokemonID)
+	for _, next := range n.EvolvesTo {
+		ids = next.pokemonIDs(ids)
+	}
+	return ids
+}
+
+// validate checks the structure of ch: every pokemon appears once
+// and every evolution, but not the root, has a valid trigger.
+func (ch chain) validate() error {
+	if ch.Root.Trigger != nil {
+		return errors.New("the first pokemon of a chain can't have a trigger")
+	}
+
+	seen := make(map[int64]bool)
+	for _, id := range ch.Root.pokemonIDs(nil) {
+		if seen[id] {
+			return fmt.Errorf("pokemon %d appears in the chain more than once", id)
+		}
+		seen[id] = true
+	}
+
+	var check func(n node) error
+	check = func(n node) error {
+		for _, next := range n.EvolvesTo {
+			if next.Trigger == nil {
+				return fmt.Errorf("the evolution of pokemon %d into %d has no trigger", n.PokemonID, next.PokemonID)
+			}
+			if err := next.Trigger.validate(); err != nil {
+				return fmt.Errorf("the evolution of pokemon %d into %d: %w", n.PokemonID, next.PokemonID, err)
+			}
+			if err := check(next); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return check(ch.Root)
+}
+
+func (t trigger) validate() error {
+	if t.MinLevel < 0 || t.MinLevel > maxLevel {
+		return fmt.Errorf("min_level must be from 1 to %d", maxLevel)
+	}
+	if t.TimeOfDay != "" && t.TimeOfDay != "day" && t.TimeOfDay != "night" {
+		return errors.New("time_of_day must be day or night")
+	}
+
+	switch t.Type {
+	case TriggerLevel:
+		if t.MinLevel == 0 {
+			return errors.New("a level trigger requires min_level")
+		}
+	case TriggerItem:
+		if t.Item == "" {
+			return errors.New("an item trigger requires item")
+		}
+	case TriggerTrade, TriggerFriendship:
+	case TriggerTimeOfDay:
+		if t.TimeOfDay == "" {
+			return errors.New("a time_of_day trigger requires time_of_day")
+		}
+	default:
+		return fmt.Errorf("unknown trigger type %q", t.Type)
+	}
+	return nil
+}
+
+// contains reports whether the pokemon with the given id belongs to ch.
+func (ch chain) contains(pokemonID int64) bool {
+	for _, id := range ch.PokemonIDs {
+		if id == pokemonID {
+			return true
+		}
+	}
+	return false
+}
+
+// sharePokemon reports whether a pokemon belongs to both chains.
+func sharePokemon(a, b chain) bool {
+	for _, id := range a.PokemonIDs {
+		if b.contains(id) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Bind applies the patch in the body of a PATCH request to the JSON form of current and decodes the result into dst.
+// When it returns false the error response has already been written: 415 for an unknown content type,
+// 400 for a malformed patch, 409 for a failed test and 422 when the patch doesn't fit current
+// or the result doesn't fit dst.
+func Bind(c *gin.Context, current, dst interface{}) bool {
+	c.Header("Accept-Patch", MergePatchType+", "+JSONPatchType)
+	patch, err := c.GetRawData()
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "the patch can't be read"})
+		return false
+	}
+	doc, err := json.Marshal(current)
+	if err != nil {
+		fmt.Println(err)
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return false
+	}
+
+	patched, err := Apply(c.ContentType(), doc, patch)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrUnsupportedType):
+		c.IndentedJSON(http.StatusUnsupportedMediaType, gin.H{"message": err.Error()})
+		return false
+	case errors.Is(err, ErrTestFailed):
+		c.IndentedJSON(http.StatusConflict, gin.H{"message": err.Error()})
+		return false
+	case errors.Is(err, ErrUnprocessable):
+		c.IndentedJSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
+		return false
+	default:
+		// the document was marshalled above, so only the patch can be malformed
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return false
+	}
+
+	if err := json.Unmarshal(patched, dst); err != nil {
+		c.IndentedJSON(http.StatusUnprocessableEntity, gin.H{"message": "the patched object is invalid: " + err.Error()})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,404 @@
+// Package jsonpatch applies partial updates to JSON documents, sent either as a JSON Merge Patch (RFC 7396)
+// or as a JSON Patch (RFC 6902).
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Content types of the supported patch formats.
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+var (
+	// ErrUnsupportedType is returned by Apply for content types other than MergePatchType and JSONPatchType.
+	ErrUnsupportedType = errors.New("the patch must be sent as " + MergePatchType + " or " + JSONPatchType)
+	// ErrInvalidPatch is returned when the patch itself is malformed.
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrTestFailed is returned when a test operation finds another value than expected.
+	ErrTestFailed = errors.New("test failed")
+	// ErrUnprocessable is returned when an operation can't be applied to the document, e.g. a path doesn't exist.
+	ErrUnprocessable = errors.New("the patch can't be applied")
+)
+
+// Apply applies patch, whose format is given by contentType, to the JSON document doc and returns the result.
+func Apply(contentType string, doc, patch []byte) ([]byte, error) {
+	switch contentType {
+	case MergePatchType:
+		return MergePatch(doc, patch)
+	case JSONPatchType:
+		return Patch(doc, patch)
+	default:
+		return nil, ErrUnsupportedType
+	}
+}
+
+// MergePatch applies a JSON Merge Patch to doc: members of patch objects replace those of doc,
+// objects are merged recursively and null removes a member.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{}, len(p))
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+			continue
+		}
+		t[key] = merge(t[key], value)
+	}
+	return t
+}
+
+// operation is an operation of a JSON Patch. Its members are kept raw so that a missing value
+// can be told from a null one.
+type operation map[string]json.RawMessage
+
+// Patch applies a JSON Patch to doc. The operations are applied in order and the patch fails as a whole
+// if any of them fails.
+func Patch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	var ops []operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: a JSON Patch must be an array of operations", ErrInvalidPatch)
+	}
+	for i, op := range ops {
+		if target, err = op.apply(target); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func (op operation) apply(doc interface{}) (interface{}, error) {
+	var name string
+	if err := op.member("op", &name); err != nil {
+		return nil, err
+	}
+	var pathMember string
+	if err := op.member("path", &pathMember); err != nil {
+		return nil, err
+	}
+	path, err := parsePointer(pathMember)
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "add", "replace", "test":
+		raw, ok := op["value"]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s needs a value", ErrInvalidPatch, name)
+		}
+		value, err := decode(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		switch name {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			if doc, _, err = remove(doc, path); err != nil {
+				return nil, err
+			}
+			return add(doc, path, value)
+		default:
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(current, value) {
+				return nil, fmt.Errorf("%w: %s doesn't hold the expected value", ErrTestFailed, pathMember)
+			}
+			return doc, nil
+		}
+
+	case "remove":
+		doc, _, err = remove(doc, path)
+		return doc, err
+
+	case "move", "copy":
+		var fromMember string
+		if err := op.member("from", &fromMember); err != nil {
+			return nil, err
+		}
+		from, err := parsePointer(fromMember)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if name == "move" {
+			if len(from) < len(path) && isPrefix(from, path) {
+				return nil, fmt.Errorf("%w: a value can't be moved into itself", ErrUnprocessable)
+			}
+			if doc, value, err = remove(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			if value, err = get(doc, from); err != nil {
+				return nil, err
+			}
+			value = clone(value)
+		}
+		return add(doc, path, value)
+
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, name)
+	}
+}
+
+// member decodes the string member of op with the given name into dst.
+func (op operation) member(name string, dst *string) error {
+	raw, ok := op[name]
+	if !ok || json.Unmarshal(raw, dst) != nil {
+		return fmt.Errorf("%w: every operation needs a %s string", ErrInvalidPatch, name)
+	}
+	return nil
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped reference tokens.
+// The empty pointer refers to the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: path %q must start with /", ErrInvalidPatch, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// index parses token as an index of arr. With end set, the "-" token and len(arr) name the position after
+// the last element.
+func index(arr []interface{}, token string, end bool) (int, error) {
+	size := len(arr)
+	if end {
+		size++
+		if token == "-" {
+			return len(arr), nil
+		}
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrUnprocessable, token)
+	}
+	if i >= size {
+		return 0, fmt.Errorf("%w: index %d is out of range", ErrUnprocessable, i)
+	}
+	return i, nil
+}
+
+// child returns the member or element of container named by token.
+func child(container interface{}, token string) (interface{}, error) {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		value, ok := c[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: member %q doesn't exist", ErrUnprocessable, token)
+		}
+		return value, nil
+	case []interface{}:
+		i, err := index(c, token, false)
+		if err != nil {
+			return nil, err
+		}
+		return c[i], nil
+	default:
+		return nil, fmt.Errorf("%w: %q is looked up in a value which is neither an object nor an array", ErrUnprocessable, token)
+	}
+}
+
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		var err error
+		if doc, err = child(doc, token); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// update replaces the parent of the last token of path with what change makes of it and returns the new document.
+// Arrays change size, so every container along the path is rebuilt from the returned values.
+func update(doc interface{}, path []string, change func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return change(doc, path[0])
+	}
+	next, err := child(doc, path[0])
+	if err != nil {
+		return nil, err
+	}
+	if next, err = update(next, path[1:], change); err != nil {
+		return nil, err
+	}
+	switch c := doc.(type) {
+	case map[string]interface{}:
+		c[path[0]] = next
+	case []interface{}:
+		i, _ := index(c, path[0], false)
+		c[i] = next
+	}
+	return doc, nil
+}
+
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch c := parent.(type) {
+		case map[string]interface{}:
+			c[token] = value
+			return c, nil
+		case []interface{}:
+			i, err := index(c, token, true)
+			if err != nil {
+				return nil, err
+			}
+			c = append(c, nil)
+			copy(c[i+1:], c[i:])
+			c[i] = value
+			return c, nil
+		default:
+			return nil, fmt.Errorf("%w: %q is added to a value which is neither an object nor an array", ErrUnprocessable, token)
+		}
+	})
+}
+
+// remove takes the value at path out of doc and returns the new document and the removed value.
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+	var removed interface{}
+	doc, err := update(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		var err error
+		if removed, err = child(parent, token); err != nil {
+			return nil, err
+		}
+		switch c := parent.(type) {
+		case map[string]interface{}:
+			delete(c, token)
+			return c, nil
+		default:
+			arr := c.([]interface{})
+			i, _ := index(arr, token, false)
+			return append(arr[:i], arr[i+1:]...), nil
+		}
+	})
+	return doc, removed, err
+}
+
+// equal compares JSON values, numbers by their value.
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if x == y {
+			return true
+		}
+		fx, errX := x.Float64()
+		fy, errY := y.Float64()
+		return errX == nil && errY == nil && fx == fy
+	default:
+		return a == b
+	}
+}
+
+func clone(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for key, member := range v {
+			c[key] = clone(member)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, element := range v {
+			c[i] = clone(element)
+		}
+		return c
+	default:
+		return value
+	}
+}
+
+// decode parses a JSON value keeping numbers as they were written, so that large ids don't lose precision.
+func decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return value, nil
+}
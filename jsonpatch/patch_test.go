@@ -0,0 +1,101 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// sameJSON reports whether a and b encode the same value.
+func sameJSON(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var va, vb interface{}
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatalf("%s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatalf("%s: %v", b, err)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+		err   error
+	}{
+		{"replace a member", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`, nil},
+		{"add a member", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`, nil},
+		{"remove a member", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`, nil},
+		{"replace an array", `{"a":["b"]}`, `{"a":["c","d"]}`, `{"a":["c","d"]}`, nil},
+		{"merge nested objects", `{"a":{"b":"c","d":"e"}}`, `{"a":{"d":null,"f":"g"}}`, `{"a":{"b":"c","f":"g"}}`, nil},
+		{"replace the document", `{"a":"b"}`, `["c"]`, `["c"]`, nil},
+		{"empty patch", `{"a":"b"}`, `{}`, `{"a":"b"}`, nil},
+		{"malformed patch", `{"a":"b"}`, `{"a":`, "", ErrInvalidPatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(MergePatchType, []byte(tt.doc), []byte(tt.patch))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if err == nil && !sameJSON(t, got, []byte(tt.want)) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+		err   error
+	}{
+		{"add a member", `{"a":1}`, `[{"op":"add","path":"/b","value":2}]`, `{"a":1,"b":2}`, nil},
+		{"add to an array", `{"a":[1,3]}`, `[{"op":"add","path":"/a/1","value":2}]`, `{"a":[1,2,3]}`, nil},
+		{"append to an array", `{"a":[1]}`, `[{"op":"add","path":"/a/-","value":2}]`, `{"a":[1,2]}`, nil},
+		{"remove a member", `{"a":1,"b":2}`, `[{"op":"remove","path":"/b"}]`, `{"a":1}`, nil},
+		{"remove from an array", `{"a":[1,2,3]}`, `[{"op":"remove","path":"/a/1"}]`, `{"a":[1,3]}`, nil},
+		{"replace", `{"a":1}`, `[{"op":"replace","path":"/a","value":"x"}]`, `{"a":"x"}`, nil},
+		{"move", `{"a":{"b":1},"c":{}}`, `[{"op":"move","from":"/a/b","path":"/c/d"}]`, `{"a":{},"c":{"d":1}}`, nil},
+		{"copy", `{"a":[1]}`, `[{"op":"copy","from":"/a","path":"/b"}]`, `{"a":[1],"b":[1]}`, nil},
+		{"passing test", `{"a":{"b":[1,"c"]}}`, `[{"op":"test","path":"/a","value":{"b":[1,"c"]}}]`, `{"a":{"b":[1,"c"]}}`, nil},
+		{"escaped pointer", `{"a/b":1,"m~n":2}`, `[{"op":"remove","path":"/a~1b"},{"op":"remove","path":"/m~0n"}]`, `{}`, nil},
+		{"operations in order", `{"a":1}`, `[{"op":"add","path":"/b","value":2},{"op":"remove","path":"/a"}]`, `{"b":2}`, nil},
+		{"failed test", `{"a":1}`, `[{"op":"test","path":"/a","value":2}]`, "", ErrTestFailed},
+		{"remove a missing member", `{"a":1}`, `[{"op":"remove","path":"/b"}]`, "", ErrUnprocessable},
+		{"replace a missing member", `{"a":1}`, `[{"op":"replace","path":"/b","value":2}]`, "", ErrUnprocessable},
+		{"index out of range", `{"a":[1]}`, `[{"op":"add","path":"/a/5","value":2}]`, "", ErrUnprocessable},
+		{"move into itself", `{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/b/c"}]`, "", ErrUnprocessable},
+		{"unknown operation", `{"a":1}`, `[{"op":"rename","path":"/a"}]`, "", ErrInvalidPatch},
+		{"missing value", `{"a":1}`, `[{"op":"add","path":"/b"}]`, "", ErrInvalidPatch},
+		{"invalid pointer", `{"a":1}`, `[{"op":"remove","path":"a"}]`, "", ErrInvalidPatch},
+		{"not an array", `{"a":1}`, `{"op":"remove","path":"/a"}`, "", ErrInvalidPatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(JSONPatchType, []byte(tt.doc), []byte(tt.patch))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if err == nil && !sameJSON(t, got, []byte(tt.want)) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyUnsupportedType(t *testing.T) {
+	for _, contentType := range []string{"", "application/json", "text/plain"} {
+		if _, err := Apply(contentType, []byte(`{}`), []byte(`{}`)); !errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("Apply(%q) error = %v, want %v", contentType, err, ErrUnsupportedType)
+		}
+	}
+}
@@ -1,17 +1,29 @@
 package main
 
 import (
-	"encoding/base64"
+	"context"
 	"fmt"
-	"strings"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"     // swagger embed files	"go.mongodb.org/mongo-driver/bson"
 	ginSwagger "github.com/swaggo/gin-swagger" // gin-swagger middleware
 
+	"example.com/pokemon-handbook/apikeys"
+	"example.com/pokemon-handbook/audit"
+	"example.com/pokemon-handbook/auth"
 	"example.com/pokemon-handbook/config"
 	_ "example.com/pokemon-handbook/docs" // import docs generated by Swag CLI
+	"example.com/pokemon-handbook/evolutions"
 	"example.com/pokemon-handbook/pokemons"
+	"example.com/pokemon-handbook/ratelimit"
+	"example.com/pokemon-handbook/tokens"
+	"example.com/pokemon-handbook/typechart"
 	"example.com/pokemon-handbook/users"
 )
 
@@ -19,7 +31,6 @@ func init() {
 	fmt.Println("This is init")
 
 	config.Conf = config.ReadConfig()
-	users.CheckAdminInDB()
 }
 
 // @title           Swagger Example API
@@ -38,64 +49,277 @@ func init() {
 // @BasePath  /
 
 // @securityDefinitions.basic  BasicAuth
+
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
+
+// @securityDefinitions.apikey  APIKeyAuth
+// @in                          header
+// @name                        X-API-Key
 func main() {
 	fmt.Println("This is main")
 
 	router := gin.Default()
+	router.Use(audit.RequestID())
+
+	store := openStorage()
+	defer store.close()
+	if err := users.MigratePlaintextPasswords(store.users); err != nil {
+		log.Fatal(err)
+	}
+	if err := users.MigrateLegacyRoles(store.users); err != nil {
+		log.Fatal(err)
+	}
+	users.CheckAdminInDB(store.users)
 
-	authorized := router.Group("/", gin.BasicAuth(gin.Accounts{
-		config.Conf.UserName:  config.Conf.Password,
-		config.Conf.UserName1: config.Conf.Password1,
-	}))
+	auditLog := audit.NewLog(store.audit)
+	pokemonHandler := pokemons.NewHandler(store.pokemons, store.history, auditLog, evolutions.NewReferenceChecker(store.evolutions))
+	evolutionHandler := evolutions.NewHandler(store.evolutions, pokemonHandler)
+	authenticator := users.NewAuthenticator(store.users, time.Duration(config.Conf.AuthCacheTTL)*time.Second)
+	lockout := auth.NewLockout(auth.LockoutPolicy{
+		Threshold:   config.Conf.LockoutThreshold,
+		IPThreshold: config.Conf.IPLockoutThreshold,
+		Backoff:     time.Duration(config.Conf.LoginBackoff) * time.Second,
+		Duration:    time.Duration(config.Conf.LockoutDuration) * time.Second,
+	}, auditLog)
+	guarded := auth.Guard(authenticator, lockout)
+	apiKeyHandler := apikeys.NewHandler(store.apiKeys, auditLog)
+	userHandler := users.NewHandler(store.users, auditLog, lockout, apiKeyHandler, authenticator)
+	tokenService := tokens.NewService(store.tokens, config.Conf.JWTKeys,
+		time.Duration(config.Conf.AccessTokenTTL)*time.Second, time.Duration(config.Conf.RefreshTokenTTL)*time.Second)
+	tokenHandler := tokens.NewHandler(tokenService, guarded, authenticator)
 
-	authorized.POST("/pokemons", pokemons.PostPokemon)
-	router.GET("/pokemons", pokemons.GetPokemons)
-	router.GET("/pokemons/:id", pokemons.GetPokemonByID)
-	authorized.PUT("/pokemons/:id", pokemons.UpdatePokemonByID)
-	authorized.DELETE("/pokemons/:id", pokemons.DeletePokemonByID)
-	router.DELETE("/pokemons", adminBasicAuth, pokemons.DeleteAllPokemons)
+	authenticated := auth.Middleware(guarded, tokenService, apikeys.NewVerifier(store.apiKeys, authenticator))
 
-	router.POST("/users", adminBasicAuth, users.PostUser)
-	router.GET("/users", adminBasicAuth, users.GetUsers)
-	router.GET("/users/:id", adminBasicAuth, users.GetUserByLogin)
-	router.PUT("/users/:id", adminBasicAuth, users.UpdateUserByLogin)
-	router.DELETE("/users/:id", adminBasicAuth, users.DeleteUserByLogin)
+	// Routes without a permission are public, the others need an authenticated user whose role grants it.
+	routes := []struct {
+		method     string
+		path       string
+		permission auth.Permission
+		handler    gin.HandlerFunc
+	}{
+		{http.MethodPost, "/auth/login", "", tokenHandler.Login},
+		{http.MethodPost, "/auth/refresh", "", tokenHandler.Refresh},
+		{http.MethodPost, "/auth/logout", "", tokenHandler.Logout},
 
-	// use ginSwagger middleware to serve the API docs
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+		{http.MethodPost, "/apikeys", auth.ManageAPIKeys, apiKeyHandler.PostAPIKey},
+		{http.MethodGet, "/apikeys", auth.ManageAPIKeys, apiKeyHandler.GetAPIKeys},
+		{http.MethodPut, "/apikeys/:id", auth.ManageAPIKeys, apiKeyHandler.UpdateAPIKeyByID},
+		{http.MethodDelete, "/apikeys/:id", auth.ManageAPIKeys, apiKeyHandler.DeleteAPIKeyByID},
 
-	router.Run(config.Conf.URL)
-}
+		{http.MethodPost, "/pokemons", auth.WritePokemons, pokemonHandler.PostPokemon},
+		{http.MethodGet, "/pokemons", "", pokemonHandler.GetPokemons},
+		{http.MethodGet, "/pokemons/search", "", pokemonHandler.SearchPokemons},
+		{http.MethodPost, "/pokemons/import", auth.WritePokemons, pokemonHandler.ImportPokemons},
+		{http.MethodPost, "/pokemons/batch", auth.WritePokemons, pokemonHandler.BatchPokemons},
+		{http.MethodGet, "/pokemons/export", "", pokemonHandler.ExportPokemons},
+		{http.MethodGet, "/pokemons/:id", "", pokemonHandler.GetPokemonByID},
+		{http.MethodPut, "/pokemons/:id", auth.WritePokemons, pokemonHandler.UpdatePokemonByID},
+		{http.MethodPatch, "/pokemons/:id", auth.WritePokemons, pokemonHandler.PatchPokemon},
+		{http.MethodDelete, "/pokemons/:id", auth.WritePokemons, pokemonHandler.DeletePokemonByID},
+		{http.MethodDelete, "/pokemons", auth.DeleteAllPokemons, pokemonHandler.DeleteAllPokemons},
 
-func adminBasicAuth(c *gin.Context) {
-	auth := strings.SplitN(c.Request.Header.Get("Authorization"), " ", 2)
+		{http.MethodGet, "/pokemons/:id/evolutions", "", evolutionHandler.GetPokemonEvolutions},
+		{http.MethodGet, "/pokemons/:id/weaknesses", "", pokemonHandler.GetPokemonWeaknesses},
+		{http.MethodGet, "/pokemons/:id/history", "", pokemonHandler.GetPokemonHistory},
+		{http.MethodGet, "/pokemons/:id/history/:rev", "", pokemonHandler.GetPokemonRevision},
+		{http.MethodGet, "/pokemons/:id/diff", "", pokemonHandler.GetPokemonDiff},
+		{http.MethodPost, "/pokemons/:id/revert/:rev", auth.WritePokemons, pokemonHandler.RevertPokemon},
+		{http.MethodGet, "/trash", auth.WritePokemons, pokemonHandler.GetTrash},
+		{http.MethodPost, "/trash/:id/restore", auth.WritePokemons, pokemonHandler.RestorePokemon},
+		{http.MethodDelete, "/trash/:id", auth.PurgePokemons, pokemonHandler.PurgePokemon},
+		{http.MethodDelete, "/trash", auth.PurgePokemons, pokemonHandler.EmptyTrash},
+		{http.MethodGet, "/types", "", typechart.GetTypes},
+		{http.MethodGet, "/types/matchup", "", typechart.GetTypeMatchup},
+		{http.MethodPost, "/evolutions", auth.WritePokemons, evolutionHandler.PostChain},
+		{http.MethodGet, "/evolutions", "", evolutionHandler.GetChains},
+		{http.MethodGet, "/evolutions/:id", "", evolutionHandler.GetChainByID},
+		{http.MethodPut, "/evolutions/:id", auth.WritePokemons, evolutionHandler.UpdateChainByID},
+		{http.MethodDelete, "/evolutions/:id", auth.WritePokemons, evolutionHandler.DeleteChainByID},
 
-	if len(auth) != 2 || auth[0] != "Basic" {
-		respondWithError(401, "Unauthorized", c)
-		return
+		{http.MethodPost, "/users", auth.ManageUsers, userHandler.PostUser},
+		{http.MethodGet, "/users", auth.ManageUsers, userHandler.GetUsers},
+		{http.MethodGet, "/users/:id", auth.ManageUsers, userHandler.GetUserByLogin},
+		{http.MethodPut, "/users/:id", auth.ManageUsers, userHandler.UpdateUserByLogin},
+		{http.MethodPatch, "/users/:id", auth.ManageUsers, userHandler.PatchUserByLogin},
+		{http.MethodDelete, "/users/:id", auth.ManageUsers, userHandler.DeleteUserByLogin},
+		{http.MethodGet, "/audit", auth.ReadAudit, auditLog.GetAudit},
+		{http.MethodGet, "/users/:id/lockout", auth.ManageUsers, lockout.GetLockout},
+		{http.MethodDelete, "/users/:id/lockout", auth.ManageUsers, lockout.ClearLockout},
+	}
+	limited := newRateLimiter().Middleware()
+	for _, r := range routes {
+		if r.permission == "" {
+			router.Handle(r.method, r.path, auth.Optional(authenticated), limited, r.handler)
+			continue
+		}
+		router.Handle(r.method, r.path, authenticated, limited, auth.Require(r.permission), r.handler)
 	}
 
-	payload, _ := base64.StdEncoding.DecodeString(auth[1])
-	pair := strings.SplitN(string(payload), ":", 2)
+	// use ginSwagger middleware to serve the API docs
+	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	if len(pair) != 2 || !authenticateUser(pair[0], pair[1]) {
-		respondWithError(401, "You have not rights", c)
-		return
+	srv := &http.Server{
+		Addr:    config.Conf.URL,
+		Handler: router,
 	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	// wait for an interrupt, then let in-flight requests finish before the storage is closed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go pokemons.PurgeExpired(ctx, store.pokemons,
+		time.Duration(config.Conf.TrashRetention)*time.Second, time.Duration(config.Conf.TrashPurgeInterval)*time.Second)
+	<-ctx.Done()
+	fmt.Println("Shutting down the server...")
 
-	c.Next()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		fmt.Println(err)
+	}
 }
 
-func authenticateUser(user, password string) bool {
-	if user == config.Conf.UserName && password == config.Conf.Password {
-		return true
+// newRateLimiter returns a Limiter applying the RateLimits of the config file.
+func newRateLimiter() *ratelimit.Limiter {
+	quotas := make(map[string]ratelimit.Quota)
+	var fallback *ratelimit.Quota
+	for route, limit := range config.Conf.RateLimits {
+		q := ratelimit.Quota{Rate: float64(limit.PerMinute) / 60, Burst: limit.Burst}
+		if route == config.DefaultRateLimit {
+			fallback = &q
+			continue
+		}
+		quotas[route] = q
 	}
-	return false
+	return ratelimit.NewLimiter(ratelimit.NewMemoryStore(), quotas, fallback)
 }
 
-func respondWithError(code int, message string, c *gin.Context) {
-	resp := map[string]string{"error": message}
+// storage holds the repositories selected by the StorageBackend config key.
+type storage struct {
+	pokemons   pokemons.PokemonRepository
+	history    pokemons.HistoryRepository
+	users      users.UserRepository
+	evolutions evolutions.ChainRepository
+	tokens     tokens.TokenRepository
+	apiKeys    apikeys.KeyRepository
+	audit      audit.Repository
+	// close releases the connections or files opened for the repositories.
+	close func()
+}
 
-	c.JSON(code, resp)
-	c.Abort()
+func openStorage() storage {
+	switch config.Conf.StorageBackend {
+	case config.MongoBackend:
+		client, err := config.NewMongoClient()
+		if err != nil {
+			log.Fatal(err)
+		}
+		db := client.Database(config.Conf.DatabaseName)
+		pokemonRepo, err := pokemons.NewMongoRepository(db.Collection(config.Conf.CollectionName))
+		if err != nil {
+			log.Fatal(err)
+		}
+		historyRepo, err := pokemons.NewMongoHistory(db.Collection(config.Conf.HistoryCollecName))
+		if err != nil {
+			log.Fatal(err)
+		}
+		evolutionRepo, err := evolutions.NewMongoRepository(db.Collection(config.Conf.EvolutionCollecName))
+		if err != nil {
+			log.Fatal(err)
+		}
+		tokenRepo, err := tokens.NewMongoRepository(db.Collection(config.Conf.TokenCollecName), db.Collection(config.Conf.RevokedTokenCollecName))
+		if err != nil {
+			log.Fatal(err)
+		}
+		apiKeyRepo, err := apikeys.NewMongoRepository(db.Collection(config.Conf.APIKeyCollecName))
+		if err != nil {
+			log.Fatal(err)
+		}
+		userRepo, err := users.NewMongoRepository(db.Collection(config.Conf.UserCollecName))
+		if err != nil {
+			log.Fatal(err)
+		}
+		auditRepo, err := audit.NewMongoRepository(db.Collection(config.Conf.AuditCollecName))
+		if err != nil {
+			log.Fatal(err)
+		}
+		return storage{
+			pokemons:   pokemonRepo,
+			history:    historyRepo,
+			users:      userRepo,
+			evolutions: evolutionRepo,
+			tokens:     tokenRepo,
+			apiKeys:    apiKeyRepo,
+			audit:      auditRepo,
+			close: func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				if err := client.Disconnect(ctx); err != nil {
+					fmt.Println(err)
+				}
+			},
+		}
+	case config.MemoryBackend:
+		return storage{
+			pokemons:   pokemons.NewMemoryRepository(),
+			history:    pokemons.NewMemoryHistory(),
+			users:      users.NewMemoryRepository(),
+			evolutions: evolutions.NewMemoryRepository(),
+			tokens:     tokens.NewMemoryRepository(),
+			apiKeys:    apikeys.NewMemoryRepository(),
+			audit:      audit.NewMemoryRepository(),
+			close:      func() {},
+		}
+	case config.BoltBackend:
+		db, err := config.OpenBoltDB(config.Conf.BoltPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		pokemonRepo, err := pokemons.NewBoltRepository(db, config.Conf.CollectionName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		historyRepo, err := pokemons.NewBoltHistory(db, config.Conf.HistoryCollecName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		userRepo, err := users.NewBoltRepository(db, config.Conf.UserCollecName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		evolutionRepo, err := evolutions.NewBoltRepository(db, config.Conf.EvolutionCollecName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		tokenRepo, err := tokens.NewBoltRepository(db, config.Conf.TokenCollecName, config.Conf.RevokedTokenCollecName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		apiKeyRepo, err := apikeys.NewBoltRepository(db, config.Conf.APIKeyCollecName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		auditRepo, err := audit.NewBoltRepository(db, config.Conf.AuditCollecName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return storage{
+			pokemons:   pokemonRepo,
+			history:    historyRepo,
+			users:      userRepo,
+			evolutions: evolutionRepo,
+			tokens:     tokenRepo,
+			apiKeys:    apiKeyRepo,
+			audit:      auditRepo,
+			close:      func() { db.Close() },
+		}
+	}
+	log.Fatal("Unknown storage backend: ", config.Conf.StorageBackend)
+	return storage{}
 }
@@ -0,0 +1,278 @@
+package pokemons
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/pokemon-handbook/audit"
+)
+
+// maxBatchOperations is the largest number of operations in a batch.
+const maxBatchOperations = 1000
+
+// Operations of a batch.
+const (
+	batchCreate = "create"
+	batchUpdate = "update"
+	batchDelete = "delete"
+)
+
+// How a batch is applied.
+const (
+	// batchAtomic applies all the operations or none of them.
+	batchAtomic = "atomic"
+	// batchOrdered applies the operations one by one and stops at the first which fails, keeping the others.
+	batchOrdered = "ordered"
+)
+
+type batchRequest struct {
+	Mode       string           `json:"mode" enums:"atomic,ordered" default:"atomic"`
+	Operations []batchOperation `json:"operations"`
+}
+
+// batchOperation is a request to POST, PUT or DELETE a pokemon.
+type batchOperation struct {
+	Op string `json:"op" enums:"create,update,delete"`
+	// ID names the pokemon to update or delete.
+	ID int64 `json:"id,omitempty" example:"25"`
+	// Pokemon is the new pokemon or the new state of the updated one.
+	Pokemon *pokemon `json:"pokemon,omitempty"`
+}
+
+// batchResult is the result of an operation. Status and Message are what the single request would respond with.
+type batchResult struct {
+	Index   int    `json:"index" example:"0"`
+	Op      string `json:"op" enums:"create,update,delete"`
+	ID      int64  `json:"id" example:"25"`
+	Status  int    `json:"status" example:"200"`
+	Message string `json:"message,omitempty" example:"pokemon was updated"`
+}
+
+type batchResponse struct {
+	Mode string `json:"mode" enums:"atomic,ordered"`
+	// Applied is the number of operations whose changes were kept.
+	Applied int           `json:"applied" example:"3"`
+	Results []batchResult `json:"results"`
+}
+
+// batchChange is a change made by an operation, recorded once it is kept.
+type batchChange struct {
+	revision string
+	action   string
+	id       int64
+	before   interface{}
+	after    *pokemon
+}
+
+// errBatchFailed makes Atomically discard the changes of a batch in which an operation failed.
+var errBatchFailed = errors.New("an operation of the batch failed")
+
+// BatchPokemons godoc
+// @title        Batch Pokemons
+// @summary      Create, update and delete many pokemons at once
+// @description  Apply a list of operations, each behaving like POST /pokemons, PUT /pokemons/{id} or DELETE /pokemons/{id}. In atomic mode, the default, either all operations are applied or none: an operation that fails discards the changes of the others. MongoDB needs a replica set for that. In ordered mode the operations are applied one by one until one fails and the changes of those before it are kept.
+// @description  The response lists the result of every operation. Its status is 200 when all operations were applied and the status of the first failed operation otherwise. Operations which were not applied or were discarded have the status 424.
+// @accept       json
+// @produce      json
+// @param        batch  body  batchRequest  true  "the operations"
+// @success      200 {object} batchResponse
+// @failure      400 {string} string "object can't be parsed into JSON"
+// @failure      400 {object} batchResponse "an operation is invalid"
+// @failure      404 {object} batchResponse "a pokemon to delete doesn't exist"
+// @failure      409 {object} batchResponse "an operation conflicts with a stored pokemon"
+// @failure      501 {string} string "the database doesn't support transactions"
+// @router       /pokemons/batch [post]
+func (h *Handler) BatchPokemons(c *gin.Context) {
+	var req batchRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "object can't be parsed into JSON"})
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = batchAtomic
+	}
+	if req.Mode != batchAtomic && req.Mode != batchOrdered {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "mode must be atomic or ordered"})
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchOperations {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("a batch must have from 1 to %d operations", maxBatchOperations)})
+		return
+	}
+
+	var results []batchResult
+	var changes []batchChange
+	// apply runs the operations until one fails and tells its index, or -1
+	apply := func(ctx context.Context, repo PokemonRepository) (int, error) {
+		results, changes = make([]batchResult, 0, len(req.Operations)), nil
+		for i, op := range req.Operations {
+			res, change, err := h.applyOperation(ctx, repo, op)
+			if err != nil {
+				return i, err
+			}
+			res.Index = i
+			results = append(results, res)
+			if res.Status >= http.StatusMultipleChoices {
+				return i, nil
+			}
+			changes = append(changes, change)
+		}
+		return -1, nil
+	}
+
+	failed := -1
+	var err error
+	if req.Mode == batchAtomic {
+		err = h.repo.Atomically(c.Request.Context(), func(ctx context.Context, repo PokemonRepository) error {
+			if failed, err = apply(ctx, repo); err == nil && failed >= 0 {
+				return errBatchFailed
+			}
+			return err
+		})
+		if err != nil {
+			changes = nil
+		}
+		if errors.Is(err, errBatchFailed) {
+			err = nil
+			for i := range results[:failed] {
+				results[i].Status = http.StatusFailedDependency
+				results[i].Message = fmt.Sprintf("discarded because operation %d failed", failed)
+			}
+		}
+	} else {
+		failed, err = apply(c.Request.Context(), h.repo)
+	}
+	if errors.Is(err, ErrTransactionsUnsupported) {
+		c.IndentedJSON(http.StatusNotImplemented, gin.H{"message": err.Error() + ", use the ordered mode"})
+		return
+	}
+	// in ordered mode the operations before a failing one are kept, even when the repository failed
+	h.recordBatch(c, changes)
+	if err != nil {
+		respondWithInternalError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if failed >= 0 {
+		status = results[failed].Status
+		for i := failed + 1; i < len(req.Operations); i++ {
+			op := req.Operations[i]
+			results = append(results, batchResult{
+				Index:   i,
+				Op:      op.Op,
+				ID:      op.target(),
+				Status:  http.StatusFailedDependency,
+				Message: fmt.Sprintf("not applied because operation %d failed", failed),
+			})
+		}
+	}
+	c.IndentedJSON(status, batchResponse{Mode: req.Mode, Applied: len(changes), Results: results})
+}
+
+// target returns the id of the pokemon an operation changes.
+func (op batchOperation) target() int64 {
+	if op.Op == batchCreate && op.Pokemon != nil {
+		return op.Pokemon.ID
+	}
+	return op.ID
+}
+
+// applyOperation applies op to repo. Failures of the operation are told by the status of the result,
+// the error is only set when the repository fails.
+func (h *Handler) applyOperation(ctx context.Context, repo PokemonRepository, op batchOperation) (batchResult, batchChange, error) {
+	res := batchResult{Op: op.Op, ID: op.target()}
+	fail := func(status int, message string) (batchResult, batchChange, error) {
+		res.Status, res.Message = status, message
+		return res, batchChange{}, nil
+	}
+
+	switch op.Op {
+	case batchCreate, batchUpdate:
+		if op.Pokemon == nil {
+			return fail(http.StatusBadRequest, "the operation needs a pokemon")
+		}
+		p := *op.Pokemon
+		if op.Op == batchUpdate && p.ID != op.ID {
+			return fail(http.StatusNotAcceptable, "pokemon's id cannot be changed")
+		}
+		if err := p.prepare(); err != nil {
+			return fail(http.StatusBadRequest, err.Error())
+		}
+
+		if op.Op == batchCreate {
+			err := repo.Create(ctx, &p)
+			if errors.Is(err, ErrDuplicateID) || errors.Is(err, ErrDuplicateName) {
+				return fail(http.StatusConflict, err.Error())
+			}
+			if err != nil {
+				return res, batchChange{}, err
+			}
+			res.Status = http.StatusCreated
+			return res, batchChange{revisionCreate, audit.ActionCreate, p.ID, nil, &p}, nil
+		}
+
+		var before interface{}
+		if old, err := repo.Get(ctx, p.ID); err == nil {
+			before = old
+		} else if !errors.Is(err, ErrNotFound) {
+			return res, batchChange{}, err
+		}
+		created, err := repo.Upsert(ctx, &p, 0)
+		if errors.Is(err, ErrDuplicateName) {
+			return fail(http.StatusConflict, err.Error())
+		}
+		if err != nil {
+			return res, batchChange{}, err
+		}
+		if created {
+			res.Status = http.StatusCreated
+			return res, batchChange{revisionCreate, audit.ActionCreate, p.ID, before, &p}, nil
+		}
+		res.Status, res.Message = http.StatusOK, "pokemon was updated"
+		return res, batchChange{revisionUpdate, audit.ActionUpdate, p.ID, before, &p}, nil
+
+	case batchDelete:
+		for _, ref := range h.refs {
+			referenced, err := ref.PokemonReferenced(ctx, op.ID)
+			if err != nil {
+				return res, batchChange{}, err
+			}
+			if referenced {
+				return fail(http.StatusConflict, "the pokemon is referred to by another resource")
+			}
+		}
+		before, err := repo.Get(ctx, op.ID)
+		if err == nil {
+			err = repo.Delete(ctx, op.ID, 0, time.Now().UTC().Truncate(time.Millisecond))
+		}
+		if errors.Is(err, ErrNotFound) {
+			return fail(http.StatusNotFound, "pokemon not found")
+		}
+		if err != nil {
+			return res, batchChange{}, err
+		}
+		res.Status, res.Message = http.StatusOK, "pokemon was deleted"
+		return res, batchChange{revisionDelete, audit.ActionDelete, op.ID, before, nil}, nil
+
+	default:
+		return fail(http.StatusBadRequest, "op must be create, update or delete")
+	}
+}
+
+// recordBatch records the revisions and audit entries of the changes a batch kept.
+func (h *Handler) recordBatch(c *gin.Context, changes []batchChange) {
+	for _, ch := range changes {
+		h.recordRevision(c, ch.revision, ch.id, ch.after, 0)
+		var after interface{}
+		if ch.after != nil {
+			after = *ch.after
+		}
+		h.auditLog.Record(c, ch.action, resource(ch.id), ch.before, after)
+	}
+}
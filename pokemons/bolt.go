@@ -0,0 +1,429 @@
+package pokemons
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type boltRepository struct {
+	db     *bbolt.DB
+	bucket []byte
+	// tx is the transaction of Atomically, nil outside of it.
+	tx *bbolt.Tx
+}
+
+// NewBoltRepository returns a PokemonRepository that stores pokemons in the given bucket of a bolt database file.
+// Documents are encoded as BSON and keyed by id, so the bucket is always ordered by id.
+func NewBoltRepository(db *bbolt.DB, bucket string) (PokemonRepository, error) {
+	r := &boltRepository{db: db, bucket: []byte(bucket)}
+	err := db.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(r.bucket)
+		if err != nil {
+			return err
+		}
+		return r.backfillSlugs(b)
+	})
+	return r, err
+}
+
+// backfillSlugs sets the slug of pokemons stored before names had slugs, so that they can be looked up by it.
+func (r *boltRepository) backfillSlugs(b *bbolt.Bucket) error {
+	// the bucket can't be changed while iterating with ForEach, so the pokemons are collected first
+	var pokemons []pokemon
+	err := b.ForEach(func(k, v []byte) error {
+		result := pokemon{}
+		if err := bson.Unmarshal(v, &result); err != nil {
+			return err
+		}
+		if result.Slug == "" {
+			pokemons = append(pokemons, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, p := range pokemons {
+		p.Slug = slugify(p.Name)
+		if err := r.put(b, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// update runs fn in a read-write transaction, or in the transaction of Atomically.
+func (r *boltRepository) update(fn func(tx *bbolt.Tx) error) error {
+	if r.tx != nil {
+		return fn(r.tx)
+	}
+	return r.db.Update(fn)
+}
+
+// view runs fn in a read-only transaction, or in the transaction of Atomically.
+func (r *boltRepository) view(fn func(tx *bbolt.Tx) error) error {
+	if r.tx != nil {
+		return fn(r.tx)
+	}
+	return r.db.View(fn)
+}
+
+// Atomically runs fn in a single read-write transaction, which is rolled back when fn fails.
+// Other writers wait for it to end.
+func (r *boltRepository) Atomically(ctx context.Context, fn func(ctx context.Context, repo PokemonRepository) error) error {
+	if r.tx != nil {
+		return fn(ctx, r)
+	}
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		return fn(ctx, &boltRepository{db: r.db, bucket: r.bucket, tx: tx})
+	})
+}
+
+// boltKey encodes id so that the byte order of keys matches the numeric order of ids.
+func boltKey(id int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(id)^(1<<63))
+	return key
+}
+
+func (r *boltRepository) put(b *bbolt.Bucket, p pokemon) error {
+	data, err := bson.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return b.Put(boltKey(p.ID), data)
+}
+
+// findSlug returns the id of the pokemon whose name has the given slug.
+// Names are not indexed, so the whole bucket is scanned.
+func (r *boltRepository) findSlug(b *bbolt.Bucket, slug string) (int64, bool, error) {
+	var id int64
+	found := false
+	err := b.ForEach(func(k, v []byte) error {
+		if found {
+			return nil
+		}
+		result := pokemon{}
+		if err := bson.Unmarshal(v, &result); err != nil {
+			return err
+		}
+		if slugify(result.Name) == slug {
+			id, found = result.ID, true
+		}
+		return nil
+	})
+	return id, found, err
+}
+
+func (r *boltRepository) Create(ctx context.Context, p *pokemon) error {
+	return r.update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		if b.Get(boltKey(p.ID)) != nil {
+			return ErrDuplicateID
+		}
+		_, found, err := r.findSlug(b, p.Slug)
+		if err != nil {
+			return err
+		}
+		if found {
+			return ErrDuplicateName
+		}
+		p.stamp(1)
+		return r.put(b, *p)
+	})
+}
+
+// CreateMany writes the whole batch in one transaction. The slugs of the stored names are read once
+// instead of scanning the bucket for every pokemon as Create and Upsert do.
+func (r *boltRepository) CreateMany(ctx context.Context, pokemons []pokemon, overwrite, ordered bool) ([]writeResult, error) {
+	results := make([]writeResult, 0, len(pokemons))
+	err := r.update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		slugs := make(map[string]int64)
+		err := b.ForEach(func(k, v []byte) error {
+			result := pokemon{}
+			if err := bson.Unmarshal(v, &result); err != nil {
+				return err
+			}
+			slugs[slugify(result.Name)] = result.ID
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for i := range pokemons {
+			p := &pokemons[i]
+			old, err := r.get(b, p.ID)
+			if err != nil && err != ErrNotFound {
+				return err
+			}
+			exists := err == nil
+
+			var res writeResult
+			if id, found := slugs[p.Slug]; found && id != p.ID {
+				res.Err = ErrDuplicateName
+			}
+			if exists && !overwrite {
+				res.Err = ErrDuplicateID
+			}
+			if res.Err == nil {
+				p.stamp(old.Version + 1)
+				if err := r.put(b, *p); err != nil {
+					return err
+				}
+				if exists {
+					delete(slugs, slugify(old.Name))
+				}
+				slugs[p.Slug] = p.ID
+				res.Created = !exists || old.DeletedAt != nil
+			}
+			results = append(results, res)
+			if res.Err != nil && ordered {
+				break
+			}
+		}
+		return nil
+	})
+	return results, err
+}
+
+// get returns the pokemon with the given id, in the trash or not.
+func (r *boltRepository) get(b *bbolt.Bucket, id int64) (pokemon, error) {
+	result := pokemon{}
+	data := b.Get(boltKey(id))
+	if data == nil {
+		return result, ErrNotFound
+	}
+	err := bson.Unmarshal(data, &result)
+	return result, err
+}
+
+func (r *boltRepository) Get(ctx context.Context, id int64) (pokemon, error) {
+	result := pokemon{}
+	err := r.view(func(tx *bbolt.Tx) error {
+		var err error
+		result, err = r.get(tx.Bucket(r.bucket), id)
+		if err == nil && result.DeletedAt != nil {
+			err = ErrNotFound
+		}
+		return err
+	})
+	return result, err
+}
+
+func (r *boltRepository) GetBySlug(ctx context.Context, slug string) (pokemon, error) {
+	result := pokemon{}
+	err := r.view(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		id, found, err := r.findSlug(b, slug)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrNotFound
+		}
+		if err := bson.Unmarshal(b.Get(boltKey(id)), &result); err != nil {
+			return err
+		}
+		if result.DeletedAt != nil {
+			return ErrNotFound
+		}
+		return nil
+	})
+	return result, err
+}
+
+// all returns in id order the stored pokemons which are in the trash or not, as deleted tells.
+func (r *boltRepository) all(deleted bool) ([]pokemon, error) {
+	var pokemons = []pokemon{}
+	err := r.view(func(tx *bbolt.Tx) error {
+		return tx.Bucket(r.bucket).ForEach(func(k, v []byte) error {
+			result := pokemon{}
+			if err := bson.Unmarshal(v, &result); err != nil {
+				return err
+			}
+			if (result.DeletedAt != nil) == deleted {
+				pokemons = append(pokemons, result)
+			}
+			return nil
+		})
+	})
+	return pokemons, err
+}
+
+func (r *boltRepository) List(ctx context.Context, q ListQuery) ([]pokemon, int64, error) {
+	pokemons, err := r.all(false)
+	if err != nil {
+		return nil, 0, err
+	}
+	return applyQuery(pokemons, q)
+}
+
+// Each doesn't keep a read transaction open while fn runs, as long read transactions keep bolt from
+// growing the database file.
+func (r *boltRepository) Each(ctx context.Context, q ListQuery, fn func(pokemon) error) error {
+	pokemons, err := r.all(false)
+	if err != nil {
+		return err
+	}
+	return eachMatching(pokemons, q, fn)
+}
+
+func (r *boltRepository) Search(ctx context.Context, query string, limit int) ([]searchResult, error) {
+	pokemons, err := r.all(false)
+	if err != nil {
+		return nil, err
+	}
+	return rankPokemons(query, pokemons, limit), nil
+}
+
+func (r *boltRepository) Upsert(ctx context.Context, p *pokemon, version int64) (bool, error) {
+	created := false
+	err := r.update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		old, err := r.get(b, p.ID)
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+		created = err == ErrNotFound || old.DeletedAt != nil
+		if version != 0 && (created || old.Version != version) {
+			return ErrVersionMismatch
+		}
+		id, found, err := r.findSlug(b, p.Slug)
+		if err != nil {
+			return err
+		}
+		if found && id != p.ID {
+			return ErrDuplicateName
+		}
+		p.stamp(old.Version + 1)
+		return r.put(b, *p)
+	})
+	return created, err
+}
+
+func (r *boltRepository) Delete(ctx context.Context, id int64, version int64, at time.Time) error {
+	return r.update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		p, err := r.get(b, id)
+		if err != nil {
+			return err
+		}
+		if p.DeletedAt != nil {
+			return ErrNotFound
+		}
+		if version != 0 && p.Version != version {
+			return ErrVersionMismatch
+		}
+		p.DeletedAt = &at
+		p.stamp(p.Version + 1)
+		return r.put(b, p)
+	})
+}
+
+func (r *boltRepository) DeleteAll(ctx context.Context, at time.Time) ([]int64, error) {
+	ids := []int64{}
+	err := r.update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		// the bucket can't be changed while iterating with ForEach, so the pokemons are collected first
+		var pokemons []pokemon
+		err := b.ForEach(func(k, v []byte) error {
+			result := pokemon{}
+			if err := bson.Unmarshal(v, &result); err != nil {
+				return err
+			}
+			if result.DeletedAt == nil {
+				pokemons = append(pokemons, result)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, p := range pokemons {
+			p.DeletedAt = &at
+			p.stamp(p.Version + 1)
+			if err := r.put(b, p); err != nil {
+				return err
+			}
+			ids = append(ids, p.ID)
+		}
+		return nil
+	})
+	return ids, err
+}
+
+func (r *boltRepository) ListDeleted(ctx context.Context) ([]pokemon, error) {
+	pokemons, err := r.all(true)
+	sortByDeletion(pokemons)
+	return pokemons, err
+}
+
+func (r *boltRepository) Restore(ctx context.Context, id int64) (pokemon, error) {
+	result := pokemon{}
+	err := r.update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		var err error
+		if result, err = r.get(b, id); err != nil {
+			return err
+		}
+		if result.DeletedAt == nil {
+			return ErrNotFound
+		}
+		result.DeletedAt = nil
+		result.stamp(result.Version + 1)
+		return r.put(b, result)
+	})
+	return result, err
+}
+
+func (r *boltRepository) Purge(ctx context.Context, id int64) (pokemon, error) {
+	result := pokemon{}
+	err := r.update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		var err error
+		if result, err = r.get(b, id); err != nil {
+			return err
+		}
+		if result.DeletedAt == nil {
+			return ErrNotFound
+		}
+		return b.Delete(boltKey(id))
+	})
+	return result, err
+}
+
+func (r *boltRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	err := r.update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		// as in DeleteAll, the keys are collected before the bucket is changed
+		var keys [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			result := pokemon{}
+			if err := bson.Unmarshal(v, &result); err != nil {
+				return err
+			}
+			if result.DeletedAt != nil && result.DeletedAt.Before(before) {
+				keys = append(keys, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		purged = int64(len(keys))
+		return nil
+	})
+	return purged, err
+}
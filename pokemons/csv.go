@@ -0,0 +1,162 @@
+package pokemons
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// csvColumns are the columns of pokemons in CSV files, named like the json fields. Base stats get a column
+// each. Abilities are separated by semicolons and the hidden ability, if any, has a column of its own.
+var csvColumns = []string{
+	"id", "name", "is_legendary", "color", "primary_type", "secondary_type",
+	"hp", "attack", "defense", "special_attack", "special_defense", "speed",
+	"abilities", "hidden_ability", "height", "weight", "generation", "genus",
+}
+
+// abilitySeparator separates the abilities of a pokemon in the abilities column.
+const abilitySeparator = ";"
+
+// statColumns are the columns of the base stats, which are all known or all unknown.
+var statColumns = []string{"hp", "attack", "defense", "special_attack", "special_defense", "speed"}
+
+// csvHeader maps the columns of a CSV header to their positions. Every column must be known, id is required
+// and the others may be left out, but the base stat columns come together.
+func csvHeader(header []string) (map[string]int, error) {
+	known := make(map[string]bool, len(csvColumns))
+	for _, column := range csvColumns {
+		known[column] = true
+	}
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !known[column] {
+			return nil, fmt.Errorf("unknown column %q", column)
+		}
+		if _, ok := columns[column]; ok {
+			return nil, fmt.Errorf("column %q is repeated", column)
+		}
+		columns[column] = i
+	}
+	if _, ok := columns["id"]; !ok {
+		return nil, fmt.Errorf("column %q is required", "id")
+	}
+	stats := 0
+	for _, column := range statColumns {
+		if _, ok := columns[column]; ok {
+			stats++
+		}
+	}
+	if stats > 0 && stats < len(statColumns) {
+		return nil, fmt.Errorf("the base stat columns %s must be given together", strings.Join(statColumns, ", "))
+	}
+	return columns, nil
+}
+
+// parseCSVRecord reads a pokemon from a CSV record with the given columns. Empty fields leave the pokemon
+// field at its zero value, which means unknown, but the base stats must all be empty or all be filled.
+func parseCSVRecord(columns map[string]int, record []string) (pokemon, error) {
+	var p pokemon
+	field := func(column string) string {
+		if i, ok := columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var err error
+	setInt := func(column string, dst *int) {
+		if s := field(column); s != "" && err == nil {
+			if *dst, err = strconv.Atoi(s); err != nil {
+				err = fmt.Errorf("%s must be a whole number", column)
+			}
+		}
+	}
+	setFloat := func(column string, dst *float64) {
+		if s := field(column); s != "" && err == nil {
+			if *dst, err = strconv.ParseFloat(s, 64); err != nil {
+				err = fmt.Errorf("%s must be a number", column)
+			}
+		}
+	}
+
+	empty := 0
+	for _, column := range statColumns {
+		if field(column) == "" {
+			empty++
+		}
+	}
+	if empty > 0 && empty < len(statColumns) {
+		return p, errors.New("the base stats must all be filled or all be left empty")
+	}
+
+	if p.ID, err = strconv.ParseInt(field("id"), 10, 64); err != nil {
+		return p, errors.New("id must be a whole number")
+	}
+	if s := field("is_legendary"); s != "" {
+		if p.IsLegendary, err = strconv.ParseBool(s); err != nil {
+			return p, errors.New("is_legendary must be true or false")
+		}
+	}
+	p.Name = field("name")
+	p.Color = field("color")
+	p.PrimaryType = field("primary_type")
+	p.SecondaryType = field("secondary_type")
+	p.Genus = field("genus")
+
+	setInt("hp", &p.BaseStats.HP)
+	setInt("attack", &p.BaseStats.Attack)
+	setInt("defense", &p.BaseStats.Defense)
+	setInt("special_attack", &p.BaseStats.SpecialAttack)
+	setInt("special_defense", &p.BaseStats.SpecialDefense)
+	setInt("speed", &p.BaseStats.Speed)
+	setInt("generation", &p.Generation)
+	setFloat("height", &p.Height)
+	setFloat("weight", &p.Weight)
+	if err != nil {
+		return p, err
+	}
+
+	if s := field("abilities"); s != "" {
+		for _, name := range strings.Split(s, abilitySeparator) {
+			p.Abilities = append(p.Abilities, ability{Name: strings.TrimSpace(name)})
+		}
+	}
+	if s := field("hidden_ability"); s != "" {
+		p.Abilities = append(p.Abilities, ability{Name: s, Hidden: true})
+	}
+	return p, nil
+}
+
+// csvRecord writes p as a record with all of csvColumns. Unknown values are left empty.
+func csvRecord(p pokemon) []string {
+	var abilities []string
+	hidden := ""
+	for _, a := range p.Abilities {
+		if a.Hidden {
+			hidden = a.Name
+			continue
+		}
+		abilities = append(abilities, a.Name)
+	}
+	number := func(n int) string {
+		if n == 0 {
+			return ""
+		}
+		return strconv.Itoa(n)
+	}
+	decimal := func(f float64) string {
+		if f == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	return []string{
+		strconv.FormatInt(p.ID, 10), p.Name, strconv.FormatBool(p.IsLegendary), p.Color, p.PrimaryType, p.SecondaryType,
+		number(p.BaseStats.HP), number(p.BaseStats.Attack), number(p.BaseStats.Defense),
+		number(p.BaseStats.SpecialAttack), number(p.BaseStats.SpecialDefense), number(p.BaseStats.Speed),
+		strings.Join(abilities, abilitySeparator), hidden, decimal(p.Height), decimal(p.Weight), number(p.Generation), p.Genus,
+	}
+}
@@ -0,0 +1,67 @@
+package pokemons
+
+import "testing"
+
+func TestCSVHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		ok     bool
+	}{
+		{"id only", []string{"id"}, true},
+		{"every column", csvColumns, true},
+		{"case and spaces", []string{" ID ", "Name"}, true},
+		{"all base stats", append([]string{"id"}, statColumns...), true},
+		{"some base stats", []string{"id", "hp", "speed"}, false},
+		{"no id", []string{"name"}, false},
+		{"unknown column", []string{"id", "nickname"}, false},
+		{"repeated column", []string{"id", "name", "NAME"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := csvHeader(tt.header)
+			if (err == nil) != tt.ok {
+				t.Errorf("csvHeader(%q) error = %v, want ok %v", tt.header, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseCSVRecord(t *testing.T) {
+	columns, err := csvHeader(csvColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := func(fields map[string]string) []string {
+		r := make([]string, len(csvColumns))
+		for i, column := range csvColumns {
+			r[i] = fields[column]
+		}
+		return r
+	}
+	stats := map[string]string{"id": "25", "hp": "35", "attack": "55", "defense": "40", "special_attack": "50", "special_defense": "50", "speed": "90"}
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   baseStats
+		ok     bool
+	}{
+		{"no base stats", map[string]string{"id": "25"}, baseStats{}, true},
+		{"all base stats", stats, baseStats{35, 55, 40, 50, 50, 90}, true},
+		{"some base stats", map[string]string{"id": "25", "hp": "35"}, baseStats{}, false},
+		{"invalid base stat", map[string]string{"id": "25", "hp": "x", "attack": "1", "defense": "1", "special_attack": "1", "special_defense": "1", "speed": "1"}, baseStats{}, false},
+		{"invalid id", map[string]string{"id": "pikachu"}, baseStats{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := parseCSVRecord(columns, record(tt.fields))
+			if (err == nil) != tt.ok {
+				t.Fatalf("error = %v, want ok %v", err, tt.ok)
+			}
+			if tt.ok && p.BaseStats != tt.want {
+				t.Errorf("base stats = %+v, want %+v", p.BaseStats, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package pokemons
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// change is a field that differs between two revisions of a pokemon.
+type change struct {
+	// Field is the json path of the field, with nested fields separated by dots.
+	Field string      `json:"field" example:"base_stats.hp"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// revisionDiff is returned by GetPokemonDiff.
+type revisionDiff struct {
+	PokemonID int64    `json:"pokemon_id" example:"25"`
+	From      int      `json:"from" example:"1"`
+	To        int      `json:"to" example:"2"`
+	Changes   []change `json:"changes"`
+}
+
+// diffPokemons returns the fields that differ between a and b in their json form, sorted by path.
+// A nil pokemon has no fields. Objects are compared field by field and arrays as a whole.
+func diffPokemons(a, b *pokemon) ([]change, error) {
+	from, err := flatten(a)
+	if err != nil {
+		return nil, err
+	}
+	to, err := flatten(b)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []change{}
+	for field, value := range from {
+		if other, ok := to[field]; !ok || !reflect.DeepEqual(value, other) {
+			changes = append(changes, change{Field: field, From: value, To: to[field]})
+		}
+	}
+	for field, value := range to {
+		if _, ok := from[field]; !ok {
+			changes = append(changes, change{Field: field, To: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+// flatten returns the leaves of the json form of p keyed by their dotted path.
+func flatten(p *pokemon) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if p == nil {
+		return fields, nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	flattenInto(fields, "", doc)
+	return fields, nil
+}
+
+func flattenInto(fields map[string]interface{}, prefix string, doc map[string]interface{}) {
+	for key, value := range doc {
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenInto(fields, prefix+key+".", nested)
+			continue
+		}
+		fields[prefix+key] = value
+	}
+}
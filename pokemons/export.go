@@ -0,0 +1,207 @@
+package pokemons
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// formatXLSX is a format pokemons can be exported to but not imported from.
+const formatXLSX = "xlsx"
+
+// exportWriter writes exported pokemons one by one in some format.
+type exportWriter interface {
+	write(p pokemon) error
+	// close ends the output. It doesn't close the underlying writer.
+	close() error
+}
+
+type exportFormat struct {
+	contentType string
+	// newWriter starts the output.
+	newWriter func(w io.Writer) (exportWriter, error)
+}
+
+var exportFormats = map[string]exportFormat{
+	formatCSV:    {"text/csv; charset=utf-8", newCSVExport},
+	formatNDJSON: {"application/x-ndjson", newNDJSONExport},
+	formatJSON:   {"application/json; charset=utf-8", newJSONExport},
+	formatXLSX:   {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", newXLSXExport},
+}
+
+// ExportPokemons godoc
+// @title        Export Pokemons
+// @summary      Download the pokemons
+// @description  Stream the pokemons matching the filters, in the given order, as a file to download. Paging parameters are ignored, the whole selection is exported. CSV and xlsx files have the columns taken by POST /pokemons/import.
+// @produce      json,text/csv,application/x-ndjson,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @param        format        query  string  false  "format of the file"  Enums(csv, ndjson, json, xlsx)  default(json)
+// @param        sort          query  string  false  "field to sort by, prefix with - for descending order"  default(id)
+// @param        is_legendary  query  bool    false  "only legendary or only non-legendary pokemons"
+// @param        color         query  string  false  "only pokemons of this color"
+// @param        name_prefix   query  string  false  "only pokemons whose name starts with this prefix, case-insensitive"
+// @success      200 {array} pokemon
+// @header       200 {string} Content-Disposition "attachment; filename=\"pokemons-20060102-150405.json\""
+// @failure      400 {string} string "invalid query parameters"
+// @router       /pokemons/export [get]
+func (h *Handler) ExportPokemons(c *gin.Context) {
+	name := c.DefaultQuery("format", formatJSON)
+	format, ok := exportFormats[name]
+	if !ok {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "format must be csv, ndjson, json or xlsx"})
+		return
+	}
+	q, err := parseListQuery(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	// the output starts with the first pokemon, so that a failing query still gets an error response
+	var w exportWriter
+	start := func() error {
+		filename := fmt.Sprintf("pokemons-%s.%s", time.Now().UTC().Format("20060102-150405"), name)
+		c.Header("Content-Type", format.contentType)
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Status(http.StatusOK)
+		var err error
+		w, err = format.newWriter(c.Writer)
+		return err
+	}
+	err = h.repo.Each(c.Request.Context(), q, func(p pokemon) error {
+		if w == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return w.write(p)
+	})
+	if err == nil && w == nil {
+		err = start()
+	}
+	if err == nil {
+		err = w.close()
+	}
+	if err == nil {
+		return
+	}
+
+	if !c.Writer.Written() {
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		respondWithInternalError(c, err)
+		return
+	}
+	// the status has been sent, the client is left with a truncated file
+	fmt.Printf("failed to export pokemons: %v\n", err)
+}
+
+type csvExport struct {
+	w *csv.Writer
+}
+
+func newCSVExport(w io.Writer) (exportWriter, error) {
+	cw := csv.NewWriter(w)
+	return &csvExport{w: cw}, cw.Write(csvColumns)
+}
+
+func (e *csvExport) write(p pokemon) error {
+	return e.w.Write(csvRecord(p))
+}
+
+func (e *csvExport) close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonExport struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONExport(w io.Writer) (exportWriter, error) {
+	buf := bufio.NewWriter(w)
+	return &ndjsonExport{buf: buf, enc: json.NewEncoder(buf)}, nil
+}
+
+func (e *ndjsonExport) write(p pokemon) error {
+	return e.enc.Encode(p)
+}
+
+func (e *ndjsonExport) close() error {
+	return e.buf.Flush()
+}
+
+// jsonExport writes an array with a pokemon per line.
+type jsonExport struct {
+	buf   *bufio.Writer
+	count int
+}
+
+func newJSONExport(w io.Writer) (exportWriter, error) {
+	buf := bufio.NewWriter(w)
+	_, err := buf.WriteString("[")
+	return &jsonExport{buf: buf}, err
+}
+
+func (e *jsonExport) write(p pokemon) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		e.buf.WriteString(",")
+	}
+	e.count++
+	e.buf.WriteString("\n")
+	_, err = e.buf.Write(data)
+	return err
+}
+
+func (e *jsonExport) close() error {
+	if _, err := e.buf.WriteString("\n]\n"); err != nil {
+		return err
+	}
+	return e.buf.Flush()
+}
+
+// xlsxColumnKinds are the kinds of the cells in csvColumns.
+var xlsxColumnKinds = map[string]int{
+	"id": xlsxNumber, "is_legendary": xlsxBool,
+	"hp": xlsxNumber, "attack": xlsxNumber, "defense": xlsxNumber,
+	"special_attack": xlsxNumber, "special_defense": xlsxNumber, "speed": xlsxNumber,
+	"height": xlsxNumber, "weight": xlsxNumber, "generation": xlsxNumber,
+}
+
+type xlsxExport struct {
+	x     *xlsxWriter
+	cells []xlsxCell
+}
+
+func newXLSXExport(w io.Writer) (exportWriter, error) {
+	x, err := newXLSXWriter(w, "Pokemons")
+	if err != nil {
+		return nil, err
+	}
+	e := &xlsxExport{x: x, cells: make([]xlsxCell, len(csvColumns))}
+	for i, column := range csvColumns {
+		e.cells[i] = xlsxCell{kind: xlsxString, value: column}
+	}
+	return e, x.writeRow(e.cells)
+}
+
+func (e *xlsxExport) write(p pokemon) error {
+	for i, value := range csvRecord(p) {
+		e.cells[i] = xlsxCell{kind: xlsxColumnKinds[csvColumns[i]], value: value}
+	}
+	return e.x.writeRow(e.cells)
+}
+
+func (e *xlsxExport) close() error {
+	return e.x.Close()
+}
@@ -2,24 +2,84 @@ package pokemons
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 
-	"example.com/pokemon-handbook/config"
+	"example.com/pokemon-handbook/audit"
+	"example.com/pokemon-handbook/auth"
+	"example.com/pokemon-handbook/conditional"
+	"example.com/pokemon-handbook/jsonpatch"
+	"example.com/pokemon-handbook/typechart"
 )
 
 type pokemon struct {
-	ID          int64  `bson:"_id" json:"id"`
-	Name        string `bson:"name" json:"name"`
+	ID   int64  `bson:"_id" json:"id" example:"25"`
+	Name string `bson:"name" json:"name" example:"Pikachu"`
+	// Slug is the URL-safe form of Name, set by the server.
+	Slug        string `bson:"slug" json:"slug" example:"pikachu"`
 	IsLegendary bool   `bson:"is_legendary" json:"is_legendary"`
-	Color       string `bson:"color" json:"color"`
+	Color       string `bson:"color" json:"color" example:"yellow"`
+
+	PrimaryType   string    `bson:"primary_type" json:"primary_type" enums:"normal,fire,water,electric,grass,ice,fighting,poison,ground,flying,psychic,bug,rock,ghost,dragon,dark,steel,fairy" example:"electric"`
+	SecondaryType string    `bson:"secondary_type" json:"secondary_type,omitempty" enums:"normal,fire,water,electric,grass,ice,fighting,poison,ground,flying,psychic,bug,rock,ghost,dragon,dark,steel,fairy"`
+	BaseStats     baseStats `bson:"base_stats" json:"base_stats"`
+	Abilities     []ability `bson:"abilities" json:"abilities"`
+	// Height is in metres and Weight in kilograms.
+	Height     float64 `bson:"height" json:"height" minimum:"0" example:"0.4"`
+	Weight     float64 `bson:"weight" json:"weight" minimum:"0" example:"6"`
+	Generation int     `bson:"generation" json:"generation" minimum:"1" maximum:"9" example:"1"`
+	Genus      string  `bson:"genus" json:"genus" example:"Mouse Pokémon"`
+	// DeletedAt is set by the server when the pokemon is moved to the trash.
+	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	// Version counts the changes of the pokemon and UpdatedAt is the time of the last one. The repository sets
+	// them and they are sent as the ETag and Last-Modified headers rather than in the body.
+	Version   int64     `bson:"version,omitempty" json:"-"`
+	UpdatedAt time.Time `bson:"updated_at" json:"-"`
+}
+
+// baseStats are the six base stats of a species. All of them are zero when they are unknown.
+type baseStats struct {
+	HP             int `bson:"hp" json:"hp" minimum:"1" maximum:"255" example:"35"`
+	Attack         int `bson:"attack" json:"attack" minimum:"1" maximum:"255" example:"55"`
+	Defense        int `bson:"defense" json:"defense" minimum:"1" maximum:"255" example:"40"`
+	SpecialAttack  int `bson:"special_attack" json:"special_attack" minimum:"1" maximum:"255" example:"50"`
+	SpecialDefense int `bson:"special_defense" json:"special_defense" minimum:"1" maximum:"255" example:"50"`
+	Speed          int `bson:"speed" json:"speed" minimum:"1" maximum:"255" example:"90"`
+}
+
+type ability struct {
+	Name   string `bson:"name" json:"name" example:"Static"`
+	Hidden bool   `bson:"hidden" json:"hidden"`
+}
+
+// ReferenceChecker is implemented by resources that refer to pokemons
+// and must not be left with dangling links when pokemons are deleted.
+type ReferenceChecker interface {
+	// PokemonReferenced reports whether the pokemon with the given id is referred to.
+	PokemonReferenced(ctx context.Context, id int64) (bool, error)
+	// AnyPokemonReferenced reports whether any pokemon is referred to.
+	AnyPokemonReferenced(ctx context.Context) (bool, error)
+}
+
+// Handler serves the /pokemons routes on top of a PokemonRepository.
+type Handler struct {
+	repo     PokemonRepository
+	history  HistoryRepository
+	auditLog *audit.Log
+	refs     []ReferenceChecker
+}
+
+// NewHandler returns a Handler that stores pokemons in repo, keeps their revisions in history,
+// records changes in auditLog and refuses to delete pokemons any of refs still refers to.
+func NewHandler(repo PokemonRepository, history HistoryRepository, auditLog *audit.Log, refs ...ReferenceChecker) *Handler {
+	return &Handler{repo: repo, history: history, auditLog: auditLog, refs: refs}
 }
 
 // Post Pokemon godoc
@@ -28,113 +88,146 @@ type pokemon struct {
 // @description  Post a pokemon to the MongoDB. If the database doesn't exist, create and insert a new value. Pass values in json format.
 // @produce      json
 // @success      201 {object} pokemon
+// @header       201 {string} ETag "version of the pokemon"
 // @failure      400 {string} string "object can't be parsed into JSON"
+// @param        pokemon  body  pokemon  true  "the new pokemon"
+// @failure      400 {string} string "pokemon's name must contain letters"
+// @failure      400 {string} string "the pokemon doesn't pass validation, e.g. unknown primary type"
 // @failure      409 {string} string "a pokemon with such id already exists"
+// @failure      409 {string} string "a pokemon with such name already exists"
 // @router       /pokemons [post]
-func PostPokemon(c *gin.Context) {
+func (h *Handler) PostPokemon(c *gin.Context) {
 	var newPokemon pokemon
 
 	if err := c.BindJSON(&newPokemon); err != nil {
 		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "object can't be parsed into JSON"})
 		return
 	}
-
-	collection, cancel, err := config.ConnectToMongoDB(config.Conf.CollectionName)
-	defer cancel()
-	if err != nil {
-		fmt.Println(err)
+	if !prepare(c, &newPokemon) {
 		return
 	}
 
-	res, err := collection.InsertOne(context.Background(), newPokemon)
-	if err != nil {
-		if mongo.IsDuplicateKeyError(err) {
-			c.IndentedJSON(http.StatusConflict, gin.H{"message": "a pokemon with such id already exists"})
+	if err := h.repo.Create(c.Request.Context(), &newPokemon); err != nil {
+		if errors.Is(err, ErrDuplicateID) || errors.Is(err, ErrDuplicateName) {
+			c.IndentedJSON(http.StatusConflict, gin.H{"message": err.Error()})
+			return
 		}
-		fmt.Println(err)
+		respondWithInternalError(c, err)
 		return
 	}
-	id := res.InsertedID
-	fmt.Printf("id value %v\n", id)
+	h.recordRevision(c, revisionCreate, newPokemon.ID, &newPokemon, 0)
+	h.auditLog.Record(c, audit.ActionCreate, resource(newPokemon.ID), nil, newPokemon)
 
-	// pokemons = append(pokemons, newPokemon)
+	conditional.SetHeaders(c, newPokemon.Version, newPokemon.UpdatedAt)
 	c.IndentedJSON(http.StatusCreated, newPokemon)
 }
 
 // GetPokemons godoc
 // @title        Get Pokemons
-// @summary      Retrieves all pokemons from the MongoDB
-// @description  Get all pokemons from the MongoDB. Pass values in json format.
+// @summary      Retrieves pokemons from the MongoDB
+// @description  Get pokemons from the MongoDB, optionally filtered, sorted and split into pages. Pass values in json format.
+// @description  The number of pokemons matching the filters is returned in the X-Total-Count header and the neighbouring pages in the Link header.
 // @produce      json
+// @param        limit         query  int     false  "maximum number of pokemons to return (1-1000)"
+// @param        offset        query  int     false  "number of pokemons to skip; pages are linked by offset when it is set"
+// @param        cursor        query  string  false  "opaque cursor taken from a Link header"
+// @param        sort          query  string  false  "field to sort by, prefix with - for descending order"  default(id)
+// @param        is_legendary  query  bool    false  "only legendary or only non-legendary pokemons"
+// @param        color         query  string  false  "only pokemons of this color"
+// @param        name_prefix   query  string  false  "only pokemons whose name starts with this prefix, case-insensitive"
 // @success      200 {array} pokemon
-// @failure      400 {string} string "object can't be parsed into JSON"
-// @failure      404 {string} string "Error: Not Found"
+// @header       200 {int} X-Total-Count "number of pokemons matching the filters"
+// @header       200 {string} Link "links to the next and previous pages"
+// @failure      400 {string} string "invalid query parameters"
 // @router       /pokemons [get]
-func GetPokemons(c *gin.Context) {
-	var pokemons = []pokemon{}
-
-	collection, cancel, err := config.ConnectToMongoDB(config.Conf.CollectionName)
-	defer cancel()
+func (h *Handler) GetPokemons(c *gin.Context) {
+	q, err := parseListQuery(c)
 	if err != nil {
-		fmt.Println(err)
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 		return
 	}
 
-	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
-	cur, err := collection.Find(context.Background(), bson.D{}, opts)
+	// ask for one more pokemon than requested to know if there is a following page
+	page := q
+	if page.Limit > 0 {
+		page.Limit++
+	}
+	pokemons, total, err := h.repo.List(c.Request.Context(), page)
 	if err != nil {
-		log.Fatal(err)
+		respondWithInternalError(c, err)
+		return
 	}
-	defer cur.Close(context.Background())
-	for cur.Next(context.Background()) {
-		// To decode into a struct, use cursor.Decode()
-		result := pokemon{}
-		err := cur.Decode(&result)
-		if err != nil {
-			log.Fatal(err)
-		}
-		fmt.Printf("Pokemon entry: %v\n", result)
-		pokemons = append(pokemons, result)
 
-		// To get the raw bson bytes use cursor.Current
-		raw := cur.Current
-		fmt.Printf("Raw result entry: %v\n", raw)
+	pokemons, links := paginate(c, q, pokemons)
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
 	}
-	if err := cur.Err(); err != nil {
-		fmt.Println(err)
+	c.IndentedJSON(http.StatusOK, pokemons)
+}
+
+// SearchPokemons godoc
+// @title        Search Pokemons
+// @summary      Search pokemons by name and genus
+// @description  Find pokemons whose name or genus is similar to the query, ignoring case, punctuation and small typos. The best matches come first.
+// @produce      json
+// @param        q      query  string  true   "text to search for, at most 64 characters"
+// @param        limit  query  int     false  "maximum number of results (1-100)"  default(10)
+// @success      200 {array} searchResult
+// @failure      400 {string} string "query must not be empty"
+// @failure      400 {string} string "query must be at most 64 characters long"
+// @router       /pokemons/search [get]
+func (h *Handler) SearchPokemons(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "query must not be empty"})
 		return
 	}
-	c.IndentedJSON(http.StatusOK, pokemons)
+	if utf8.RuneCountInString(query) > maxSearchQueryLen {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("query must be at most %d characters long", maxSearchQueryLen)})
+		return
+	}
+
+	limit := defaultSearchLimit
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("limit must be a number from 1 to %d", maxSearchLimit)})
+			return
+		}
+		limit = n
+	}
+
+	results, err := h.repo.Search(c.Request.Context(), query, limit)
+	if err != nil {
+		respondWithInternalError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, results)
 }
 
 // GetPokemonByID godoc
 // @title        Get Pokemon By ID
 // @summary      Retrieve pokemon from the MongoDB based on given ID
-// @description  Get a pokemon from the MongoDB by ID. Pass values in json format. If there aren't any pokemon with the ID gives a message "pokemon not found".
+// @description  Get a pokemon from the MongoDB by ID or by the slug of its name, e.g. "mr-mime". Pass values in json format. If there aren't any pokemon with the ID gives a message "pokemon not found".
+// @description  The ETag and Last-Modified headers tell the version of the pokemon. Sending them back in If-None-Match or If-Modified-Since gets a 304 response while the pokemon is unchanged.
 // @produce      json
+// @param        id                 path    string  true   "pokemon id or name slug"
+// @param        If-None-Match      header  string  false  "ETag of the pokemon the client has"
+// @param        If-Modified-Since  header  string  false  "Last-Modified of the pokemon the client has"
 // @success      200 {object} pokemon
-// @failure      406 {string} string "must be a number"
+// @header       200 {string} ETag "version of the pokemon"
+// @header       200 {string} Last-Modified "time of the last change of the pokemon"
+// @success      304 {string} string "the pokemon is unchanged"
+// @failure      406 {string} string "must be a number or a name"
 // @failure      404 {string} string "pokemon not found"
 // @router       /pokemons/{id} [get]
-func GetPokemonByID(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
-	if err != nil {
-		c.IndentedJSON(http.StatusNotAcceptable, gin.H{"message": "must be a number"})
+func (h *Handler) GetPokemonByID(c *gin.Context) {
+	result, ok := h.lookup(c)
+	if !ok {
 		return
 	}
-
-	collection, cancel, err := config.ConnectToMongoDB(config.Conf.CollectionName)
-	defer cancel()
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-
-	result := pokemon{}
-	err = collection.FindOne(context.Background(), bson.D{{Key: "_id", Value: id}}).Decode(&result)
-	if err != nil {
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "pokemon not found"})
-		// log.Fatal(err)
+	if conditional.NotModified(c, result.Version, result.UpdatedAt) {
 		return
 	}
 	c.IndentedJSON(http.StatusOK, result)
@@ -143,18 +236,26 @@ func GetPokemonByID(c *gin.Context) {
 // UpdatePokemonByID godoc
 // @title        Update Pokemon By ID
 // @summary      Update pokemon's data in the MongoDB based on given ID
-// @description  Update an existing pokemon in the MongoDB by ID. Pass values in json format. If there isn't pokemon with the ID creates a new pokemon.
+// @description  Update an existing pokemon in the MongoDB by ID or by the slug of its name. Pass values in json format. If there isn't pokemon with the ID creates a new pokemon.
+// @description  With an If-Match header the pokemon is only replaced while it is at the version of that ETag.
 // @produce      json
+// @param        id        path    string   true   "pokemon id or name slug"
+// @param        If-Match  header  string   false  "ETag of the pokemon the change is based on"
+// @param        pokemon   body    pokemon  true   "the new state of the pokemon"
 // @success      200 {string} string "pokemon was updated"
 // @success      201 {object} pokemon
-// @failure      406 {string} string "must be a number"
+// @header       200,201 {string} ETag "new version of the pokemon"
+// @failure      406 {string} string "must be a number or a name"
+// @failure      404 {string} string "pokemon not found"
 // @failure      400 {string} string "object can't be parsed into JSON"
+// @failure      400 {string} string "the pokemon doesn't pass validation, e.g. unknown primary type"
 // @failure      406 {string} string "pokemon's id cannot be changed"
+// @failure      409 {string} string "a pokemon with such name already exists"
+// @failure      412 {string} string "the pokemon has been changed since it was read"
 // @router       /pokemons/{id} [put]
-func UpdatePokemonByID(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
-	if err != nil {
-		c.IndentedJSON(http.StatusNotAcceptable, gin.H{"message": "must be a number"})
+func (h *Handler) UpdatePokemonByID(c *gin.Context) {
+	id, ok := h.resolveID(c)
+	if !ok {
 		return
 	}
 	var newPokemon pokemon
@@ -168,95 +269,553 @@ func UpdatePokemonByID(c *gin.Context) {
 		c.IndentedJSON(http.StatusNotAcceptable, gin.H{"message": "pokemon's id cannot be changed"})
 		return
 	}
+	if !prepare(c, &newPokemon) {
+		return
+	}
 
-	collection, cancel, err := config.ConnectToMongoDB(config.Conf.CollectionName)
-	defer cancel()
+	var before interface{}
+	old, err := h.repo.Get(c.Request.Context(), id)
+	if err == nil {
+		before = old
+	} else if !errors.Is(err, ErrNotFound) {
+		respondWithInternalError(c, err)
+		return
+	}
+	version, ok := conditional.Match(c, old.Version, before != nil)
+	if !ok {
+		return
+	}
+
+	created, err := h.repo.Upsert(c.Request.Context(), &newPokemon, version)
 	if err != nil {
-		fmt.Println(err)
+		respondWithWriteError(c, err)
 		return
 	}
+	action := audit.ActionUpdate
+	if created {
+		action = audit.ActionCreate
+	}
+	h.recordRevision(c, action, id, &newPokemon, 0)
+	h.auditLog.Record(c, action, resource(id), before, newPokemon)
 
-	opts := options.Update().SetUpsert(true)
-	filter := bson.D{{Key: "_id", Value: id}}
-	update := bson.D{{Key: "$set", Value: newPokemon}}
+	conditional.SetHeaders(c, newPokemon.Version, newPokemon.UpdatedAt)
+	if created {
+		fmt.Printf("inserted a new pokemon with ID %v\n", newPokemon.ID)
+		c.IndentedJSON(http.StatusCreated, newPokemon)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "pokemon was updated"})
+}
 
-	result, err := collection.UpdateOne(context.Background(), filter, update, opts)
+// PatchPokemon godoc
+// @title        Patch Pokemon
+// @summary      Change some fields of a pokemon
+// @description  Update an existing pokemon by ID or by the slug of its name with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902), told apart by the Content-Type. Fields the patch doesn't mention keep their values. The patched pokemon is validated like a PUT one and returned.
+// @description  With an If-Match header the pokemon is only patched while it is at the version of that ETag.
+// @accept       application/merge-patch+json,application/json-patch+json
+// @produce      json
+// @param        id        path    string  true   "pokemon id or name slug"
+// @param        If-Match  header  string  false  "ETag of the pokemon the patch is based on"
+// @param        patch     body    object  true   "the patch, e.g. {\"color\": \"red\"} or [{\"op\": \"replace\", \"path\": \"/color\", \"value\": \"red\"}]"
+// @success      200 {object} pokemon
+// @header       200 {string} ETag "new version of the pokemon"
+// @failure      406 {string} string "must be a number or a name"
+// @failure      404 {string} string "pokemon not found"
+// @failure      400 {string} string "invalid patch"
+// @failure      400 {string} string "the pokemon doesn't pass validation, e.g. unknown primary type"
+// @failure      406 {string} string "pokemon's id cannot be changed"
+// @failure      409 {string} string "test failed"
+// @failure      409 {string} string "a pokemon with such name already exists"
+// @failure      409 {string} string "the pokemon was changed or deleted while it was patched"
+// @failure      412 {string} string "the pokemon has been changed since it was read"
+// @failure      415 {string} string "the patch must be sent as application/merge-patch+json or application/json-patch+json"
+// @failure      422 {string} string "the patch can't be applied"
+// @router       /pokemons/{id} [patch]
+func (h *Handler) PatchPokemon(c *gin.Context) {
+	id, ok := h.resolveID(c)
+	if !ok {
+		return
+	}
+	before, err := h.repo.Get(c.Request.Context(), id)
 	if err != nil {
-		log.Fatal(err)
+		respondWithLookupError(c, err)
+		return
+	}
+	version, ok := conditional.Match(c, before.Version, true)
+	if !ok {
+		return
 	}
 
-	if result.MatchedCount != 0 {
-		c.IndentedJSON(http.StatusOK, gin.H{"message": "pokemon was updated"})
+	var newPokemon pokemon
+	if !jsonpatch.Bind(c, before, &newPokemon) {
 		return
 	}
-	if result.UpsertedCount != 0 {
-		// pokemons = append(pokemons, newPokemon)
-		fmt.Printf("inserted a new pokemon with ID %v\n", result.UpsertedID)
-		c.IndentedJSON(http.StatusCreated, newPokemon)
+	if newPokemon.ID != id {
+		c.IndentedJSON(http.StatusNotAcceptable, gin.H{"message": "pokemon's id cannot be changed"})
+		return
+	}
+	if !prepare(c, &newPokemon) {
+		return
 	}
+
+	// the patch was applied to before, so it is only written while the pokemon is still at that version
+	if _, err := h.repo.Upsert(c.Request.Context(), &newPokemon, before.Version); err != nil {
+		if errors.Is(err, ErrVersionMismatch) && version == 0 {
+			c.IndentedJSON(http.StatusConflict, gin.H{"message": "the pokemon was changed while it was patched, retry"})
+			return
+		}
+		respondWithWriteError(c, err)
+		return
+	}
+	h.recordRevision(c, revisionUpdate, id, &newPokemon, 0)
+	h.auditLog.Record(c, audit.ActionUpdate, resource(id), before, newPokemon)
+
+	conditional.SetHeaders(c, newPokemon.Version, newPokemon.UpdatedAt)
+	c.IndentedJSON(http.StatusOK, newPokemon)
 }
 
 // DeletePokemonByID godoc
 // @title        Delete Pokemon By ID
 // @summary      Delete pokemon in the MongoDB based on given ID
-// @description  Delete an existing pokemon in the MongoDB by ID and gives a message. Pass values in json format. If there isn't pokemon with the ID gives a message.
+// @description  Move an existing pokemon to the trash by ID or by the slug of its name and gives a message. Pass values in json format. If there isn't pokemon with the ID gives a message. Pokemons stay in the trash, see GET /trash, until they are restored or purged.
+// @description  With an If-Match header the pokemon is only deleted while it is at the version of that ETag.
 // @produce      json
+// @param        id        path    string  true   "pokemon id or name slug"
+// @param        If-Match  header  string  false  "ETag of the pokemon the deletion is based on"
 // @success      200 {object} pokemon "pokemon was deleted"
-// @failure      406 {string} string "must be a number"
-// @failure      201 {string} string "pokemon not found"
+// @failure      406 {string} string "must be a number or a name"
+// @failure      404 {string} string "pokemon not found"
+// @failure      409 {string} string "the pokemon is referred to by another resource"
+// @failure      412 {string} string "the pokemon has been changed since it was read"
 // @router       /pokemons/{id} [delete]
-func DeletePokemonByID(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
-	if err != nil {
-		c.IndentedJSON(http.StatusNotAcceptable, gin.H{"message": "must be a number"})
+func (h *Handler) DeletePokemonByID(c *gin.Context) {
+	id, ok := h.resolveID(c)
+	if !ok {
 		return
 	}
 
-	collection, cancel, err := config.ConnectToMongoDB(config.Conf.CollectionName)
-	defer cancel()
-	if err != nil {
-		fmt.Println(err)
-		return
+	for _, ref := range h.refs {
+		referenced, err := ref.PokemonReferenced(c.Request.Context(), id)
+		if err != nil {
+			respondWithInternalError(c, err)
+			return
+		}
+		if referenced {
+			c.IndentedJSON(http.StatusConflict, gin.H{"message": "the pokemon is referred to by another resource"})
+			return
+		}
 	}
 
-	res, err := collection.DeleteOne(context.Background(), bson.D{{Key: "_id", Value: id}})
+	before, err := h.repo.Get(c.Request.Context(), id)
 	if err != nil {
-		log.Fatal(err)
+		respondWithLookupError(c, err)
 		return
 	}
-	// pokemons = append(pokemons[:s], pokemons[s+1:]...)
-	if res.DeletedCount == 0 {
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "pokemon not found"})
-	} else {
-		c.IndentedJSON(http.StatusOK, gin.H{"message": "pokemon was deleted"})
+	version, ok := conditional.Match(c, before.Version, true)
+	if !ok {
+		return
+	}
+	if err := h.repo.Delete(c.Request.Context(), id, version, time.Now().UTC().Truncate(time.Millisecond)); err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			c.IndentedJSON(http.StatusNotFound, gin.H{"message": "pokemon not found"})
+		case errors.Is(err, ErrVersionMismatch):
+			conditional.Failed(c)
+		default:
+			respondWithInternalError(c, err)
+		}
+		return
 	}
+	h.recordRevision(c, revisionDelete, id, nil, 0)
+	h.auditLog.Record(c, audit.ActionDelete, resource(id), before, nil)
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "pokemon was deleted"})
 }
 
 // DeleteAllPokemons godoc
 // @title        Delete All Pokemons
 // @summary      Delete all pokemons in the MongoDB
-// @description  Delete all existing pokemons in the MongoDB and gives a message "all pokemons are deleted". Pass values in json format. If there aren't pokemons in the database gives a message "pokemons not found".
+// @description  Move all existing pokemons to the trash and gives a message "all pokemons are deleted". Pass values in json format. If there aren't pokemons in the database gives a message "pokemons not found".
 // @produce      json
 // @success      200 {object} pokemon "all pokemons was deleted"
 // @failure      404 {string} string "pokemons not found"
+// @failure      409 {string} string "pokemons are referred to by another resource"
 // @router       /pokemons [delete]
-func DeleteAllPokemons(c *gin.Context) {
-	collection, cancel, err := config.ConnectToMongoDB(config.Conf.CollectionName)
-	defer cancel()
-	if err != nil {
-		fmt.Println(err)
-		return
+func (h *Handler) DeleteAllPokemons(c *gin.Context) {
+	for _, ref := range h.refs {
+		referenced, err := ref.AnyPokemonReferenced(c.Request.Context())
+		if err != nil {
+			respondWithInternalError(c, err)
+			return
+		}
+		if referenced {
+			c.IndentedJSON(http.StatusConflict, gin.H{"message": "pokemons are referred to by another resource"})
+			return
+		}
 	}
 
-	res, err := collection.DeleteMany(context.Background(), bson.D{})
+	deleted, err := h.repo.DeleteAll(c.Request.Context(), time.Now().UTC().Truncate(time.Millisecond))
 	if err != nil {
-		log.Fatal(err)
+		respondWithInternalError(c, err)
 		return
 	}
-	// pokemons = make(map[int]pokemon)
-	if res.DeletedCount == 0 {
+	if len(deleted) == 0 {
 		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "pokemons not found"})
 	} else {
+		for _, id := range deleted {
+			h.recordRevision(c, revisionDelete, id, nil, 0)
+		}
+		// the deleted pokemons are kept in their history, the audit log only tells how many there were
+		h.auditLog.Record(c, audit.ActionDeleteAll, "pokemons", gin.H{"count": len(deleted)}, nil)
 		c.IndentedJSON(http.StatusOK, gin.H{"message": "all pokemons was deleted"})
 	}
 }
+
+// typeProfile is how a pokemon fares against attacks of every type.
+type typeProfile struct {
+	PokemonID int64    `json:"pokemon_id" example:"25"`
+	Types     []string `json:"types" example:"electric"`
+	typechart.Profile
+}
+
+// GetPokemonWeaknesses godoc
+// @title        Get Pokemon Weaknesses
+// @summary      Retrieve the weaknesses, resistances and immunities of a pokemon
+// @description  Get the damage multiplier of every attacking type against the stored types of the pokemon with the given ID or name slug.
+// @produce      json
+// @param        id  path  string  true  "pokemon id or name slug"
+// @success      200 {object} typeProfile
+// @failure      406 {string} string "must be a number or a name"
+// @failure      404 {string} string "pokemon not found"
+// @failure      422 {string} string "pokemon's types are unknown"
+// @router       /pokemons/{id}/weaknesses [get]
+func (h *Handler) GetPokemonWeaknesses(c *gin.Context) {
+	p, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+	if p.PrimaryType == "" {
+		c.IndentedJSON(http.StatusUnprocessableEntity, gin.H{"message": "pokemon's types are unknown"})
+		return
+	}
+
+	types := []string{p.PrimaryType}
+	if p.SecondaryType != "" {
+		types = append(types, p.SecondaryType)
+	}
+	c.IndentedJSON(http.StatusOK, typeProfile{
+		PokemonID: p.ID,
+		Types:     types,
+		Profile:   typechart.DefenseProfile(types...),
+	})
+}
+
+// GetPokemonHistory godoc
+// @title        Get Pokemon History
+// @summary      List the revisions of a pokemon
+// @description  Get every change made to the pokemon with the given ID or name slug, oldest first. Deleted pokemons keep their history and can only be named by ID.
+// @produce      json
+// @param        id  path  string  true  "pokemon id or name slug"
+// @success      200 {array} revisionInfo
+// @failure      406 {string} string "must be a number or a name"
+// @failure      404 {string} string "pokemon has no history"
+// @router       /pokemons/{id}/history [get]
+func (h *Handler) GetPokemonHistory(c *gin.Context) {
+	id, ok := h.resolveID(c)
+	if !ok {
+		return
+	}
+	revisions, err := h.history.List(c.Request.Context(), id)
+	if err != nil {
+		respondWithInternalError(c, err)
+		return
+	}
+	if len(revisions) == 0 {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "pokemon has no history"})
+		return
+	}
+
+	infos := make([]revisionInfo, len(revisions))
+	for i, r := range revisions {
+		infos[i] = r.info()
+	}
+	c.IndentedJSON(http.StatusOK, infos)
+}
+
+// GetPokemonRevision godoc
+// @title        Get Pokemon Revision
+// @summary      Retrieve a revision of a pokemon
+// @description  Get a change made to the pokemon with the given ID or name slug together with the pokemon as it was after the change, null after a deletion.
+// @produce      json
+// @param        id   path  string  true  "pokemon id or name slug"
+// @param        rev  path  int     true  "revision number"
+// @success      200 {object} revision
+// @failure      406 {string} string "must be a number or a name"
+// @failure      400 {string} string "revision must be a positive number"
+// @failure      404 {string} string "revision not found"
+// @router       /pokemons/{id}/history/{rev} [get]
+func (h *Handler) GetPokemonRevision(c *gin.Context) {
+	id, ok := h.resolveID(c)
+	if !ok {
+		return
+	}
+	rev, ok := parseRevision(c, c.Param("rev"))
+	if !ok {
+		return
+	}
+	r, err := h.history.Get(c.Request.Context(), id, rev)
+	if err != nil {
+		respondWithLookupError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, r)
+}
+
+// GetPokemonDiff godoc
+// @title        Get Pokemon Diff
+// @summary      Compare two revisions of a pokemon
+// @description  Get the fields that differ between two revisions of the pokemon with the given ID or name slug. Revision 0 is the pokemon before it was created.
+// @produce      json
+// @param        id    path   string  true   "pokemon id or name slug"
+// @param        from  query  int     false  "older revision, the one before to by default"
+// @param        to    query  int     false  "newer revision, the latest by default"
+// @success      200 {object} revisionDiff
+// @failure      406 {string} string "must be a number or a name"
+// @failure      400 {string} string "revision must be a positive number"
+// @failure      404 {string} string "revision not found"
+// @router       /pokemons/{id}/diff [get]
+func (h *Handler) GetPokemonDiff(c *gin.Context) {
+	id, ok := h.resolveID(c)
+	if !ok {
+		return
+	}
+	revisions, err := h.history.List(c.Request.Context(), id)
+	if err != nil {
+		respondWithInternalError(c, err)
+		return
+	}
+
+	to := len(revisions)
+	if s := c.Query("to"); s != "" {
+		if to, ok = parseRevision(c, s); !ok {
+			return
+		}
+	}
+	from := to - 1
+	if s := c.Query("from"); s != "" {
+		if from, err = strconv.Atoi(s); err != nil || from < 0 {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "revision must be a positive number"})
+			return
+		}
+	}
+	if to < 1 || to > len(revisions) || from > len(revisions) {
+		respondWithLookupError(c, ErrRevisionNotFound)
+		return
+	}
+
+	// revision 0 is the pokemon before it existed
+	var before *pokemon
+	if from > 0 {
+		before = revisions[from-1].Pokemon
+	}
+	changes, err := diffPokemons(before, revisions[to-1].Pokemon)
+	if err != nil {
+		respondWithInternalError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, revisionDiff{PokemonID: id, From: from, To: to, Changes: changes})
+}
+
+// RevertPokemon godoc
+// @title        Revert Pokemon
+// @summary      Restore a revision of a pokemon
+// @description  Store the pokemon with the given ID as it was after the given revision. This is recorded as a new revision; a deleted pokemon is created again.
+// @description  With an If-Match header the pokemon is only reverted while it is at the version of that ETag.
+// @produce      json
+// @param        id        path    string  true   "pokemon id or name slug"
+// @param        rev       path    int     true   "revision number to restore"
+// @param        If-Match  header  string  false  "ETag of the pokemon the revert is based on"
+// @success      200 {object} pokemon
+// @header       200 {string} ETag "new version of the pokemon"
+// @failure      406 {string} string "must be a number or a name"
+// @failure      400 {string} string "revision must be a positive number"
+// @failure      404 {string} string "revision not found"
+// @failure      409 {string} string "a pokemon with such name already exists"
+// @failure      412 {string} string "the pokemon has been changed since it was read"
+// @failure      422 {string} string "the revision deleted the pokemon"
+// @router       /pokemons/{id}/revert/{rev} [post]
+func (h *Handler) RevertPokemon(c *gin.Context) {
+	id, ok := h.resolveID(c)
+	if !ok {
+		return
+	}
+	rev, ok := parseRevision(c, c.Param("rev"))
+	if !ok {
+		return
+	}
+	r, err := h.history.Get(c.Request.Context(), id, rev)
+	if err != nil {
+		respondWithLookupError(c, err)
+		return
+	}
+	if r.Pokemon == nil {
+		c.IndentedJSON(http.StatusUnprocessableEntity, gin.H{"message": "the revision deleted the pokemon"})
+		return
+	}
+
+	var before interface{}
+	old, err := h.repo.Get(c.Request.Context(), id)
+	if err == nil {
+		before = old
+	} else if !errors.Is(err, ErrNotFound) {
+		respondWithInternalError(c, err)
+		return
+	}
+	version, ok := conditional.Match(c, old.Version, before != nil)
+	if !ok {
+		return
+	}
+
+	// the stored revision must be left as it was, so the pokemon is written from a copy
+	p := *r.Pokemon
+	if _, err := h.repo.Upsert(c.Request.Context(), &p, version); err != nil {
+		respondWithWriteError(c, err)
+		return
+	}
+	h.recordRevision(c, revisionRevert, id, &p, rev)
+	h.auditLog.Record(c, audit.ActionRevert, resource(id), before, p)
+
+	conditional.SetHeaders(c, p.Version, p.UpdatedAt)
+	c.IndentedJSON(http.StatusOK, p)
+}
+
+// parseRevision parses a revision number. When it returns false the error response has already been written.
+func parseRevision(c *gin.Context, s string) (int, bool) {
+	rev, err := strconv.Atoi(s)
+	if err != nil || rev < 1 {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "revision must be a positive number"})
+		return 0, false
+	}
+	return rev, true
+}
+
+// recordRevision appends a revision of the pokemon with the given id to its history. p is the pokemon after the
+// change, nil after a deletion. The change has already happened, so failures to record it are printed.
+func (h *Handler) recordRevision(c *gin.Context, action string, id int64, p *pokemon, revertedTo int) {
+	r := revision{
+		PokemonID:  id,
+		Time:       time.Now().UTC().Truncate(time.Millisecond),
+		Action:     action,
+		RevertedTo: revertedTo,
+		Pokemon:    p,
+	}
+	if principal, ok := auth.CurrentUser(c); ok {
+		r.Actor = principal.Login
+	}
+	if _, err := h.history.Append(c.Request.Context(), r); err != nil {
+		fmt.Printf("failed to record revision of pokemon %d: %v\n", id, err)
+	}
+}
+
+// resource names the pokemon with the given id in the audit log.
+func resource(id int64) string {
+	return "pokemons/" + strconv.FormatInt(id, 10)
+}
+
+// find returns the pokemon named by param, which is either a numeric id or a name slug.
+func (h *Handler) find(ctx context.Context, param string) (pokemon, error) {
+	if isNumeric(param) || strings.HasPrefix(param, "-") {
+		id, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return pokemon{}, ErrInvalidID
+		}
+		return h.repo.Get(ctx, id)
+	}
+
+	slug := slugify(param)
+	if slug == "" {
+		return pokemon{}, ErrInvalidID
+	}
+	return h.repo.GetBySlug(ctx, slug)
+}
+
+// ResolveID returns the id of the pokemon named by param, which is either a numeric id or a name slug.
+// Numeric ids are returned without checking that the pokemon exists.
+func (h *Handler) ResolveID(ctx context.Context, param string) (int64, error) {
+	if id, err := strconv.ParseInt(param, 10, 64); err == nil {
+		return id, nil
+	}
+	p, err := h.find(ctx, param)
+	return p.ID, err
+}
+
+// PokemonExists reports whether a pokemon with the given id is stored.
+func (h *Handler) PokemonExists(ctx context.Context, id int64) (bool, error) {
+	_, err := h.repo.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// lookup returns the pokemon named by the id path parameter.
+// When it returns false the error response has already been written.
+func (h *Handler) lookup(c *gin.Context) (pokemon, bool) {
+	result, err := h.find(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWithLookupError(c, err)
+		return result, false
+	}
+	return result, true
+}
+
+// resolveID returns the id named by the id path parameter without loading the pokemon when it is numeric,
+// so that PUT can create a pokemon with a new id.
+// When it returns false the error response has already been written.
+func (h *Handler) resolveID(c *gin.Context) (int64, bool) {
+	id, err := h.ResolveID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWithLookupError(c, err)
+		return id, false
+	}
+	return id, true
+}
+
+func respondWithLookupError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidID):
+		c.IndentedJSON(http.StatusNotAcceptable, gin.H{"message": "must be a number or a name"})
+	case errors.Is(err, ErrNotFound):
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "pokemon not found"})
+	case errors.Is(err, ErrRevisionNotFound):
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "revision not found"})
+	default:
+		respondWithInternalError(c, err)
+	}
+}
+
+// prepare normalizes and validates a pokemon received from a client and derives its slug.
+// When it returns false the pokemon can't be stored and the error response has already been written.
+func prepare(c *gin.Context, p *pokemon) bool {
+	if err := p.prepare(); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return false
+	}
+	return true
+}
+
+// respondWithWriteError responds to the errors of Upsert.
+func respondWithWriteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrDuplicateName):
+		c.IndentedJSON(http.StatusConflict, gin.H{"message": err.Error()})
+	case errors.Is(err, ErrVersionMismatch):
+		conditional.Failed(c)
+	default:
+		respondWithInternalError(c, err)
+	}
+}
+
+func respondWithInternalError(c *gin.Context, err error) {
+	fmt.Println(err)
+	c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+}
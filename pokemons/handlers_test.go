@@ -0,0 +1,282 @@
+package pokemons
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/pokemon-handbook/audit"
+)
+
+// newTestRouter serves the handler of memory repositories holding the given pokemons.
+func newTestRouter(t *testing.T, pokemons ...pokemon) (*gin.Engine, PokemonRepository, HistoryRepository) {
+	t.Helper()
+	return newTestRouterWith(t, NewMemoryRepository(), pokemons...)
+}
+
+// newTestRouterWith serves the handler of repo, to which the given pokemons are added, and a memory history.
+func newTestRouterWith(t *testing.T, repo PokemonRepository, pokemons ...pokemon) (*gin.Engine, PokemonRepository, HistoryRepository) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	history := NewMemoryHistory()
+	for i := range pokemons {
+		if err := repo.Create(context.Background(), &pokemons[i]); err != nil {
+			t.Fatalf("Create(%d): %v", pokemons[i].ID, err)
+		}
+	}
+	h := NewHandler(repo, history, audit.NewLog(audit.NewMemoryRepository()))
+
+	r := gin.New()
+	r.GET("/pokemons/search", h.SearchPokemons)
+	r.GET("/pokemons/:id", h.GetPokemonByID)
+	r.PUT("/pokemons/:id", h.UpdatePokemonByID)
+	r.PATCH("/pokemons/:id", h.PatchPokemon)
+	r.DELETE("/pokemons/:id", h.DeletePokemonByID)
+	r.DELETE("/pokemons", h.DeleteAllPokemons)
+	r.POST("/pokemons/:id/revert/:rev", h.RevertPokemon)
+	r.POST("/trash/:id/restore", h.RestorePokemon)
+	return r, repo, history
+}
+
+func serve(r *gin.Engine, method, path string, header http.Header) *httptest.ResponseRecorder {
+	return serveBody(r, method, path, header, "")
+}
+
+func serveBody(r *gin.Engine, method, path string, header http.Header, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestDeleteAllPokemons(t *testing.T) {
+	tests := []struct {
+		name     string
+		pokemons []pokemon
+		status   int
+	}{
+		{"no pokemons", nil, http.StatusNotFound},
+		{"some pokemons", []pokemon{{ID: 25, Name: "Pikachu", Slug: "pikachu"}, {ID: 1, Name: "Bulbasaur", Slug: "bulbasaur"}}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, repo, history := newTestRouter(t, tt.pokemons...)
+
+			w := serve(r, http.MethodDelete, "/pokemons", nil)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+
+			trash, err := repo.ListDeleted(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(trash) != len(tt.pokemons) {
+				t.Errorf("%d pokemons in the trash, want %d", len(trash), len(tt.pokemons))
+			}
+			for _, p := range tt.pokemons {
+				revs, err := history.List(context.Background(), p.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n := len(revs); n == 0 || revs[n-1].Action != revisionDelete {
+					t.Errorf("the history of pokemon %d doesn't end with a deletion: %+v", p.ID, revs)
+				}
+			}
+		})
+	}
+}
+
+func TestRevertPokemon(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		status  int
+		etag    string
+	}{
+		{"unconditional", "", http.StatusOK, `"3"`},
+		{"current version", `"2"`, http.StatusOK, `"3"`},
+		{"stale version", `"1"`, http.StatusPreconditionFailed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			r, repo, history := newTestRouter(t, pokemon{ID: 25, Name: "Pikachu", Slug: "pikachu", Color: "yellow"})
+			first, _ := repo.Get(ctx, 25)
+			if _, err := history.Append(ctx, revision{PokemonID: 25, Action: revisionCreate, Pokemon: &first}); err != nil {
+				t.Fatal(err)
+			}
+			changed := first
+			changed.Color = "red"
+			if _, err := repo.Upsert(ctx, &changed, 0); err != nil {
+				t.Fatal(err)
+			}
+
+			header := http.Header{}
+			if tt.ifMatch != "" {
+				header.Set("If-Match", tt.ifMatch)
+			}
+			w := serve(r, http.MethodPost, "/pokemons/25/revert/1", header)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if got := w.Header().Get("ETag"); got != tt.etag {
+				t.Errorf("ETag = %s, want %s", got, tt.etag)
+			}
+
+			stored, _ := repo.Get(ctx, 25)
+			if want := map[bool]string{true: "yellow", false: "red"}[tt.status == http.StatusOK]; stored.Color != want {
+				t.Errorf("color = %s, want %s", stored.Color, want)
+			}
+			rev, err := history.Get(ctx, 25, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rev.Pokemon.Version != 1 {
+				t.Errorf("revision 1 was changed to version %d", rev.Pokemon.Version)
+			}
+		})
+	}
+}
+
+func TestRestorePokemon(t *testing.T) {
+	tests := []struct {
+		name    string
+		deleted bool
+		status  int
+		etag    string
+	}{
+		{"in the trash", true, http.StatusOK, `"3"`},
+		{"not deleted", false, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			r, repo, _ := newTestRouter(t, pokemon{ID: 25, Name: "Pikachu", Slug: "pikachu"})
+			if tt.deleted {
+				if err := repo.Delete(ctx, 25, 0, time.Now()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			w := serve(r, http.MethodPost, "/trash/25/restore", nil)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if got := w.Header().Get("ETag"); got != tt.etag {
+				t.Errorf("ETag = %s, want %s", got, tt.etag)
+			}
+		})
+	}
+}
+
+// racingRepository runs a concurrent change once a handler has read a pokemon, before it writes it back.
+type racingRepository struct {
+	PokemonRepository
+	afterGet func()
+}
+
+func (r *racingRepository) Get(ctx context.Context, id int64) (pokemon, error) {
+	p, err := r.PokemonRepository.Get(ctx, id)
+	if fn := r.afterGet; fn != nil {
+		r.afterGet = nil
+		fn()
+	}
+	return p, err
+}
+
+func TestPatchPokemonRace(t *testing.T) {
+	mergePatch := http.Header{"Content-Type": {"application/merge-patch+json"}}
+	tests := []struct {
+		name    string
+		ifMatch string
+		// race is the request served between the read and the write of the patch.
+		race    func(r *gin.Engine) *httptest.ResponseRecorder
+		status  int
+		color   string
+		inTrash bool
+		version int64
+	}{
+		{"no race", "", nil, http.StatusOK, "red", false, 2},
+		{"interleaved patch", "", func(r *gin.Engine) *httptest.ResponseRecorder {
+			return serveBody(r, http.MethodPatch, "/pokemons/25", mergePatch, `{"color": "blue"}`)
+		}, http.StatusConflict, "blue", false, 2},
+		{"interleaved patch with If-Match", `"1"`, func(r *gin.Engine) *httptest.ResponseRecorder {
+			return serveBody(r, http.MethodPatch, "/pokemons/25", mergePatch, `{"color": "blue"}`)
+		}, http.StatusPreconditionFailed, "blue", false, 2},
+		{"interleaved delete", "", func(r *gin.Engine) *httptest.ResponseRecorder {
+			return serve(r, http.MethodDelete, "/pokemons/25", nil)
+		}, http.StatusConflict, "yellow", true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := &racingRepository{PokemonRepository: NewMemoryRepository()}
+			r, _, _ := newTestRouterWith(t, repo, pokemon{ID: 25, Name: "Pikachu", Slug: "pikachu", Color: "yellow"})
+			if tt.race != nil {
+				repo.afterGet = func() {
+					if w := tt.race(r); w.Code != http.StatusOK {
+						t.Errorf("racing request: status = %d: %s", w.Code, w.Body)
+					}
+				}
+			}
+
+			header := http.Header{"Content-Type": mergePatch["Content-Type"]}
+			if tt.ifMatch != "" {
+				header.Set("If-Match", tt.ifMatch)
+			}
+			w := serveBody(r, http.MethodPatch, "/pokemons/25", header, `{"color": "red"}`)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+
+			stored, err := repo.PokemonRepository.Get(ctx, 25)
+			if tt.inTrash {
+				if !errors.Is(err, ErrNotFound) {
+					t.Fatalf("the deleted pokemon was brought back: %+v, %v", stored, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Color != tt.color || stored.Version != tt.version {
+				t.Errorf("stored color %s at version %d, want %s at version %d", stored.Color, stored.Version, tt.color, tt.version)
+			}
+		})
+	}
+}
+
+func TestSearchQueryLength(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{"short query", "pikachu", http.StatusOK},
+		{"longest query", strings.Repeat("p", maxSearchQueryLen), http.StatusOK},
+		{"longest query of multibyte runes", strings.Repeat("é", maxSearchQueryLen), http.StatusOK},
+		{"too long query", strings.Repeat("p", maxSearchQueryLen+1), http.StatusBadRequest},
+		{"too long query of multibyte runes", strings.Repeat("é", maxSearchQueryLen+1), http.StatusBadRequest},
+		{"surrounding spaces don't count", "  " + strings.Repeat("p", maxSearchQueryLen) + "  ", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, _ := newTestRouter(t, pokemon{ID: 25, Name: "Pikachu", Slug: "pikachu"})
+			w := serve(r, http.MethodGet, "/pokemons/search?q="+url.QueryEscape(tt.query), nil)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
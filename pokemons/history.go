@@ -0,0 +1,64 @@
+package pokemons
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrRevisionNotFound is returned by a HistoryRepository when a pokemon has no revision with the requested number.
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// Actions recorded in the history of a pokemon.
+const (
+	revisionCreate  = "create"
+	revisionUpdate  = "update"
+	revisionDelete  = "delete"
+	revisionRevert  = "revert"
+	revisionRestore = "restore"
+)
+
+// revisionInfo describes a change to a pokemon, as listed by GetPokemonHistory.
+type revisionInfo struct {
+	PokemonID int64 `json:"pokemon_id" example:"25"`
+	// Rev numbers the revisions of a pokemon from 1.
+	Rev    int       `json:"rev" example:"1"`
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor" example:"admin"`
+	Action string    `json:"action" enums:"create,update,delete,revert,restore"`
+	// RevertedTo is the revision restored by a revert.
+	RevertedTo int `json:"reverted_to,omitempty"`
+}
+
+// revision is a change to a pokemon together with the pokemon after the change.
+type revision struct {
+	PokemonID  int64     `bson:"pokemon_id" json:"pokemon_id" example:"25"`
+	Rev        int       `bson:"rev" json:"rev" example:"1"`
+	Time       time.Time `bson:"time" json:"time"`
+	Actor      string    `bson:"actor" json:"actor" example:"admin"`
+	Action     string    `bson:"action" json:"action" enums:"create,update,delete,revert,restore"`
+	RevertedTo int       `bson:"reverted_to,omitempty" json:"reverted_to,omitempty"`
+	// Pokemon is nil after a deletion.
+	Pokemon *pokemon `bson:"pokemon" json:"pokemon"`
+}
+
+func (r revision) info() revisionInfo {
+	return revisionInfo{
+		PokemonID:  r.PokemonID,
+		Rev:        r.Rev,
+		Time:       r.Time,
+		Actor:      r.Actor,
+		Action:     r.Action,
+		RevertedTo: r.RevertedTo,
+	}
+}
+
+// HistoryRepository keeps the revisions of every pokemon. Revisions are never changed or deleted.
+type HistoryRepository interface {
+	// Append stores r as the next revision of r.PokemonID, setting r.Rev, and returns it.
+	Append(ctx context.Context, r revision) (revision, error)
+	// List returns the revisions of a pokemon, oldest first.
+	List(ctx context.Context, pokemonID int64) ([]revision, error)
+	// Get returns a revision of a pokemon or ErrRevisionNotFound.
+	Get(ctx context.Context, pokemonID int64, rev int) (revision, error)
+}
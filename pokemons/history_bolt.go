@@ -0,0 +1,90 @@
+package pokemons
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type boltHistory struct {
+	db     *bbolt.DB
+	bucket []byte
+}
+
+// NewBoltHistory returns a HistoryRepository that stores revisions in the given bucket of a bolt database file.
+// Documents are encoded as BSON and keyed by pokemon id followed by revision number,
+// so the revisions of a pokemon are next to each other and in order.
+func NewBoltHistory(db *bbolt.DB, bucket string) (HistoryRepository, error) {
+	h := &boltHistory{db: db, bucket: []byte(bucket)}
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(h.bucket)
+		return err
+	})
+	return h, err
+}
+
+func revisionKey(pokemonID int64, rev int) []byte {
+	key := make([]byte, 12)
+	copy(key, boltKey(pokemonID))
+	binary.BigEndian.PutUint32(key[8:], uint32(rev))
+	return key
+}
+
+func (h *boltHistory) Append(ctx context.Context, r revision) (revision, error) {
+	err := h.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(h.bucket)
+		prefix := boltKey(r.PokemonID)
+
+		// seeking past the highest possible revision of the id lands right after its last revision
+		r.Rev = 1
+		c := b.Cursor()
+		k, _ := c.Seek(revisionKey(r.PokemonID, -1))
+		if k == nil {
+			k, _ = c.Last()
+		} else {
+			k, _ = c.Prev()
+		}
+		if k != nil && bytes.HasPrefix(k, prefix) {
+			r.Rev = int(binary.BigEndian.Uint32(k[8:])) + 1
+		}
+
+		data, err := bson.Marshal(r)
+		if err != nil {
+			return err
+		}
+		return b.Put(revisionKey(r.PokemonID, r.Rev), data)
+	})
+	return r, err
+}
+
+func (h *boltHistory) List(ctx context.Context, pokemonID int64) ([]revision, error) {
+	var revisions = []revision{}
+	err := h.db.View(func(tx *bbolt.Tx) error {
+		prefix := boltKey(pokemonID)
+		c := tx.Bucket(h.bucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			result := revision{}
+			if err := bson.Unmarshal(v, &result); err != nil {
+				return err
+			}
+			revisions = append(revisions, result)
+		}
+		return nil
+	})
+	return revisions, err
+}
+
+func (h *boltHistory) Get(ctx context.Context, pokemonID int64, rev int) (revision, error) {
+	result := revision{}
+	err := h.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(h.bucket).Get(revisionKey(pokemonID, rev))
+		if data == nil {
+			return ErrRevisionNotFound
+		}
+		return bson.Unmarshal(data, &result)
+	})
+	return result, err
+}
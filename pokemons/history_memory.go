@@ -0,0 +1,44 @@
+package pokemons
+
+import (
+	"context"
+	"sync"
+)
+
+type memoryHistory struct {
+	mu        sync.RWMutex
+	revisions map[int64][]revision
+}
+
+// NewMemoryHistory returns a HistoryRepository that keeps revisions in process memory.
+// It is safe for concurrent use and loses its data when the process exits.
+func NewMemoryHistory() HistoryRepository {
+	return &memoryHistory{revisions: make(map[int64][]revision)}
+}
+
+func (h *memoryHistory) Append(ctx context.Context, r revision) (revision, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r.Rev = len(h.revisions[r.PokemonID]) + 1
+	h.revisions[r.PokemonID] = append(h.revisions[r.PokemonID], r)
+	return r, nil
+}
+
+func (h *memoryHistory) List(ctx context.Context, pokemonID int64) ([]revision, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return append([]revision{}, h.revisions[pokemonID]...), nil
+}
+
+func (h *memoryHistory) Get(ctx context.Context, pokemonID int64, rev int) (revision, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	revisions := h.revisions[pokemonID]
+	if rev < 1 || rev > len(revisions) {
+		return revision{}, ErrRevisionNotFound
+	}
+	return revisions[rev-1], nil
+}
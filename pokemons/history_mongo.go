@@ -0,0 +1,73 @@
+package pokemons
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxAppendAttempts bounds the retries of Append when concurrent requests take the same revision number.
+const maxAppendAttempts = 5
+
+type mongoHistory struct {
+	collection *mongo.Collection
+}
+
+// NewMongoHistory returns a HistoryRepository backed by the given MongoDB collection
+// and creates the unique index numbering the revisions of each pokemon.
+func NewMongoHistory(collection *mongo.Collection) (HistoryRepository, error) {
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "pokemon_id", Value: 1}, {Key: "rev", Value: 1}},
+		Options: options.Index().SetName("pokemon_id_rev_unique").SetUnique(true),
+	})
+	return &mongoHistory{collection: collection}, err
+}
+
+func (h *mongoHistory) Append(ctx context.Context, r revision) (revision, error) {
+	for attempt := 1; ; attempt++ {
+		last := revision{}
+		opts := options.FindOne().SetSort(bson.D{{Key: "rev", Value: -1}}).SetProjection(bson.D{{Key: "rev", Value: 1}})
+		err := h.collection.FindOne(ctx, bson.D{{Key: "pokemon_id", Value: r.PokemonID}}, opts).Decode(&last)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return r, err
+		}
+
+		r.Rev = last.Rev + 1
+		_, err = h.collection.InsertOne(ctx, r)
+		if !mongo.IsDuplicateKeyError(err) || attempt == maxAppendAttempts {
+			return r, err
+		}
+	}
+}
+
+func (h *mongoHistory) List(ctx context.Context, pokemonID int64) ([]revision, error) {
+	var revisions = []revision{}
+
+	opts := options.Find().SetSort(bson.D{{Key: "rev", Value: 1}})
+	cur, err := h.collection.Find(ctx, bson.D{{Key: "pokemon_id", Value: pokemonID}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		result := revision{}
+		if err := cur.Decode(&result); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, result)
+	}
+	return revisions, cur.Err()
+}
+
+func (h *mongoHistory) Get(ctx context.Context, pokemonID int64, rev int) (revision, error) {
+	result := revision{}
+
+	filter := bson.D{{Key: "pokemon_id", Value: pokemonID}, {Key: "rev", Value: rev}}
+	err := h.collection.FindOne(ctx, filter).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		return result, ErrRevisionNotFound
+	}
+	return result, err
+}
@@ -0,0 +1,437 @@
+package pokemons
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/pokemon-handbook/audit"
+)
+
+// importBatchSize is the number of pokemons written to the repository at once by an import.
+const importBatchSize = 500
+
+// maxNDJSONLine is the longest line accepted in NDJSON input.
+const maxNDJSONLine = 1 << 20
+
+// What an import does with pokemons whose id or name is already stored.
+const (
+	onDuplicateSkip      = "skip"
+	onDuplicateOverwrite = "overwrite"
+	onDuplicateFail      = "fail"
+)
+
+// Formats of imported and exported pokemons.
+const (
+	formatCSV    = "csv"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+)
+
+// importFormats maps the media types of import requests to formats.
+var importFormats = map[string]string{
+	"text/csv":             formatCSV,
+	"application/json":     formatJSON,
+	"application/x-ndjson": formatNDJSON,
+	"application/ndjson":   formatNDJSON,
+}
+
+// importReport is returned by ImportPokemons. In a dry run the counts tell what an import would do.
+type importReport struct {
+	DryRun bool `json:"dry_run"`
+	// Rows is the number of rows read.
+	Rows    int `json:"rows" example:"151"`
+	Created int `json:"created" example:"150"`
+	Updated int `json:"updated" example:"0"`
+	Skipped int `json:"skipped" example:"0"`
+	Failed  int `json:"failed" example:"1"`
+	// Message tells why the import stopped before the end of the input.
+	Message string     `json:"message,omitempty"`
+	Errors  []rowError `json:"errors"`
+}
+
+// rowError tells why a row wasn't imported.
+type rowError struct {
+	// Row is the line of the row in CSV and NDJSON input and its position in a JSON array, counting from 1.
+	Row int `json:"row" example:"42"`
+	// ID is left out when the row couldn't be decoded.
+	ID *int64 `json:"id,omitempty" example:"25"`
+	// Status is the status PostPokemon would respond with.
+	Status  int    `json:"status" example:"409"`
+	Message string `json:"message" example:"a pokemon with such id already exists"`
+}
+
+// ImportPokemons godoc
+// @title        Import Pokemons
+// @summary      Import many pokemons at once
+// @description  Create pokemons from CSV, a JSON array or NDJSON, as told by the format parameter or the Content-Type header. CSV input starts with a header naming some of the columns id, name, is_legendary, color, primary_type, secondary_type, hp, attack, defense, special_attack, special_defense, speed, abilities (separated by semicolons), hidden_ability, height, weight, generation and genus. The six base stat columns are given together or not at all, and their fields in a row are all filled or all empty.
+// @description  Every row is validated like the body of POST /pokemons. Invalid rows are reported and skipped, the others are written in batches. Pokemons whose id or name is already stored are skipped, overwritten or make the import stop, as on_duplicate tells; rows imported before the import stops are kept.
+// @description  A dry run reads and validates the whole input without storing anything. It doesn't see the ids and names held by pokemons in the trash.
+// @accept       text/csv,application/json,application/x-ndjson
+// @produce      json
+// @param        format        query  string  false  "format of the body, by default taken from Content-Type"  Enums(csv, json, ndjson)
+// @param        on_duplicate  query  string  false  "what to do with pokemons already stored"  Enums(skip, overwrite, fail)  default(fail)
+// @param        dry_run       query  bool    false  "only validate the input"
+// @success      200 {object} importReport
+// @failure      400 {object} importReport "the input can't be read"
+// @failure      400 {string} string "on_duplicate must be skip, overwrite or fail"
+// @failure      409 {object} importReport "the import stopped at a duplicate"
+// @failure      415 {string} string "unsupported format"
+// @router       /pokemons/import [post]
+func (h *Handler) ImportPokemons(c *gin.Context) {
+	format, ok := importFormat(c)
+	if !ok {
+		c.IndentedJSON(http.StatusUnsupportedMediaType, gin.H{"message": "unsupported format, use csv, json or ndjson"})
+		return
+	}
+	onDuplicate := c.DefaultQuery("on_duplicate", onDuplicateFail)
+	if onDuplicate != onDuplicateSkip && onDuplicate != onDuplicateOverwrite && onDuplicate != onDuplicateFail {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "on_duplicate must be skip, overwrite or fail"})
+		return
+	}
+	dryRun := false
+	if s := c.Query("dry_run"); s != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(s); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "dry_run must be true or false"})
+			return
+		}
+	}
+
+	imp := &importer{
+		h:           h,
+		c:           c,
+		onDuplicate: onDuplicate,
+		dryRun:      dryRun,
+		report:      importReport{DryRun: dryRun, Errors: []rowError{}},
+		seenIDs:     make(map[int64]bool),
+		seenSlugs:   make(map[string]int64),
+	}
+	status, err := imp.run(newRowReader(format, c.Request.Body))
+	if err != nil {
+		respondWithInternalError(c, err)
+		return
+	}
+	if !dryRun && imp.report.Created+imp.report.Updated > 0 {
+		// every imported pokemon has its revision, the audit log only tells how many there were
+		h.auditLog.Record(c, audit.ActionImport, "pokemons", nil,
+			gin.H{"created": imp.report.Created, "updated": imp.report.Updated})
+	}
+	c.IndentedJSON(status, imp.report)
+}
+
+// importFormat returns the format of the body of an import request.
+func importFormat(c *gin.Context) (string, bool) {
+	if format := c.Query("format"); format != "" {
+		return format, format == formatCSV || format == formatJSON || format == formatNDJSON
+	}
+	mediaType, _, err := mime.ParseMediaType(c.ContentType())
+	if err != nil {
+		return "", false
+	}
+	format, ok := importFormats[mediaType]
+	return format, ok
+}
+
+// importRow is a valid pokemon waiting to be written.
+type importRow struct {
+	row int
+	p   pokemon
+}
+
+// importer runs one import request.
+type importer struct {
+	h           *Handler
+	c           *gin.Context
+	onDuplicate string
+	dryRun      bool
+	report      importReport
+
+	// seenIDs and seenSlugs hold the pokemons checked in a dry run, which are not stored.
+	seenIDs   map[int64]bool
+	seenSlugs map[string]int64
+}
+
+// run imports the pokemons read from rows and returns the status of the response.
+// The error is only set when the repository fails.
+func (imp *importer) run(rows rowReader) (int, error) {
+	batch := make([]importRow, 0, importBatchSize)
+	for {
+		row, p, err := rows.next()
+		if err == io.EOF {
+			break
+		}
+		var invalid *invalidRowError
+		if errors.As(err, &invalid) {
+			imp.report.Rows++
+			imp.fail(row, nil, http.StatusBadRequest, invalid.Error())
+			continue
+		}
+		if err != nil {
+			// the rows read so far are still imported
+			if _, err := imp.flush(batch); err != nil {
+				return 0, err
+			}
+			imp.report.Message = fmt.Sprintf("the input can't be read: %v", err)
+			return http.StatusBadRequest, nil
+		}
+
+		imp.report.Rows++
+		if err := p.prepare(); err != nil {
+			imp.fail(row, &p.ID, http.StatusBadRequest, err.Error())
+			continue
+		}
+		batch = append(batch, importRow{row: row, p: p})
+		if len(batch) < importBatchSize {
+			continue
+		}
+		stopped, err := imp.flush(batch)
+		if err != nil {
+			return 0, err
+		}
+		if stopped {
+			return http.StatusConflict, nil
+		}
+		batch = batch[:0]
+	}
+
+	stopped, err := imp.flush(batch)
+	if err != nil {
+		return 0, err
+	}
+	if stopped {
+		return http.StatusConflict, nil
+	}
+	return http.StatusOK, nil
+}
+
+func (imp *importer) fail(row int, id *int64, status int, message string) {
+	imp.report.Failed++
+	imp.report.Errors = append(imp.report.Errors, rowError{Row: row, ID: id, Status: status, Message: message})
+}
+
+// flush writes a batch, or only checks it in a dry run, and reports whether the import has to stop at a duplicate.
+func (imp *importer) flush(batch []importRow) (bool, error) {
+	if len(batch) == 0 {
+		return false, nil
+	}
+	results, err := imp.write(batch)
+	if err != nil {
+		return false, err
+	}
+
+	for i, res := range results {
+		r := batch[i]
+		switch {
+		case res.Err == nil && res.Created:
+			imp.report.Created++
+		case res.Err == nil:
+			imp.report.Updated++
+		case imp.onDuplicate == onDuplicateSkip && (errors.Is(res.Err, ErrDuplicateID) || errors.Is(res.Err, ErrDuplicateName)):
+			imp.report.Skipped++
+		case errors.Is(res.Err, ErrDuplicateID) || errors.Is(res.Err, ErrDuplicateName):
+			imp.fail(r.row, &r.p.ID, http.StatusConflict, res.Err.Error())
+		default:
+			return false, res.Err
+		}
+		if res.Err == nil && !imp.dryRun {
+			action := revisionUpdate
+			if res.Created {
+				action = revisionCreate
+			}
+			// the batch is reused, so the revision gets a copy
+			p := r.p
+			imp.h.recordRevision(imp.c, action, p.ID, &p, 0)
+		}
+	}
+
+	// an ordered write stops at the first pokemon it can't store
+	if last := results[len(results)-1]; imp.onDuplicate == onDuplicateFail && last.Err != nil {
+		imp.report.Message = fmt.Sprintf("the import stopped at the duplicate in row %d", batch[len(results)-1].row)
+		return true, nil
+	}
+	return false, nil
+}
+
+// write stores a batch, or only checks it in a dry run.
+func (imp *importer) write(batch []importRow) ([]writeResult, error) {
+	overwrite := imp.onDuplicate == onDuplicateOverwrite
+	ordered := imp.onDuplicate == onDuplicateFail
+	if !imp.dryRun {
+		pokemons := make([]pokemon, len(batch))
+		for i, r := range batch {
+			pokemons[i] = r.p
+		}
+		return imp.h.repo.CreateMany(imp.c.Request.Context(), pokemons, overwrite, ordered)
+	}
+
+	results := make([]writeResult, 0, len(batch))
+	for _, r := range batch {
+		res, err := imp.check(r.p, overwrite)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+		if res.Err != nil && ordered {
+			break
+		}
+	}
+	return results, nil
+}
+
+// check tells what writing p would do, taking into account the pokemons checked before it.
+func (imp *importer) check(p pokemon, overwrite bool) (writeResult, error) {
+	ctx := imp.c.Request.Context()
+	exists := imp.seenIDs[p.ID]
+	if !exists {
+		_, err := imp.h.repo.Get(ctx, p.ID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return writeResult{}, err
+		}
+		exists = err == nil
+	}
+	if exists && !overwrite {
+		return writeResult{Err: ErrDuplicateID}, nil
+	}
+
+	owner, taken := imp.seenSlugs[p.Slug]
+	if !taken {
+		stored, err := imp.h.repo.GetBySlug(ctx, p.Slug)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return writeResult{}, err
+		}
+		owner, taken = stored.ID, err == nil
+	}
+	if taken && owner != p.ID {
+		return writeResult{Err: ErrDuplicateName}, nil
+	}
+
+	imp.seenIDs[p.ID] = true
+	imp.seenSlugs[p.Slug] = p.ID
+	return writeResult{Created: !exists}, nil
+}
+
+// rowReader reads the pokemons of an import one by one.
+type rowReader interface {
+	// next returns the next pokemon and the row it was read from. It returns io.EOF at the end of the input,
+	// an *invalidRowError for a row which can't be decoded and any other error when the input can't be read on.
+	next() (int, pokemon, error)
+}
+
+// invalidRowError is returned by a rowReader for a row which can't be decoded into a pokemon.
+type invalidRowError struct {
+	err error
+}
+
+func (e *invalidRowError) Error() string { return e.err.Error() }
+
+func newRowReader(format string, body io.Reader) rowReader {
+	switch format {
+	case formatCSV:
+		return &csvRows{r: csv.NewReader(body)}
+	case formatNDJSON:
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(nil, maxNDJSONLine)
+		return &ndjsonRows{scanner: scanner}
+	default:
+		return &jsonRows{dec: json.NewDecoder(body)}
+	}
+}
+
+type csvRows struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func (rows *csvRows) next() (int, pokemon, error) {
+	if rows.columns == nil {
+		rows.r.FieldsPerRecord = -1
+		header, err := rows.r.Read()
+		if err == io.EOF {
+			return 0, pokemon{}, errors.New("CSV input must start with a header")
+		}
+		if err != nil {
+			return 0, pokemon{}, err
+		}
+		if rows.columns, err = csvHeader(header); err != nil {
+			return 0, pokemon{}, err
+		}
+	}
+
+	record, err := rows.r.Read()
+	if err != nil {
+		return 0, pokemon{}, err
+	}
+	line, _ := rows.r.FieldPos(0)
+	p, err := parseCSVRecord(rows.columns, record)
+	if err != nil {
+		return line, p, &invalidRowError{err}
+	}
+	return line, p, nil
+}
+
+type jsonRows struct {
+	dec     *json.Decoder
+	started bool
+	n       int
+}
+
+func (rows *jsonRows) next() (int, pokemon, error) {
+	if !rows.started {
+		rows.started = true
+		if t, err := rows.dec.Token(); err != nil || t != json.Delim('[') {
+			return 0, pokemon{}, errors.New("JSON input must be an array of pokemons")
+		}
+	}
+	if !rows.dec.More() {
+		if _, err := rows.dec.Token(); err != nil {
+			return 0, pokemon{}, err
+		}
+		return 0, pokemon{}, io.EOF
+	}
+
+	// a syntax error ends the input, a value which doesn't fit a pokemon only its row
+	var raw json.RawMessage
+	if err := rows.dec.Decode(&raw); err != nil {
+		return 0, pokemon{}, err
+	}
+	rows.n++
+	var p pokemon
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return rows.n, p, &invalidRowError{err}
+	}
+	return rows.n, p, nil
+}
+
+type ndjsonRows struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (rows *ndjsonRows) next() (int, pokemon, error) {
+	for rows.scanner.Scan() {
+		rows.line++
+		data := bytes.TrimSpace(rows.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var p pokemon
+		if err := json.Unmarshal(data, &p); err != nil {
+			return rows.line, p, &invalidRowError{err}
+		}
+		return rows.line, p, nil
+	}
+	if err := rows.scanner.Err(); err != nil {
+		return rows.line + 1, pokemon{}, err
+	}
+	return 0, pokemon{}, io.EOF
+}
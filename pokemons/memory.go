@@ -0,0 +1,271 @@
+package pokemons
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type memoryRepository struct {
+	mu sync.RWMutex
+	// pokemons holds the pokemons in the trash too.
+	pokemons map[int64]pokemon
+	// slugs maps the slug of every stored name to the pokemon id.
+	slugs map[string]int64
+}
+
+// NewMemoryRepository returns a PokemonRepository that keeps pokemons in process memory.
+// It is safe for concurrent use and loses its data when the process exits.
+func NewMemoryRepository() PokemonRepository {
+	return &memoryRepository{
+		pokemons: make(map[int64]pokemon),
+		slugs:    make(map[string]int64),
+	}
+}
+
+func (r *memoryRepository) Create(ctx context.Context, p *pokemon) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.create(p)
+}
+
+// create stores a new pokemon. The caller must hold r.mu.
+func (r *memoryRepository) create(p *pokemon) error {
+	if _, ok := r.pokemons[p.ID]; ok {
+		return ErrDuplicateID
+	}
+	if _, ok := r.slugs[p.Slug]; ok {
+		return ErrDuplicateName
+	}
+	p.stamp(1)
+	r.pokemons[p.ID] = *p
+	r.slugs[p.Slug] = p.ID
+	return nil
+}
+
+func (r *memoryRepository) CreateMany(ctx context.Context, pokemons []pokemon, overwrite, ordered bool) ([]writeResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	results := make([]writeResult, 0, len(pokemons))
+	for i := range pokemons {
+		p := &pokemons[i]
+		var res writeResult
+		if overwrite {
+			res.Created, res.Err = r.upsert(p, 0)
+		} else {
+			res.Err = r.create(p)
+			res.Created = res.Err == nil
+		}
+		results = append(results, res)
+		if res.Err != nil && ordered {
+			break
+		}
+	}
+	return results, nil
+}
+
+// Atomically runs fn on a copy of the repository, which replaces the repository when fn succeeds.
+// Other writers wait for fn to return.
+func (r *memoryRepository) Atomically(ctx context.Context, fn func(ctx context.Context, repo PokemonRepository) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx := &memoryRepository{
+		pokemons: make(map[int64]pokemon, len(r.pokemons)),
+		slugs:    make(map[string]int64, len(r.slugs)),
+	}
+	for id, p := range r.pokemons {
+		tx.pokemons[id] = p
+	}
+	for slug, id := range r.slugs {
+		tx.slugs[slug] = id
+	}
+	if err := fn(ctx, tx); err != nil {
+		return err
+	}
+	r.pokemons, r.slugs = tx.pokemons, tx.slugs
+	return nil
+}
+
+func (r *memoryRepository) Get(ctx context.Context, id int64) (pokemon, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.pokemons[id]
+	if !ok || p.DeletedAt != nil {
+		return pokemon{}, ErrNotFound
+	}
+	return p, nil
+}
+
+func (r *memoryRepository) GetBySlug(ctx context.Context, slug string) (pokemon, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.slugs[slug]
+	if !ok || r.pokemons[id].DeletedAt != nil {
+		return pokemon{}, ErrNotFound
+	}
+	return r.pokemons[id], nil
+}
+
+// live returns the pokemons which are not in the trash. The caller must hold r.mu.
+func (r *memoryRepository) live() []pokemon {
+	pokemons := make([]pokemon, 0, len(r.pokemons))
+	for _, p := range r.pokemons {
+		if p.DeletedAt == nil {
+			pokemons = append(pokemons, p)
+		}
+	}
+	return pokemons
+}
+
+func (r *memoryRepository) List(ctx context.Context, q ListQuery) ([]pokemon, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return applyQuery(r.live(), q)
+}
+
+func (r *memoryRepository) Each(ctx context.Context, q ListQuery, fn func(pokemon) error) error {
+	// fn may be slow, so it is called without holding the lock
+	r.mu.RLock()
+	pokemons := r.live()
+	r.mu.RUnlock()
+
+	return eachMatching(pokemons, q, fn)
+}
+
+func (r *memoryRepository) Search(ctx context.Context, query string, limit int) ([]searchResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return rankPokemons(query, r.live(), limit), nil
+}
+
+func (r *memoryRepository) Upsert(ctx context.Context, p *pokemon, version int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.upsert(p, version)
+}
+
+// upsert replaces or inserts a pokemon. The caller must hold r.mu.
+func (r *memoryRepository) upsert(p *pokemon, version int64) (bool, error) {
+	old, exists := r.pokemons[p.ID]
+	if version != 0 && (!exists || old.DeletedAt != nil || old.Version != version) {
+		return false, ErrVersionMismatch
+	}
+	if id, ok := r.slugs[p.Slug]; ok && id != p.ID {
+		return false, ErrDuplicateName
+	}
+	if exists {
+		delete(r.slugs, old.Slug)
+	}
+	p.stamp(old.Version + 1)
+	r.pokemons[p.ID] = *p
+	r.slugs[p.Slug] = p.ID
+	return !exists || old.DeletedAt != nil, nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, id int64, version int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.pokemons[id]
+	if !ok || p.DeletedAt != nil {
+		return ErrNotFound
+	}
+	if version != 0 && p.Version != version {
+		return ErrVersionMismatch
+	}
+	p.DeletedAt = &at
+	p.stamp(p.Version + 1)
+	r.pokemons[id] = p
+	return nil
+}
+
+func (r *memoryRepository) DeleteAll(ctx context.Context, at time.Time) ([]int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := []int64{}
+	for id, p := range r.pokemons {
+		if p.DeletedAt == nil {
+			p.DeletedAt = &at
+			p.stamp(p.Version + 1)
+			r.pokemons[id] = p
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+func (r *memoryRepository) ListDeleted(ctx context.Context) ([]pokemon, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var pokemons = []pokemon{}
+	for _, p := range r.pokemons {
+		if p.DeletedAt != nil {
+			pokemons = append(pokemons, p)
+		}
+	}
+	sortByDeletion(pokemons)
+	return pokemons, nil
+}
+
+func (r *memoryRepository) Restore(ctx context.Context, id int64) (pokemon, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.pokemons[id]
+	if !ok || p.DeletedAt == nil {
+		return pokemon{}, ErrNotFound
+	}
+	p.DeletedAt = nil
+	p.stamp(p.Version + 1)
+	r.pokemons[id] = p
+	return p, nil
+}
+
+func (r *memoryRepository) Purge(ctx context.Context, id int64) (pokemon, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.pokemons[id]
+	if !ok || p.DeletedAt == nil {
+		return pokemon{}, ErrNotFound
+	}
+	delete(r.pokemons, id)
+	delete(r.slugs, p.Slug)
+	return p, nil
+}
+
+func (r *memoryRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	for id, p := range r.pokemons {
+		if p.DeletedAt != nil && p.DeletedAt.Before(before) {
+			delete(r.pokemons, id)
+			delete(r.slugs, p.Slug)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// sortByDeletion orders pokemons in the trash from the most recently deleted, then by id.
+func sortByDeletion(pokemons []pokemon) {
+	sort.Slice(pokemons, func(i, j int) bool {
+		a, b := pokemons[i], pokemons[j]
+		if !a.DeletedAt.Equal(*b.DeletedAt) {
+			return a.DeletedAt.After(*b.DeletedAt)
+		}
+		return a.ID < b.ID
+	})
+}
@@ -0,0 +1,512 @@
+package pokemons
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRepository struct {
+	collection *mongo.Collection
+}
+
+// slugIndex is the name of the unique index on pokemon name slugs.
+const slugIndex = "slug_unique"
+
+// notDeleted matches the pokemons which are not in the trash.
+var notDeleted = bson.E{Key: "deleted_at", Value: bson.D{{Key: "$exists", Value: false}}}
+
+// inTrash matches the pokemons which are in the trash.
+var inTrash = bson.E{Key: "deleted_at", Value: bson.D{{Key: "$exists", Value: true}}}
+
+// NewMongoRepository returns a PokemonRepository backed by the given MongoDB collection
+// and creates the indexes it relies on.
+func NewMongoRepository(collection *mongo.Collection) (PokemonRepository, error) {
+	r := &mongoRepository{collection: collection}
+	if err := r.backfillSlugs(context.Background()); err != nil {
+		return r, err
+	}
+
+	// a collection can have only one text index, so the one covering just names has to go
+	_, err := collection.Indexes().DropOne(context.Background(), "search_text")
+	if err != nil && !isIndexNotFound(err) {
+		return r, err
+	}
+
+	_, err = collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "name", Value: "text"}, {Key: "genus", Value: "text"}},
+			Options: options.Index().SetName("search_text_v2"),
+		},
+		{
+			Keys:    bson.D{{Key: "slug", Value: 1}},
+			Options: options.Index().SetName(slugIndex).SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetName("deleted_at").SetSparse(true),
+		},
+	})
+	return r, err
+}
+
+// Atomically runs fn in a multi-document transaction, which needs a replica set or a sharded cluster.
+// fn may be called again when the transaction hits a transient error.
+func (r *mongoRepository) Atomically(ctx context.Context, fn func(ctx context.Context, repo PokemonRepository) error) error {
+	session, err := r.collection.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc, r)
+	})
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Name == "IllegalOperation" {
+		return ErrTransactionsUnsupported
+	}
+	return err
+}
+
+// isIndexNotFound reports whether err says that a dropped index or its collection doesn't exist.
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && (cmdErr.Name == "IndexNotFound" || cmdErr.Name == "NamespaceNotFound")
+}
+
+// backfillSlugs sets the slug of pokemons stored before names had slugs,
+// so that the unique slug index can be built.
+func (r *mongoRepository) backfillSlugs(ctx context.Context) error {
+	pokemons, err := r.find(ctx, bson.D{{Key: "slug", Value: bson.D{{Key: "$exists", Value: false}}}}, options.Find())
+	if err != nil {
+		return err
+	}
+	for _, p := range pokemons {
+		update := bson.D{{Key: "$set", Value: bson.D{{Key: "slug", Value: slugify(p.Name)}}}}
+		if _, err := r.collection.UpdateByID(ctx, p.ID, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// duplicateKeyError tells which unique index a duplicate key error came from.
+func duplicateKeyError(err error) error {
+	if !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	if strings.Contains(err.Error(), slugIndex) {
+		return ErrDuplicateName
+	}
+	return ErrDuplicateID
+}
+
+func (r *mongoRepository) Create(ctx context.Context, p *pokemon) error {
+	p.stamp(1)
+	_, err := r.collection.InsertOne(ctx, p)
+	return duplicateKeyError(err)
+}
+
+// CreateMany sends the batch as one InsertMany, or a bulk write of upserts when overwrite is set.
+// The bulk write doesn't return the documents, so the versions it incremented are read back afterwards.
+func (r *mongoRepository) CreateMany(ctx context.Context, pokemons []pokemon, overwrite, ordered bool) ([]writeResult, error) {
+	if len(pokemons) == 0 {
+		return []writeResult{}, nil
+	}
+
+	// the pokemons which are stored and not in the trash tell replacements from creations
+	live := make(map[int64]bool)
+	var err error
+	if overwrite {
+		ids := make(bson.A, len(pokemons))
+		for i, p := range pokemons {
+			ids[i] = p.ID
+		}
+		filter := bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}, notDeleted}
+		var stored []pokemon
+		stored, err = r.find(ctx, filter, options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}}))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range stored {
+			live[p.ID] = true
+		}
+
+		models := make([]mongo.WriteModel, len(pokemons))
+		for i := range pokemons {
+			pokemons[i].stamp(0)
+			filter := bson.D{{Key: "_id", Value: pokemons[i].ID}}
+			models[i] = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(upsertUpdate(pokemons[i])).SetUpsert(true)
+		}
+		_, err = r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(ordered))
+	} else {
+		docs := make([]interface{}, len(pokemons))
+		for i := range pokemons {
+			pokemons[i].stamp(1)
+			docs[i] = pokemons[i]
+		}
+		_, err = r.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(ordered))
+	}
+
+	results := make([]writeResult, len(pokemons))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, we := range bulkErr.WriteErrors {
+			results[we.Index].Err = writeError(we.WriteError)
+		}
+		if ordered && len(bulkErr.WriteErrors) > 0 {
+			// the pokemons following the failed one were not sent
+			results = results[:bulkErr.WriteErrors[0].Index+1]
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		if results[i].Err == nil {
+			id := pokemons[i].ID
+			results[i].Created = !live[id]
+			live[id] = true
+		}
+	}
+	if overwrite {
+		if err := r.readVersions(ctx, pokemons, results); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// readVersions sets the Version of the pokemons which were stored to the one in the collection.
+func (r *mongoRepository) readVersions(ctx context.Context, pokemons []pokemon, results []writeResult) error {
+	ids := bson.A{}
+	for i := range results {
+		if results[i].Err == nil {
+			ids = append(ids, pokemons[i].ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	opts := options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}, {Key: "version", Value: 1}})
+	stored, err := r.find(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}}, opts)
+	if err != nil {
+		return err
+	}
+	versions := make(map[int64]int64, len(stored))
+	for _, p := range stored {
+		versions[p.ID] = p.Version
+	}
+	for i := range results {
+		if results[i].Err == nil {
+			pokemons[i].Version = versions[pokemons[i].ID]
+		}
+	}
+	return nil
+}
+
+// writeError translates an error of one write in a bulk operation.
+func writeError(we mongo.WriteError) error {
+	if we.Code == 11000 {
+		if strings.Contains(we.Message, slugIndex) {
+			return ErrDuplicateName
+		}
+		return ErrDuplicateID
+	}
+	return errors.New(we.Message)
+}
+
+func (r *mongoRepository) Get(ctx context.Context, id int64) (pokemon, error) {
+	result := pokemon{}
+
+	err := r.collection.FindOne(ctx, bson.D{{Key: "_id", Value: id}, notDeleted}).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		return result, ErrNotFound
+	}
+	return result, err
+}
+
+func (r *mongoRepository) GetBySlug(ctx context.Context, slug string) (pokemon, error) {
+	result := pokemon{}
+
+	err := r.collection.FindOne(ctx, bson.D{{Key: "slug", Value: slug}, notDeleted}).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		return result, ErrNotFound
+	}
+	return result, err
+}
+
+func (r *mongoRepository) List(ctx context.Context, q ListQuery) ([]pokemon, int64, error) {
+	if err := q.validate(); err != nil {
+		return nil, 0, err
+	}
+
+	filter := mongoFilter(q.Filter)
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	field := sortFields[q.Sort]
+	desc := q.descending()
+	opts := options.Find().SetSort(mongoSort(field, desc))
+
+	if q.Cursor != nil {
+		value, err := q.Cursor.value()
+		if err != nil {
+			return nil, 0, err
+		}
+		op := "$gt"
+		if desc {
+			op = "$lt"
+		}
+		keyset := bson.D{{Key: "_id", Value: bson.D{{Key: op, Value: q.Cursor.ID}}}}
+		if field.key != "_id" {
+			keyset = bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: field.key, Value: bson.D{{Key: op, Value: value}}}},
+				bson.D{{Key: field.key, Value: value}, {Key: "_id", Value: bson.D{{Key: op, Value: q.Cursor.ID}}}},
+			}}}
+		}
+		filter = bson.D{{Key: "$and", Value: bson.A{filter, keyset}}}
+	} else if q.Offset > 0 {
+		opts.SetSkip(int64(q.Offset))
+	}
+	if q.Limit > 0 {
+		opts.SetLimit(int64(q.Limit))
+	}
+
+	pokemons, err := r.find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	if desc != q.Desc {
+		reverse(pokemons)
+	}
+	return pokemons, total, nil
+}
+
+// mongoSort returns the sort document ordering pokemons by field, then by id.
+func mongoSort(field sortField, desc bool) bson.D {
+	direction := 1
+	if desc {
+		direction = -1
+	}
+	sortBy := bson.D{{Key: field.key, Value: direction}}
+	if field.key != "_id" {
+		sortBy = append(sortBy, bson.E{Key: "_id", Value: direction})
+	}
+	return sortBy
+}
+
+// Each decodes the pokemons one by one from the cursor, so they are never all in memory.
+func (r *mongoRepository) Each(ctx context.Context, q ListQuery, fn func(pokemon) error) error {
+	if _, ok := sortFields[q.Sort]; !ok {
+		return errors.New("unknown sort field")
+	}
+	opts := options.Find().SetSort(mongoSort(sortFields[q.Sort], q.Desc))
+	cur, err := r.collection.Find(ctx, mongoFilter(q.Filter), opts)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		result := pokemon{}
+		if err := cur.Decode(&result); err != nil {
+			return err
+		}
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+func (r *mongoRepository) find(ctx context.Context, filter interface{}, opts *options.FindOptions) ([]pokemon, error) {
+	var pokemons = []pokemon{}
+
+	cur, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		result := pokemon{}
+		if err := cur.Decode(&result); err != nil {
+			return nil, err
+		}
+		pokemons = append(pokemons, result)
+	}
+	return pokemons, cur.Err()
+}
+
+// Search looks the query up in the text index first. The index only matches whole words,
+// so when it finds nothing, as with misspelt names, every pokemon is scored by rankPokemons.
+func (r *mongoRepository) Search(ctx context.Context, query string, limit int) ([]searchResult, error) {
+	textScore := bson.D{{Key: "score", Value: bson.D{{Key: "$meta", Value: "textScore"}}}}
+	opts := options.Find().SetProjection(textScore).SetSort(textScore)
+	candidates, err := r.find(ctx, bson.D{{Key: "$text", Value: bson.D{{Key: "$search", Value: query}}}, notDeleted}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(candidates) == 0 {
+		candidates, err = r.find(ctx, bson.D{notDeleted}, options.Find())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rankPokemons(query, candidates, limit), nil
+}
+
+// mongoFilter translates f into a MongoDB query document matching the pokemons which are not in the trash.
+func mongoFilter(f Filter) bson.D {
+	filter := bson.D{notDeleted}
+	if f.IsLegendary != nil {
+		filter = append(filter, bson.E{Key: "is_legendary", Value: *f.IsLegendary})
+	}
+	if f.Color != "" {
+		filter = append(filter, bson.E{Key: "color", Value: f.Color})
+	}
+	if f.NamePrefix != "" {
+		filter = append(filter, bson.E{Key: "name", Value: primitive.Regex{Pattern: "^" + regexp.QuoteMeta(f.NamePrefix), Options: "i"}})
+	}
+	return filter
+}
+
+// upsertUpdate returns the update document storing p over whatever has its id. p has no deleted_at,
+// so a pokemon in the trash has to be taken out of it explicitly. The version of p is 0, which leaves it
+// out of $set, and is incremented in the same update.
+func upsertUpdate(p pokemon) bson.D {
+	return bson.D{
+		{Key: "$set", Value: p},
+		{Key: "$unset", Value: bson.D{{Key: "deleted_at", Value: ""}}},
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+	}
+}
+
+// trashUpdate moves a pokemon into the trash at the given time, or out of it when at is nil,
+// counting the move as a change of the pokemon.
+func trashUpdate(at *time.Time) bson.D {
+	set := bson.D{{Key: "updated_at", Value: time.Now().UTC().Truncate(time.Millisecond)}}
+	update := bson.D{{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}}}
+	if at == nil {
+		update = append(update, bson.E{Key: "$unset", Value: bson.D{{Key: "deleted_at", Value: ""}}})
+	} else {
+		set = append(set, bson.E{Key: "deleted_at", Value: *at})
+	}
+	return append(update, bson.E{Key: "$set", Value: set})
+}
+
+// Upsert only inserts when version is 0. With a version, a pokemon which is missing, in the trash or
+// at another version matches nothing and ErrVersionMismatch is returned.
+func (r *mongoRepository) Upsert(ctx context.Context, p *pokemon, version int64) (bool, error) {
+	opts := options.FindOneAndUpdate().SetUpsert(version == 0).SetReturnDocument(options.Before)
+	filter := bson.D{{Key: "_id", Value: p.ID}}
+	if version != 0 {
+		filter = append(filter, bson.E{Key: "version", Value: version}, notDeleted)
+	}
+	p.stamp(0)
+
+	old := pokemon{}
+	err := r.collection.FindOneAndUpdate(ctx, filter, upsertUpdate(*p), opts).Decode(&old)
+	if err == mongo.ErrNoDocuments {
+		if version != 0 {
+			return false, ErrVersionMismatch
+		}
+		p.Version = 1
+		return true, nil
+	}
+	if err != nil {
+		return false, duplicateKeyError(err)
+	}
+	p.Version = old.Version + 1
+	return old.DeletedAt != nil, nil
+}
+
+func (r *mongoRepository) Delete(ctx context.Context, id int64, version int64, at time.Time) error {
+	filter := bson.D{{Key: "_id", Value: id}, notDeleted}
+	if version != 0 {
+		filter = append(filter, bson.E{Key: "version", Value: version})
+	}
+	res, err := r.collection.UpdateOne(ctx, filter, trashUpdate(&at))
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		// tell a pokemon at another version from a missing one
+		if version != 0 {
+			if _, err := r.Get(ctx, id); err == nil {
+				return ErrVersionMismatch
+			}
+		}
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteAll updates the pokemons one by one, as UpdateMany doesn't tell which documents it changed.
+// A pokemon deleted by someone else in the meantime is skipped, so the returned ids are exactly those
+// this call moved to the trash.
+func (r *mongoRepository) DeleteAll(ctx context.Context, at time.Time) ([]int64, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetProjection(bson.D{{Key: "_id", Value: 1}})
+	live, err := r.find(ctx, bson.D{notDeleted}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	update := trashUpdate(&at)
+	ids := []int64{}
+	for _, p := range live {
+		res, err := r.collection.UpdateOne(ctx, bson.D{{Key: "_id", Value: p.ID}, notDeleted}, update)
+		if err != nil {
+			return ids, err
+		}
+		if res.ModifiedCount > 0 {
+			ids = append(ids, p.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *mongoRepository) ListDeleted(ctx context.Context) ([]pokemon, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "deleted_at", Value: -1}, {Key: "_id", Value: 1}})
+	return r.find(ctx, bson.D{inTrash}, opts)
+}
+
+func (r *mongoRepository) Restore(ctx context.Context, id int64) (pokemon, error) {
+	result := pokemon{}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	update := trashUpdate(nil)
+	err := r.collection.FindOneAndUpdate(ctx, bson.D{{Key: "_id", Value: id}, inTrash}, update, opts).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		return result, ErrNotFound
+	}
+	return result, err
+}
+
+func (r *mongoRepository) Purge(ctx context.Context, id int64) (pokemon, error) {
+	result := pokemon{}
+
+	err := r.collection.FindOneAndDelete(ctx, bson.D{{Key: "_id", Value: id}, inTrash}).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		return result, ErrNotFound
+	}
+	return result, err
+}
+
+func (r *mongoRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.collection.DeleteMany(ctx, bson.D{{Key: "deleted_at", Value: bson.D{{Key: "$lt", Value: before}}}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,59 @@
+package pokemons
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"example.com/pokemon-handbook/config"
+)
+
+// BenchmarkMongoGet compares reading a pokemon through the client shared by all requests with connecting
+// to the server for every request, as the handlers did before. It needs a MongoDB server at MONGODB_URL,
+// mongodb://localhost:27017 by default, and is skipped without one.
+func BenchmarkMongoGet(b *testing.B) {
+	config.Conf.DatabaseURL = os.Getenv("MONGODB_URL")
+	if config.Conf.DatabaseURL == "" {
+		config.Conf.DatabaseURL = "mongodb://localhost:27017"
+	}
+	config.Conf.MongoServerSelectionTimeout = 2
+
+	ctx := context.Background()
+	shared, err := config.NewMongoClient()
+	if err != nil {
+		b.Skipf("no MongoDB server: %v", err)
+	}
+	defer shared.Disconnect(ctx)
+	collection := shared.Database("pokemon_handbook_bench").Collection("pokemons")
+	defer collection.Drop(ctx)
+
+	repo, err := NewMongoRepository(collection)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := repo.Create(ctx, &pokemon{ID: 25, Name: "Pikachu", Slug: "pikachu"}); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("shared client", func(b *testing.B) {
+		repo := &mongoRepository{collection: collection}
+		for i := 0; i < b.N; i++ {
+			if _, err := repo.Get(ctx, 25); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("client per request", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			client, err := config.NewMongoClient()
+			if err != nil {
+				b.Fatal(err)
+			}
+			repo := &mongoRepository{collection: client.Database("pokemon_handbook_bench").Collection("pokemons")}
+			if _, err := repo.Get(ctx, 25); err != nil {
+				b.Fatal(err)
+			}
+			client.Disconnect(ctx)
+		}
+	})
+}
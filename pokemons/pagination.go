@@ -0,0 +1,127 @@
+package pokemons
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxListLimit caps the page size a client can ask GET /pokemons for.
+const maxListLimit = 1000
+
+// parseListQuery reads the filter, sort and pagination parameters of GET /pokemons.
+func parseListQuery(c *gin.Context) (ListQuery, error) {
+	q := ListQuery{Sort: "id"}
+
+	if s := c.Query("sort"); s != "" {
+		q.Sort = strings.TrimPrefix(s, "-")
+		q.Desc = strings.HasPrefix(s, "-")
+		if _, ok := sortFields[q.Sort]; !ok {
+			return q, fmt.Errorf("pokemons can't be sorted by %q", q.Sort)
+		}
+	}
+
+	if s := c.Query("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			return q, fmt.Errorf("limit must be a number from 1 to %d", maxListLimit)
+		}
+		q.Limit = limit
+	}
+
+	if s := c.Query("offset"); s != "" {
+		offset, err := strconv.Atoi(s)
+		if err != nil || offset < 0 {
+			return q, errors.New("offset must be a non-negative number")
+		}
+		q.Offset = offset
+	}
+
+	if s := c.Query("cursor"); s != "" {
+		if c.Query("offset") != "" {
+			return q, errors.New("cursor and offset can't be used together")
+		}
+		cur, err := DecodeCursor(s)
+		if err != nil {
+			return q, err
+		}
+		q.Cursor = cur
+	}
+	if err := q.validate(); err != nil {
+		return q, err
+	}
+
+	if s := c.Query("is_legendary"); s != "" {
+		legendary, err := strconv.ParseBool(s)
+		if err != nil {
+			return q, errors.New("is_legendary must be true or false")
+		}
+		q.Filter.IsLegendary = &legendary
+	}
+	q.Filter.Color = c.Query("color")
+	q.Filter.NamePrefix = c.Query("name_prefix")
+
+	return q, nil
+}
+
+// paginate trims the extra pokemon fetched to detect a following page
+// and returns the Link header values pointing at the neighbouring pages.
+// Pages are linked by offset when the client asked for an offset and by cursor otherwise.
+func paginate(c *gin.Context, q ListQuery, pokemons []pokemon) ([]pokemon, []string) {
+	if q.Limit == 0 {
+		return pokemons, nil
+	}
+
+	var hasNext, hasPrev bool
+	if q.Cursor != nil && q.Cursor.Before {
+		hasNext = true
+		hasPrev = len(pokemons) > q.Limit
+		if hasPrev {
+			pokemons = pokemons[1:]
+		}
+	} else {
+		hasNext = len(pokemons) > q.Limit
+		hasPrev = q.Cursor != nil || q.Offset > 0
+		if hasNext {
+			pokemons = pokemons[:q.Limit]
+		}
+	}
+
+	var links []string
+	if c.Query("offset") != "" {
+		if hasNext {
+			links = append(links, pageLink(c, "next", "offset", strconv.Itoa(q.Offset+q.Limit)))
+		}
+		if hasPrev {
+			prev := q.Offset - q.Limit
+			if prev < 0 {
+				prev = 0
+			}
+			links = append(links, pageLink(c, "prev", "offset", strconv.Itoa(prev)))
+		}
+		return pokemons, links
+	}
+
+	if len(pokemons) == 0 {
+		return pokemons, nil
+	}
+	if hasNext {
+		links = append(links, pageLink(c, "next", "cursor", newCursor(q, pokemons[len(pokemons)-1], false).Encode()))
+	}
+	if hasPrev {
+		links = append(links, pageLink(c, "prev", "cursor", newCursor(q, pokemons[0], true).Encode()))
+	}
+	return pokemons, links
+}
+
+// pageLink returns a Link header value for the current request with the query parameter key set to value.
+func pageLink(c *gin.Context, rel, key, value string) string {
+	u := *c.Request.URL
+	values := u.Query()
+	values.Set(key, value)
+	u.RawQuery = values.Encode()
+	return fmt.Sprintf("<%s>; rel=\"%s\"", u.RequestURI(), rel)
+}
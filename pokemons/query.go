@@ -0,0 +1,257 @@
+package pokemons
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ListQuery describes which page of pokemons PokemonRepository.List returns.
+type ListQuery struct {
+	Filter Filter
+	// Sort is the json name of the field to sort by; ties are broken by id.
+	Sort string
+	Desc bool
+	// Limit is the maximum number of pokemons to return, 0 means no limit.
+	Limit  int
+	Offset int
+	// Cursor, when set, replaces Offset with keyset pagination.
+	Cursor *Cursor
+}
+
+// Filter restricts the pokemons returned by PokemonRepository.List. Zero fields match everything.
+type Filter struct {
+	IsLegendary *bool
+	Color       string
+	// NamePrefix is matched case-insensitively.
+	NamePrefix string
+}
+
+// Cursor points at the pokemon a page starts after (or ends before) in a given sort order.
+type Cursor struct {
+	Sort   string          `json:"s"`
+	Desc   bool            `json:"d,omitempty"`
+	Before bool            `json:"b,omitempty"`
+	Value  json.RawMessage `json:"v"`
+	ID     int64           `json:"id"`
+}
+
+// ErrInvalidCursor is returned when a cursor can't be decoded or doesn't match the requested sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type sortField struct {
+	key   string
+	value func(p pokemon) interface{}
+}
+
+// sortFields lists the fields pokemons can be sorted by, keyed by json name.
+var sortFields = map[string]sortField{
+	"id":           {key: "_id", value: func(p pokemon) interface{} { return p.ID }},
+	"name":         {key: "name", value: func(p pokemon) interface{} { return p.Name }},
+	"is_legendary": {key: "is_legendary", value: func(p pokemon) interface{} { return p.IsLegendary }},
+	"color":        {key: "color", value: func(p pokemon) interface{} { return p.Color }},
+	"primary_type": {key: "primary_type", value: func(p pokemon) interface{} { return p.PrimaryType }},
+	"height":       {key: "height", value: func(p pokemon) interface{} { return p.Height }},
+	"weight":       {key: "weight", value: func(p pokemon) interface{} { return p.Weight }},
+	"generation":   {key: "generation", value: func(p pokemon) interface{} { return int64(p.Generation) }},
+	"genus":        {key: "genus", value: func(p pokemon) interface{} { return p.Genus }},
+}
+
+// newCursor returns a cursor pointing at p in the sort order of q.
+func newCursor(q ListQuery, p pokemon, before bool) *Cursor {
+	value, _ := json.Marshal(sortFields[q.Sort].value(p))
+	return &Cursor{Sort: q.Sort, Desc: q.Desc, Before: before, Value: value, ID: p.ID}
+}
+
+// Encode returns the opaque form of the cursor used in query strings.
+func (cur *Cursor) Encode() string {
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor produced by Cursor.Encode.
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	cur := &Cursor{}
+	if err := json.Unmarshal(data, cur); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if _, err := cur.value(); err != nil {
+		return nil, err
+	}
+	return cur, nil
+}
+
+// value decodes the sort value stored in the cursor into the type of its sort field.
+func (cur *Cursor) value() (interface{}, error) {
+	field, ok := sortFields[cur.Sort]
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	v := reflect.New(reflect.TypeOf(field.value(pokemon{})))
+	if err := json.Unmarshal(cur.Value, v.Interface()); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return v.Elem().Interface(), nil
+}
+
+// validate checks that q can be answered.
+func (q ListQuery) validate() error {
+	if _, ok := sortFields[q.Sort]; !ok {
+		return errors.New("unknown sort field")
+	}
+	if q.Cursor != nil && (q.Cursor.Sort != q.Sort || q.Cursor.Desc != q.Desc) {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+// descending reports whether the repository has to read in descending order,
+// which is reversed when paging backwards from a cursor.
+func (q ListQuery) descending() bool {
+	if q.Cursor != nil && q.Cursor.Before {
+		return !q.Desc
+	}
+	return q.Desc
+}
+
+func (f Filter) matches(p pokemon) bool {
+	if f.IsLegendary != nil && p.IsLegendary != *f.IsLegendary {
+		return false
+	}
+	if f.Color != "" && p.Color != f.Color {
+		return false
+	}
+	if f.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(p.Name), strings.ToLower(f.NamePrefix)) {
+		return false
+	}
+	return true
+}
+
+// compareValues orders two values of the same sort field.
+func compareValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case int64:
+		b := b.(int64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	case float64:
+		b := b.(float64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	case string:
+		return strings.Compare(a, b.(string))
+	case bool:
+		b := b.(bool)
+		switch {
+		case !a && b:
+			return -1
+		case a && !b:
+			return 1
+		}
+	}
+	return 0
+}
+
+// compare orders two pokemons by the sort field of q and then by id.
+func (q ListQuery) compare(a, b pokemon) int {
+	field := sortFields[q.Sort]
+	if c := compareValues(field.value(a), field.value(b)); c != 0 {
+		return c
+	}
+	return compareValues(a.ID, b.ID)
+}
+
+// applyQuery answers q over all stored pokemons for the backends that can't query natively.
+// It returns the requested page and the number of pokemons matching the filter.
+func applyQuery(all []pokemon, q ListQuery) ([]pokemon, int64, error) {
+	if err := q.validate(); err != nil {
+		return nil, 0, err
+	}
+
+	matched := make([]pokemon, 0, len(all))
+	for _, p := range all {
+		if q.Filter.matches(p) {
+			matched = append(matched, p)
+		}
+	}
+	total := int64(len(matched))
+
+	desc := q.descending()
+	sort.Slice(matched, func(i, j int) bool {
+		c := q.compare(matched[i], matched[j])
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	if q.Cursor != nil {
+		value, err := q.Cursor.value()
+		if err != nil {
+			return nil, 0, err
+		}
+		field := sortFields[q.Sort]
+		start := sort.Search(len(matched), func(i int) bool {
+			c := compareValues(field.value(matched[i]), value)
+			if c == 0 {
+				c = compareValues(matched[i].ID, q.Cursor.ID)
+			}
+			if desc {
+				return c < 0
+			}
+			return c > 0
+		})
+		matched = matched[start:]
+	} else if q.Offset > 0 {
+		if q.Offset >= len(matched) {
+			matched = matched[:0]
+		} else {
+			matched = matched[q.Offset:]
+		}
+	}
+
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+	}
+	if desc != q.Desc {
+		reverse(matched)
+	}
+	return matched, total, nil
+}
+
+// eachMatching is Each for repositories which load the whole collection: it sorts the pokemons matching
+// q.Filter before calling fn with each of them.
+func eachMatching(all []pokemon, q ListQuery, fn func(pokemon) error) error {
+	q.Limit, q.Offset, q.Cursor = 0, 0, nil
+	matched, _, err := applyQuery(all, q)
+	if err != nil {
+		return err
+	}
+	for _, p := range matched {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func reverse(pokemons []pokemon) {
+	for i, j := 0, len(pokemons)-1; i < j; i, j = i+1, j-1 {
+		pokemons[i], pokemons[j] = pokemons[j], pokemons[i]
+	}
+}
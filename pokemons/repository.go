@@ -0,0 +1,92 @@
+package pokemons
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by a PokemonRepository when there is no pokemon with the requested id.
+var ErrNotFound = errors.New("pokemon not found")
+
+// ErrDuplicateID is returned by PokemonRepository.Create when a pokemon with the same id is already stored.
+var ErrDuplicateID = errors.New("a pokemon with such id already exists")
+
+// ErrInvalidID is returned when a path parameter is neither a pokemon id nor a name slug.
+var ErrInvalidID = errors.New("must be a number or a name")
+
+// ErrDuplicateName is returned by PokemonRepository.Create and Upsert when another pokemon has a name with the same slug.
+var ErrDuplicateName = errors.New("a pokemon with such name already exists")
+
+// ErrVersionMismatch is returned by PokemonRepository.Upsert and Delete when the pokemon to change is not
+// at the expected version.
+var ErrVersionMismatch = errors.New("the pokemon has been changed since it was read")
+
+// ErrTransactionsUnsupported is returned by PokemonRepository.Atomically when the storage has no transactions,
+// as MongoDB servers outside of a replica set.
+var ErrTransactionsUnsupported = errors.New("the database doesn't support transactions")
+
+// writeResult is the result of storing one pokemon of a batch.
+type writeResult struct {
+	// Created is false when an existing pokemon was replaced.
+	Created bool
+	// Err is ErrDuplicateID, ErrDuplicateName or another error keeping the pokemon from being stored.
+	Err error
+}
+
+// stamp sets the version and the modification time of a pokemon about to be stored.
+func (p *pokemon) stamp(version int64) {
+	p.Version = version
+	p.UpdatedAt = time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// PokemonRepository is the storage used by the pokemon handlers.
+//
+// Deleted pokemons are moved to the trash: they are hidden from every method but the trash ones,
+// yet keep their id and name until they are purged.
+//
+// Every write of a pokemon increments its Version and sets its UpdatedAt. Upsert and Delete can be told
+// the version the pokemon must be at, so that changes based on an outdated read are refused.
+type PokemonRepository interface {
+	// Create stores a new pokemon at version 1, setting the Version and UpdatedAt of p,
+	// or returns ErrDuplicateID or ErrDuplicateName.
+	Create(ctx context.Context, p *pokemon) error
+	// CreateMany stores a batch of pokemons as if Create, or Upsert when overwrite is set, was called for each
+	// of them in order, and returns the result of each call. When ordered is set it stops at the first pokemon
+	// which can't be stored, so there are fewer results than pokemons.
+	CreateMany(ctx context.Context, pokemons []pokemon, overwrite, ordered bool) ([]writeResult, error)
+	// Get returns the pokemon with the given id or ErrNotFound.
+	Get(ctx context.Context, id int64) (pokemon, error)
+	// GetBySlug returns the pokemon whose name has the given slug or ErrNotFound.
+	GetBySlug(ctx context.Context, slug string) (pokemon, error)
+	// List returns the page of pokemons described by q and the number of pokemons matching q.Filter.
+	List(ctx context.Context, q ListQuery) ([]pokemon, int64, error)
+	// Each calls fn with every pokemon matching q.Filter in the order of q.Sort and q.Desc, ignoring the paging
+	// fields of q. It stops at the first error fn returns and returns it.
+	Each(ctx context.Context, q ListQuery, fn func(pokemon) error) error
+	// Search returns up to limit pokemons whose name or genus is similar to query, best matches first.
+	Search(ctx context.Context, query string, limit int) ([]searchResult, error)
+	// Upsert replaces the pokemon with p.ID or inserts it, reporting whether it was created, and sets the
+	// Version and UpdatedAt of p to the stored ones. A pokemon in the trash is replaced and counts as created.
+	// Unless version is 0, the pokemon must be stored, out of the trash, at that version, or ErrVersionMismatch
+	// is returned. It returns ErrDuplicateName if another pokemon has a name with the same slug.
+	Upsert(ctx context.Context, p *pokemon, version int64) (created bool, err error)
+	// Delete moves the pokemon with the given id to the trash, marking it as deleted at the given time,
+	// or returns ErrNotFound. Unless version is 0, the pokemon must be at that version or ErrVersionMismatch
+	// is returned.
+	Delete(ctx context.Context, id int64, version int64, at time.Time) error
+	// DeleteAll moves every pokemon to the trash and returns the ids of those it deleted, in id order.
+	DeleteAll(ctx context.Context, at time.Time) ([]int64, error)
+	// ListDeleted returns the pokemons in the trash, most recently deleted first.
+	ListDeleted(ctx context.Context) ([]pokemon, error)
+	// Restore takes the pokemon with the given id out of the trash and returns it, or returns ErrNotFound.
+	Restore(ctx context.Context, id int64) (pokemon, error)
+	// Purge removes the pokemon with the given id from the trash for good and returns it, or returns ErrNotFound.
+	Purge(ctx context.Context, id int64) (pokemon, error)
+	// PurgeDeleted removes for good the pokemons deleted before the given time and returns how many there were.
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+	// Atomically calls fn with a repository whose changes are all kept when fn returns nil and all discarded
+	// when it returns an error, which Atomically returns. It returns ErrTransactionsUnsupported when the
+	// storage can't discard changes.
+	Atomically(ctx context.Context, fn func(ctx context.Context, repo PokemonRepository) error) error
+}
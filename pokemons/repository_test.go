@@ -0,0 +1,184 @@
+package pokemons
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example.com/pokemon-handbook/config"
+)
+
+// backends returns a repository of every kind that needs no server.
+func backends(t *testing.T) map[string]PokemonRepository {
+	t.Helper()
+	db, err := config.OpenBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	bolt, err := NewBoltRepository(db, "pokemons")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]PokemonRepository{"memory": NewMemoryRepository(), "bolt": bolt}
+}
+
+func TestRepositoryWrites(t *testing.T) {
+	at := time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+	pikachu := func() *pokemon { return &pokemon{ID: 25, Name: "Pikachu", Slug: "pikachu"} }
+
+	tests := []struct {
+		name string
+		// write changes the repository, which holds Pikachu and Bulbasaur at version 1.
+		write func(ctx context.Context, repo PokemonRepository) error
+		err   error
+		// version is the version Pikachu is at afterwards, in or out of the trash.
+		version int64
+	}{
+		{"create a duplicate id", func(ctx context.Context, repo PokemonRepository) error {
+			return repo.Create(ctx, &pokemon{ID: 25, Name: "Raichu", Slug: "raichu"})
+		}, ErrDuplicateID, 1},
+		{"create a duplicate name", func(ctx context.Context, repo PokemonRepository) error {
+			return repo.Create(ctx, &pokemon{ID: 26, Name: "Pikachu", Slug: "pikachu"})
+		}, ErrDuplicateName, 1},
+		{"upsert", func(ctx context.Context, repo PokemonRepository) error {
+			_, err := repo.Upsert(ctx, pikachu(), 0)
+			return err
+		}, nil, 2},
+		{"upsert at the current version", func(ctx context.Context, repo PokemonRepository) error {
+			_, err := repo.Upsert(ctx, pikachu(), 1)
+			return err
+		}, nil, 2},
+		{"upsert at an old version", func(ctx context.Context, repo PokemonRepository) error {
+			_, err := repo.Upsert(ctx, pikachu(), 2)
+			return err
+		}, ErrVersionMismatch, 1},
+		{"upsert with the name of another pokemon", func(ctx context.Context, repo PokemonRepository) error {
+			_, err := repo.Upsert(ctx, &pokemon{ID: 25, Name: "Bulbasaur", Slug: "bulbasaur"}, 0)
+			return err
+		}, ErrDuplicateName, 1},
+		{"delete", func(ctx context.Context, repo PokemonRepository) error {
+			return repo.Delete(ctx, 25, 0, at)
+		}, nil, 2},
+		{"delete at an old version", func(ctx context.Context, repo PokemonRepository) error {
+			return repo.Delete(ctx, 25, 2, at)
+		}, ErrVersionMismatch, 1},
+		{"delete a missing pokemon", func(ctx context.Context, repo PokemonRepository) error {
+			return repo.Delete(ctx, 150, 0, at)
+		}, ErrNotFound, 1},
+		{"delete twice", func(ctx context.Context, repo PokemonRepository) error {
+			if err := repo.Delete(ctx, 25, 0, at); err != nil {
+				return err
+			}
+			return repo.Delete(ctx, 25, 0, at)
+		}, ErrNotFound, 2},
+		{"delete and restore", func(ctx context.Context, repo PokemonRepository) error {
+			if err := repo.Delete(ctx, 25, 0, at); err != nil {
+				return err
+			}
+			_, err := repo.Restore(ctx, 25)
+			return err
+		}, nil, 3},
+		{"restore a pokemon out of the trash", func(ctx context.Context, repo PokemonRepository) error {
+			_, err := repo.Restore(ctx, 25)
+			return err
+		}, ErrNotFound, 1},
+		{"delete all", func(ctx context.Context, repo PokemonRepository) error {
+			ids, err := repo.DeleteAll(ctx, at)
+			if err == nil && (len(ids) != 2 || ids[0] != 1 || ids[1] != 25) {
+				return errors.New("DeleteAll didn't return the ids of both pokemons in order")
+			}
+			return err
+		}, nil, 2},
+		{"upsert a pokemon in the trash", func(ctx context.Context, repo PokemonRepository) error {
+			if err := repo.Delete(ctx, 25, 0, at); err != nil {
+				return err
+			}
+			created, err := repo.Upsert(ctx, pikachu(), 0)
+			if err == nil && !created {
+				return errors.New("replacing a pokemon in the trash doesn't count as a creation")
+			}
+			return err
+		}, nil, 3},
+	}
+	for _, tt := range tests {
+		for name, repo := range backends(t) {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				ctx := context.Background()
+				for _, p := range []*pokemon{pikachu(), {ID: 1, Name: "Bulbasaur", Slug: "bulbasaur"}} {
+					if err := repo.Create(ctx, p); err != nil {
+						t.Fatal(err)
+					}
+					if p.Version != 1 || p.UpdatedAt.IsZero() {
+						t.Fatalf("Create stamped version %d at %v", p.Version, p.UpdatedAt)
+					}
+				}
+
+				if err := tt.write(ctx, repo); !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+				version := pokemonVersion(ctx, t, repo, 25)
+				if version != tt.version {
+					t.Errorf("version = %d, want %d", version, tt.version)
+				}
+			})
+		}
+	}
+}
+
+// pokemonVersion returns the version of the pokemon with the given id, in or out of the trash.
+func pokemonVersion(ctx context.Context, t *testing.T, repo PokemonRepository, id int64) int64 {
+	t.Helper()
+	if p, err := repo.Get(ctx, id); err == nil {
+		return p.Version
+	}
+	trash, err := repo.ListDeleted(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range trash {
+		if p.ID == id {
+			return p.Version
+		}
+	}
+	t.Fatalf("pokemon %d not found", id)
+	return 0
+}
+
+func TestCreateManyVersions(t *testing.T) {
+	tests := []struct {
+		name      string
+		overwrite bool
+		// versions are those of the pokemons passed to CreateMany afterwards.
+		versions []int64
+		created  []bool
+	}{
+		{"create", false, []int64{0, 1}, []bool{false, true}},
+		{"overwrite", true, []int64{2, 1}, []bool{false, true}},
+	}
+	for _, tt := range tests {
+		for name, repo := range backends(t) {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				ctx := context.Background()
+				if err := repo.Create(ctx, &pokemon{ID: 25, Name: "Pikachu", Slug: "pikachu"}); err != nil {
+					t.Fatal(err)
+				}
+				batch := []pokemon{{ID: 25, Name: "Pikachu", Slug: "pikachu"}, {ID: 26, Name: "Raichu", Slug: "raichu"}}
+				results, err := repo.CreateMany(ctx, batch, tt.overwrite, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i, res := range results {
+					if res.Created != tt.created[i] {
+						t.Errorf("pokemon %d: created = %v, want %v", batch[i].ID, res.Created, tt.created[i])
+					}
+					if res.Err == nil && batch[i].Version != tt.versions[i] {
+						t.Errorf("pokemon %d: version = %d, want %d", batch[i].ID, batch[i].Version, tt.versions[i])
+					}
+				}
+			})
+		}
+	}
+}
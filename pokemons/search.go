@@ -0,0 +1,146 @@
+package pokemons
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// minSearchScore is the similarity below which a pokemon is not considered a match.
+	minSearchScore = 0.5
+
+	defaultSearchLimit = 10
+	maxSearchLimit     = 100
+
+	// maxSearchQueryLen is the longest query in runes. The query is compared with every candidate in time
+	// growing with its length, and no name or genus is nearly as long.
+	maxSearchQueryLen = 64
+)
+
+type searchResult struct {
+	Pokemon pokemon `json:"pokemon"`
+	// Score is the similarity between the query and the pokemon, from 0 to 1.
+	Score float64 `json:"score"`
+}
+
+// searchableText returns the texts of p a search query is matched against.
+func searchableText(p pokemon) []string {
+	return []string{p.Name, p.Genus}
+}
+
+// rankPokemons scores candidates against query and returns the best matches first.
+func rankPokemons(query string, candidates []pokemon, limit int) []searchResult {
+	results := []searchResult{}
+	q := normalizeSearchText(query)
+	if q == "" {
+		return results
+	}
+
+	for _, p := range candidates {
+		best := 0.0
+		for _, text := range searchableText(p) {
+			if score := similarity(q, normalizeSearchText(text)); score > best {
+				best = score
+			}
+		}
+		if best >= minSearchScore {
+			results = append(results, searchResult{Pokemon: p, Score: best})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Pokemon.ID < results[j].Pokemon.ID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// normalizeSearchText lowercases s and drops everything but letters and digits,
+// so that "Mr. Mime" and "mr mime" compare equal.
+func normalizeSearchText(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// similarity scores how well the normalized query q matches the normalized text t.
+// Whole and partial matches score highest, otherwise the score falls with the edit distance.
+func similarity(q, t string) float64 {
+	if t == "" {
+		return 0
+	}
+	qr, tr := []rune(q), []rune(t)
+	coverage := float64(len(qr)) / float64(len(tr))
+	if coverage > 1 {
+		coverage = 1
+	}
+
+	switch {
+	case q == t:
+		return 1
+	case strings.HasPrefix(t, q):
+		return 0.9 + 0.09*coverage
+	case strings.Contains(t, q):
+		return 0.8 + 0.09*coverage
+	}
+
+	longest := len(qr)
+	if len(tr) > longest {
+		longest = len(tr)
+	}
+	score := 1 - float64(editDistance(qr, tr))/float64(longest)
+
+	// a misspelt beginning of a long name, e.g. "charz" for "charizard"
+	if len(tr) > len(qr) {
+		prefix := 1 - float64(editDistance(qr, tr[:len(qr)]))/float64(len(qr))
+		if partial := 0.8 * prefix; partial > score {
+			score = partial
+		}
+	}
+	return score
+}
+
+// editDistance returns the optimal string alignment distance between a and b:
+// the number of insertions, deletions, substitutions and transpositions of adjacent runes turning a into b.
+func editDistance(a, b []rune) int {
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d := minInt(rows[i-1][j]+1, minInt(rows[i][j-1]+1, rows[i-1][j-1]+cost))
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d = minInt(d, rows[i-2][j-2]+1)
+			}
+			rows[i][j] = d
+		}
+	}
+	return rows[len(a)][len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,42 @@
+package pokemons
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// slugify turns a pokemon name into the URL-safe form used to look it up,
+// e.g. "Mr. Mime" becomes "mr-mime", "Flabébé" becomes "flabebe" and "Nidoran♀" becomes "nidoran-f".
+func slugify(name string) string {
+	name = strings.NewReplacer("♀", "-f", "♂", "-m", "'", "", "’", "").Replace(name)
+
+	var b strings.Builder
+	dash := false
+	for _, r := range norm.NFD.String(strings.ToLower(name)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// drop the accents split off by NFD
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		default:
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// isNumeric reports whether s consists of digits only, so it would be read as an id rather than a slug.
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,142 @@
+package pokemons
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/pokemon-handbook/audit"
+	"example.com/pokemon-handbook/conditional"
+)
+
+// GetTrash godoc
+// @title        Get Trash
+// @summary      List deleted pokemons
+// @description  Get the pokemons in the trash, most recently deleted first. They keep their id and name, so neither can be reused, until they are restored or purged. Pokemons are purged automatically once they have been in the trash for the configured retention period.
+// @produce      json
+// @success      200 {array} pokemon
+// @router       /trash [get]
+func (h *Handler) GetTrash(c *gin.Context) {
+	pokemons, err := h.repo.ListDeleted(c.Request.Context())
+	if err != nil {
+		respondWithInternalError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, pokemons)
+}
+
+// RestorePokemon godoc
+// @title        Restore Pokemon
+// @summary      Take a pokemon out of the trash
+// @description  Restore the deleted pokemon with the given ID as it was when it was deleted.
+// @produce      json
+// @param        id  path  int  true  "pokemon id"
+// @success      200 {object} pokemon
+// @header       200 {string} ETag "new version of the pokemon"
+// @failure      406 {string} string "must be a number"
+// @failure      404 {string} string "pokemon not found in the trash"
+// @router       /trash/{id}/restore [post]
+func (h *Handler) RestorePokemon(c *gin.Context) {
+	id, ok := trashID(c)
+	if !ok {
+		return
+	}
+	p, err := h.repo.Restore(c.Request.Context(), id)
+	if err != nil {
+		respondWithTrashError(c, err)
+		return
+	}
+	h.recordRevision(c, revisionRestore, id, &p, 0)
+	h.auditLog.Record(c, audit.ActionRestore, resource(id), nil, p)
+
+	conditional.SetHeaders(c, p.Version, p.UpdatedAt)
+	c.IndentedJSON(http.StatusOK, p)
+}
+
+// PurgePokemon godoc
+// @title        Purge Pokemon
+// @summary      Delete a pokemon in the trash for good
+// @description  Remove the deleted pokemon with the given ID from the trash. It can't be restored afterwards, though its revisions are kept and it can still be reverted to one of them.
+// @produce      json
+// @param        id  path  int  true  "pokemon id"
+// @success      200 {string} string "pokemon was purged"
+// @failure      406 {string} string "must be a number"
+// @failure      404 {string} string "pokemon not found in the trash"
+// @router       /trash/{id} [delete]
+func (h *Handler) PurgePokemon(c *gin.Context) {
+	id, ok := trashID(c)
+	if !ok {
+		return
+	}
+	p, err := h.repo.Purge(c.Request.Context(), id)
+	if err != nil {
+		respondWithTrashError(c, err)
+		return
+	}
+	h.auditLog.Record(c, audit.ActionPurge, resource(id), p, nil)
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "pokemon was purged"})
+}
+
+// EmptyTrash godoc
+// @title        Empty Trash
+// @summary      Delete every pokemon in the trash for good
+// @description  Remove all the deleted pokemons from the trash. They can't be restored afterwards.
+// @produce      json
+// @success      200 {string} string "trash was emptied"
+// @router       /trash [delete]
+func (h *Handler) EmptyTrash(c *gin.Context) {
+	purged, err := h.repo.PurgeDeleted(c.Request.Context(), time.Now())
+	if err != nil {
+		respondWithInternalError(c, err)
+		return
+	}
+	if purged > 0 {
+		h.auditLog.Record(c, audit.ActionPurge, "trash", gin.H{"count": purged}, nil)
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "trash was emptied", "purged": purged})
+}
+
+// PurgeExpired hard-deletes, every interval until ctx is done, the pokemons which have been in the trash
+// of repo for longer than retention.
+func PurgeExpired(ctx context.Context, repo PokemonRepository, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purged, err := repo.PurgeDeleted(ctx, time.Now().Add(-retention))
+		if err != nil {
+			fmt.Printf("failed to purge the trash: %v\n", err)
+		} else if purged > 0 {
+			fmt.Printf("purged %d pokemons from the trash\n", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// trashID returns the id path parameter. Pokemons in the trash are only named by id.
+// When it returns false the error response has already been written.
+func trashID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.IndentedJSON(http.StatusNotAcceptable, gin.H{"message": "must be a number"})
+		return 0, false
+	}
+	return id, true
+}
+
+func respondWithTrashError(c *gin.Context, err error) {
+	if errors.Is(err, ErrNotFound) {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "pokemon not found in the trash"})
+		return
+	}
+	respondWithInternalError(c, err)
+}
@@ -0,0 +1,111 @@
+package pokemons
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"example.com/pokemon-handbook/typechart"
+)
+
+const (
+	minBaseStat   = 1
+	maxBaseStat   = 255
+	maxAbilities  = 3
+	maxGeneration = 9
+)
+
+// errNameWithoutLetters is returned by prepare for names which can't be told apart from ids.
+var errNameWithoutLetters = errors.New("pokemon's name must contain letters")
+
+// prepare normalizes and validates a pokemon received from a client and derives its slug.
+func (p *pokemon) prepare() error {
+	p.DeletedAt = nil
+	p.normalize()
+	if err := p.validate(); err != nil {
+		return err
+	}
+
+	p.Slug = slugify(p.Name)
+	if p.Slug == "" || isNumeric(p.Slug) {
+		return errNameWithoutLetters
+	}
+	return nil
+}
+
+// normalize puts the fields of p that are matched case-insensitively into their canonical form.
+func (p *pokemon) normalize() {
+	p.PrimaryType = strings.ToLower(strings.TrimSpace(p.PrimaryType))
+	p.SecondaryType = strings.ToLower(strings.TrimSpace(p.SecondaryType))
+}
+
+// validate checks the fields of p against the rules of the handbook.
+// Zero values mean that a field is unknown and are always accepted.
+func (p pokemon) validate() error {
+	if p.PrimaryType != "" && !typechart.IsType(p.PrimaryType) {
+		return fmt.Errorf("unknown primary type %q", p.PrimaryType)
+	}
+	if p.SecondaryType != "" {
+		if !typechart.IsType(p.SecondaryType) {
+			return fmt.Errorf("unknown secondary type %q", p.SecondaryType)
+		}
+		if p.PrimaryType == "" {
+			return errors.New("a secondary type requires a primary type")
+		}
+		if p.SecondaryType == p.PrimaryType {
+			return errors.New("secondary type must differ from the primary type")
+		}
+	}
+
+	if p.BaseStats != (baseStats{}) {
+		stats := []struct {
+			name  string
+			value int
+		}{
+			{"hp", p.BaseStats.HP},
+			{"attack", p.BaseStats.Attack},
+			{"defense", p.BaseStats.Defense},
+			{"special_attack", p.BaseStats.SpecialAttack},
+			{"special_defense", p.BaseStats.SpecialDefense},
+			{"speed", p.BaseStats.Speed},
+		}
+		for _, stat := range stats {
+			if stat.value < minBaseStat || stat.value > maxBaseStat {
+				return fmt.Errorf("base stat %s must be from %d to %d", stat.name, minBaseStat, maxBaseStat)
+			}
+		}
+	}
+
+	if len(p.Abilities) > maxAbilities {
+		return fmt.Errorf("a pokemon can't have more than %d abilities", maxAbilities)
+	}
+	hidden := 0
+	seen := make(map[string]bool)
+	for _, a := range p.Abilities {
+		name := strings.ToLower(strings.TrimSpace(a.Name))
+		if name == "" {
+			return errors.New("ability name must not be empty")
+		}
+		if seen[name] {
+			return fmt.Errorf("ability %q is listed twice", a.Name)
+		}
+		seen[name] = true
+		if a.Hidden {
+			hidden++
+		}
+	}
+	if hidden > 1 {
+		return errors.New("a pokemon can't have more than one hidden ability")
+	}
+
+	if p.Height < 0 {
+		return errors.New("height must not be negative")
+	}
+	if p.Weight < 0 {
+		return errors.New("weight must not be negative")
+	}
+	if p.Generation < 0 || p.Generation > maxGeneration {
+		return fmt.Errorf("generation must be from 1 to %d", maxGeneration)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Package ratelimit limits how often each client can call each route, with one token bucket per client and route.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/pokemon-handbook/auth"
+)
+
+// Quota is the size and refill rate of a token bucket.
+type Quota struct {
+	// Rate is how many tokens are added per second.
+	Rate float64
+	// Burst is the capacity of the bucket, i.e. how many requests can be made at once.
+	Burst int
+}
+
+// Result tells what Store.Take did.
+type Result struct {
+	Allowed bool
+	// Remaining is how many tokens are left in the bucket.
+	Remaining int
+	// RetryAfter is how long until the next token, when the request was not allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store keeps the token buckets. It is an interface so that several instances of the server
+// can share their buckets through a store such as Redis.
+type Store interface {
+	// Take removes a token from the bucket of key, refilled according to q, if there is one.
+	Take(ctx context.Context, key string, q Quota, now time.Time) (Result, error)
+}
+
+// Limiter applies quotas to routes.
+type Limiter struct {
+	store Store
+	// quotas are keyed by "METHOD /route/:param".
+	quotas map[string]Quota
+	// fallback applies to the routes without a quota. Routes are not limited when it is nil.
+	fallback *Quota
+	// now is time.Now, replaced by tests.
+	now func() time.Time
+}
+
+// NewLimiter returns a Limiter keeping buckets in store. quotas are keyed by the method and path of a route
+// as they are registered, like "GET /pokemons/:id", and fallback, if not nil, applies to the other routes.
+func NewLimiter(store Store, quotas map[string]Quota, fallback *Quota) *Limiter {
+	return &Limiter{store: store, quotas: quotas, fallback: fallback, now: time.Now}
+}
+
+// Middleware returns a handler limiting requests per route and per client. Clients are told apart by
+// the authenticated login, so it has to come after auth.Middleware on protected routes, or by the client IP.
+// Responses carry the X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers,
+// and refused requests get 429 with Retry-After.
+func (l *Limiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		q, ok := l.quotas[route]
+		if !ok {
+			if l.fallback == nil {
+				c.Next()
+				return
+			}
+			q = *l.fallback
+		}
+
+		client := "ip:" + c.ClientIP()
+		if p, ok := auth.CurrentUser(c); ok {
+			client = "user:" + p.Login
+		}
+		res, err := l.store.Take(c.Request.Context(), route+" "+client, q, l.now())
+		if err != nil {
+			// a broken store must not take the API down with it
+			fmt.Println(err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(q.Burst))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"message": "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// seconds rounds d up to whole seconds.
+func seconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
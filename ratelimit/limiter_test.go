@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMiddleware(t *testing.T) {
+	// a token every 30 seconds, 2 at once
+	quota := Quota{Rate: 1.0 / 30, Burst: 2}
+	type request struct {
+		// at is the time of the request since the first one.
+		at     time.Duration
+		path   string
+		ip     string
+		status int
+		// remaining and retryAfter are the expected X-RateLimit-Remaining and Retry-After headers.
+		remaining  string
+		retryAfter string
+	}
+	tests := []struct {
+		name     string
+		fallback *Quota
+		requests []request
+	}{
+		{"limited", nil, []request{
+			{0, "/limited", "10.0.0.1", http.StatusOK, "1", ""},
+			{0, "/limited", "10.0.0.1", http.StatusOK, "0", ""},
+			{0, "/limited", "10.0.0.1", http.StatusTooManyRequests, "0", "30"},
+			{20 * time.Second, "/limited", "10.0.0.1", http.StatusTooManyRequests, "0", "10"},
+			{30 * time.Second, "/limited", "10.0.0.1", http.StatusOK, "0", ""},
+		}},
+		{"retry after rounded up", nil, []request{
+			{0, "/limited", "10.0.0.1", http.StatusOK, "1", ""},
+			{0, "/limited", "10.0.0.1", http.StatusOK, "0", ""},
+			{29500 * time.Millisecond, "/limited", "10.0.0.1", http.StatusTooManyRequests, "0", "1"},
+		}},
+		{"clients have their own bucket", nil, []request{
+			{0, "/limited", "10.0.0.1", http.StatusOK, "1", ""},
+			{0, "/limited", "10.0.0.1", http.StatusOK, "0", ""},
+			{0, "/limited", "10.0.0.2", http.StatusOK, "1", ""},
+			{0, "/limited", "10.0.0.1", http.StatusTooManyRequests, "0", "30"},
+		}},
+		{"routes have their own bucket", &quota, []request{
+			{0, "/limited", "10.0.0.1", http.StatusOK, "1", ""},
+			{0, "/limited", "10.0.0.1", http.StatusOK, "0", ""},
+			{0, "/other", "10.0.0.1", http.StatusOK, "1", ""},
+		}},
+		{"no fallback", nil, []request{
+			{0, "/other", "10.0.0.1", http.StatusOK, "", ""},
+			{0, "/other", "10.0.0.1", http.StatusOK, "", ""},
+			{0, "/other", "10.0.0.1", http.StatusOK, "", ""},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			start := time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+			var now time.Time
+			l := NewLimiter(NewMemoryStore(), map[string]Quota{"GET /limited": quota}, tt.fallback)
+			l.now = func() time.Time { return now }
+
+			r := gin.New()
+			r.Use(l.Middleware())
+			ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+			r.GET("/limited", ok)
+			r.GET("/other", ok)
+
+			for i, req := range tt.requests {
+				now = start.Add(req.at)
+				httpReq := httptest.NewRequest(http.MethodGet, req.path, nil)
+				httpReq.RemoteAddr = req.ip + ":1234"
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httpReq)
+
+				if w.Code != req.status {
+					t.Errorf("request %d: status = %d, want %d", i, w.Code, req.status)
+				}
+				if got := w.Header().Get("X-RateLimit-Remaining"); got != req.remaining {
+					t.Errorf("request %d: X-RateLimit-Remaining = %q, want %q", i, got, req.remaining)
+				}
+				if got := w.Header().Get("Retry-After"); got != req.retryAfter {
+					t.Errorf("request %d: Retry-After = %q, want %q", i, got, req.retryAfter)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store drops the buckets which are full again.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	quota  Quota
+}
+
+// refill adds the tokens earned since the last request.
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(b.quota.Burst), b.tokens+elapsed*b.quota.Rate)
+	}
+	b.last = now
+}
+
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewMemoryStore returns a Store keeping buckets in process memory.
+// It is safe for concurrent use and only limits the requests made to this process.
+func NewMemoryStore() Store {
+	return &memoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *memoryStore) Take(ctx context.Context, key string, q Quota, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > sweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(q.Burst), last: now}
+		s.buckets[key] = b
+	}
+	b.quota = q
+	b.refill(now)
+
+	res := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = durationFor(1-b.tokens, q.Rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = durationFor(float64(q.Burst)-b.tokens, q.Rate)
+	return res, nil
+}
+
+// sweep drops the buckets that are full again, they are the same as no bucket. The caller must hold s.mu.
+func (s *memoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.quota.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// durationFor returns how long it takes to earn tokens at rate per second. rate must be positive.
+func durationFor(tokens, rate float64) time.Duration {
+	return time.Duration(tokens / rate * float64(time.Second))
+}
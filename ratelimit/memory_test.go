@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	// a token every 2 seconds, 3 at once
+	quota := Quota{Rate: 0.5, Burst: 3}
+	type take struct {
+		// at is the time of the request since the first one.
+		at   time.Duration
+		key  string
+		want Result
+	}
+	tests := []struct {
+		name  string
+		takes []take
+	}{
+		{"burst", []take{
+			{0, "a", Result{Allowed: true, Remaining: 2, Reset: 2 * time.Second}},
+			{0, "a", Result{Allowed: true, Remaining: 1, Reset: 4 * time.Second}},
+			{0, "a", Result{Allowed: true, Remaining: 0, Reset: 6 * time.Second}},
+			{0, "a", Result{Allowed: false, Remaining: 0, RetryAfter: 2 * time.Second, Reset: 6 * time.Second}},
+		}},
+		{"retry after a partial refill", []take{
+			{0, "a", Result{Allowed: true, Remaining: 2, Reset: 2 * time.Second}},
+			{0, "a", Result{Allowed: true, Remaining: 1, Reset: 4 * time.Second}},
+			{0, "a", Result{Allowed: true, Remaining: 0, Reset: 6 * time.Second}},
+			{500 * time.Millisecond, "a", Result{Allowed: false, Remaining: 0, RetryAfter: 1500 * time.Millisecond, Reset: 5500 * time.Millisecond}},
+		}},
+		{"refill", []take{
+			{0, "a", Result{Allowed: true, Remaining: 2, Reset: 2 * time.Second}},
+			{0, "a", Result{Allowed: true, Remaining: 1, Reset: 4 * time.Second}},
+			{0, "a", Result{Allowed: true, Remaining: 0, Reset: 6 * time.Second}},
+			{2 * time.Second, "a", Result{Allowed: true, Remaining: 0, Reset: 6 * time.Second}},
+			{2 * time.Second, "a", Result{Allowed: false, Remaining: 0, RetryAfter: 2 * time.Second, Reset: 6 * time.Second}},
+		}},
+		{"refill up to the burst", []take{
+			{0, "a", Result{Allowed: true, Remaining: 2, Reset: 2 * time.Second}},
+			{time.Hour, "a", Result{Allowed: true, Remaining: 2, Reset: 2 * time.Second}},
+		}},
+		{"keys have their own bucket", []take{
+			{0, "a", Result{Allowed: true, Remaining: 2, Reset: 2 * time.Second}},
+			{0, "a", Result{Allowed: true, Remaining: 1, Reset: 4 * time.Second}},
+			{0, "a", Result{Allowed: true, Remaining: 0, Reset: 6 * time.Second}},
+			{0, "b", Result{Allowed: true, Remaining: 2, Reset: 2 * time.Second}},
+			{0, "a", Result{Allowed: false, Remaining: 0, RetryAfter: 2 * time.Second, Reset: 6 * time.Second}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryStore()
+			start := time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+			for i, take := range tt.takes {
+				got, err := s.Take(context.Background(), take.key, quota, start.Add(take.at))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != take.want {
+					t.Errorf("take %d of %s at %v = %+v, want %+v", i, take.key, take.at, got, take.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	s := NewMemoryStore().(*memoryStore)
+	start := time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+	quota := Quota{Rate: 1, Burst: 10}
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := s.Take(context.Background(), key, quota, start); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the buckets of a, b and c are full again by then
+	if _, err := s.Take(context.Background(), "d", quota, start.Add(2*sweepInterval)); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.buckets) != 1 {
+		t.Errorf("%d buckets are kept, want only the one of d", len(s.buckets))
+	}
+}
@@ -0,0 +1,108 @@
+package tokens
+
+import (
+	"context"
+	"time"
+
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type boltRepository struct {
+	db      *bbolt.DB
+	refresh []byte
+	revoked []byte
+}
+
+// NewBoltRepository returns a TokenRepository keeping refresh tokens and revoked access tokens
+// in the given buckets of a bolt database file. Documents are encoded as BSON and keyed by hash or id.
+func NewBoltRepository(db *bbolt.DB, refreshBucket, revokedBucket string) (TokenRepository, error) {
+	r := &boltRepository{db: db, refresh: []byte(refreshBucket), revoked: []byte(revokedBucket)}
+	err := db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(r.refresh); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(r.revoked)
+		return err
+	})
+	return r, err
+}
+
+// purge drops the expired entries of bucket b. Both token types keep their expiry in "expires_at".
+func purge(b *bbolt.Bucket) error {
+	now := time.Now()
+	c := b.Cursor()
+	for k, v := c.First(); k != nil; {
+		var entry struct {
+			ExpiresAt time.Time `bson:"expires_at"`
+		}
+		if err := bson.Unmarshal(v, &entry); err != nil {
+			return err
+		}
+		if now.After(entry.ExpiresAt) {
+			key := append([]byte{}, k...)
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			// Next would skip an entry after Delete, seeking lands on the one following key
+			k, v = c.Seek(key)
+			continue
+		}
+		k, v = c.Next()
+	}
+	return nil
+}
+
+func put(b *bbolt.Bucket, key string, doc interface{}) error {
+	if err := purge(b); err != nil {
+		return err
+	}
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(key), data)
+}
+
+func (r *boltRepository) SaveRefresh(ctx context.Context, t refreshToken) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		return put(tx.Bucket(r.refresh), t.Hash, t)
+	})
+}
+
+func (r *boltRepository) TakeRefresh(ctx context.Context, hash string) (refreshToken, error) {
+	result := refreshToken{}
+	err := r.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.refresh)
+		data := b.Get([]byte(hash))
+		if data == nil {
+			return ErrNotFound
+		}
+		if err := bson.Unmarshal(data, &result); err != nil {
+			return err
+		}
+		if err := b.Delete([]byte(hash)); err != nil {
+			return err
+		}
+		if time.Now().After(result.ExpiresAt) {
+			return ErrNotFound
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (r *boltRepository) Revoke(ctx context.Context, t revokedToken) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		return put(tx.Bucket(r.revoked), t.ID, t)
+	})
+}
+
+func (r *boltRepository) IsRevoked(ctx context.Context, id string) (bool, error) {
+	found := false
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		found = tx.Bucket(r.revoked).Get([]byte(id)) != nil
+		return nil
+	})
+	return found, err
+}
@@ -0,0 +1,151 @@
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/pokemon-handbook/auth"
+)
+
+type credentials struct {
+	Login    string `json:"login" example:"admin"`
+	Password string `json:"password" example:"admin"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Handler serves the /auth routes.
+type Handler struct {
+	service *Service
+	authn   auth.Authenticator
+	users   auth.UserLookup
+}
+
+// NewHandler returns a Handler issuing tokens with service to the users accepted by authn.
+func NewHandler(service *Service, authn auth.Authenticator, users auth.UserLookup) *Handler {
+	return &Handler{service: service, authn: authn, users: users}
+}
+
+// Login godoc
+// @title        Login
+// @summary      Exchange a login and password for tokens
+// @description  Check the login and password against the users collection and return a short-lived access token to send as "Authorization: Bearer <token>" and a refresh token for POST /auth/refresh.
+// @accept       json
+// @produce      json
+// @param        credentials  body  credentials  true  "login and password of the user"
+// @success      200 {object} tokenPair
+// @failure      400 {string} string "object can't be parsed into JSON"
+// @failure      401 {string} string "invalid login or password"
+// @failure      429 {string} string "too many failed attempts"
+// @router       /auth/login [post]
+func (h *Handler) Login(c *gin.Context) {
+	var creds credentials
+	if err := c.BindJSON(&creds); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "object can't be parsed into JSON"})
+		return
+	}
+
+	ctx := auth.WithClientIP(c.Request.Context(), c.ClientIP())
+	p, err := h.authn.Authenticate(ctx, creds.Login, creds.Password)
+	var locked *auth.LockedOutError
+	if errors.As(err, &locked) {
+		auth.SetRetryAfter(c, err)
+		c.IndentedJSON(http.StatusTooManyRequests, gin.H{"message": err.Error()})
+		return
+	}
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		auth.SetRetryAfter(c, err)
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"message": "invalid login or password"})
+		return
+	}
+	if err != nil {
+		respondWithInternalError(c, err)
+		return
+	}
+
+	pair, err := h.service.issue(c.Request.Context(), p)
+	if err != nil {
+		respondWithInternalError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, pair)
+}
+
+// Refresh godoc
+// @title        Refresh
+// @summary      Exchange a refresh token for new tokens
+// @description  Return a new access token and refresh token. The refresh token sent can't be used again.
+// @accept       json
+// @produce      json
+// @param        token  body  refreshRequest  true  "the refresh token"
+// @success      200 {object} tokenPair
+// @failure      400 {string} string "object can't be parsed into JSON"
+// @failure      401 {string} string "invalid or expired token"
+// @router       /auth/refresh [post]
+func (h *Handler) Refresh(c *gin.Context) {
+	var req refreshRequest
+	if err := c.BindJSON(&req); err != nil || req.RefreshToken == "" {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "object can't be parsed into JSON"})
+		return
+	}
+
+	pair, err := h.service.refresh(c.Request.Context(), h.users, req.RefreshToken)
+	if errors.Is(err, auth.ErrInvalidToken) {
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		respondWithInternalError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, pair)
+}
+
+// Logout godoc
+// @title        Logout
+// @summary      Revoke tokens
+// @description  Revoke the access token sent as "Authorization: Bearer <token>" and the refresh token in the body. Either of them may be left out.
+// @accept       json
+// @produce      json
+// @param        token  body  refreshRequest  false  "the refresh token"
+// @success      200 {string} string "logged out"
+// @failure      400 {string} string "no token to revoke"
+// @router       /auth/logout [post]
+func (h *Handler) Logout(c *gin.Context) {
+	var req refreshRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&req); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "object can't be parsed into JSON"})
+			return
+		}
+	}
+	access, hasAccess := auth.BearerToken(c.Request)
+	if !hasAccess && req.RefreshToken == "" {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "no token to revoke"})
+		return
+	}
+
+	if hasAccess {
+		if err := h.service.revoke(c.Request.Context(), access); err != nil {
+			respondWithInternalError(c, err)
+			return
+		}
+	}
+	if req.RefreshToken != "" {
+		if err := h.service.discardRefresh(c.Request.Context(), req.RefreshToken); err != nil {
+			respondWithInternalError(c, err)
+			return
+		}
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+func respondWithInternalError(c *gin.Context, err error) {
+	fmt.Println(err)
+	c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+}
@@ -0,0 +1,78 @@
+package tokens
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryRepository struct {
+	mu      sync.Mutex
+	refresh map[string]refreshToken
+	revoked map[string]revokedToken
+}
+
+// NewMemoryRepository returns a TokenRepository that keeps tokens in process memory.
+// It is safe for concurrent use and loses its data when the process exits.
+func NewMemoryRepository() TokenRepository {
+	return &memoryRepository{
+		refresh: make(map[string]refreshToken),
+		revoked: make(map[string]revokedToken),
+	}
+}
+
+// purge drops the expired entries. The caller must hold r.mu.
+func (r *memoryRepository) purge() {
+	now := time.Now()
+	for hash, t := range r.refresh {
+		if now.After(t.ExpiresAt) {
+			delete(r.refresh, hash)
+		}
+	}
+	for id, t := range r.revoked {
+		if now.After(t.ExpiresAt) {
+			delete(r.revoked, id)
+		}
+	}
+}
+
+func (r *memoryRepository) SaveRefresh(ctx context.Context, t refreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.purge()
+	r.refresh[t.Hash] = t
+	return nil
+}
+
+func (r *memoryRepository) TakeRefresh(ctx context.Context, hash string) (refreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.refresh[hash]
+	if !ok {
+		return refreshToken{}, ErrNotFound
+	}
+	delete(r.refresh, hash)
+	if time.Now().After(t.ExpiresAt) {
+		return refreshToken{}, ErrNotFound
+	}
+	return t, nil
+}
+
+func (r *memoryRepository) Revoke(ctx context.Context, t revokedToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.purge()
+	r.revoked[t.ID] = t
+	return nil
+}
+
+func (r *memoryRepository) IsRevoked(ctx context.Context, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.revoked[id]
+	return ok, nil
+}
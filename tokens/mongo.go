@@ -0,0 +1,64 @@
+package tokens
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRepository struct {
+	refresh *mongo.Collection
+	revoked *mongo.Collection
+}
+
+// NewMongoRepository returns a TokenRepository keeping refresh tokens and revoked access tokens
+// in the given MongoDB collections. It creates TTL indexes so that MongoDB removes expired entries.
+func NewMongoRepository(refresh, revoked *mongo.Collection) (TokenRepository, error) {
+	for _, collection := range []*mongo.Collection{refresh, revoked} {
+		_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &mongoRepository{refresh: refresh, revoked: revoked}, nil
+}
+
+func (r *mongoRepository) SaveRefresh(ctx context.Context, t refreshToken) error {
+	_, err := r.refresh.InsertOne(ctx, t)
+	return err
+}
+
+func (r *mongoRepository) TakeRefresh(ctx context.Context, hash string) (refreshToken, error) {
+	result := refreshToken{}
+
+	// the TTL monitor runs once a minute, so expired tokens may still be there
+	filter := bson.D{
+		{Key: "_id", Value: hash},
+		{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: time.Now()}}},
+	}
+	err := r.refresh.FindOneAndDelete(ctx, filter).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		return result, ErrNotFound
+	}
+	return result, err
+}
+
+func (r *mongoRepository) Revoke(ctx context.Context, t revokedToken) error {
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.revoked.ReplaceOne(ctx, bson.D{{Key: "_id", Value: t.ID}}, t, opts)
+	return err
+}
+
+func (r *mongoRepository) IsRevoked(ctx context.Context, id string) (bool, error) {
+	err := r.revoked.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	return err == nil, err
+}
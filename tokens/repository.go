@@ -0,0 +1,39 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by a TokenRepository when there is no unexpired refresh token with the requested hash.
+var ErrNotFound = errors.New("refresh token not found")
+
+// refreshToken is stored for every refresh token handed out. The token itself is only known to the client.
+type refreshToken struct {
+	// Hash is the SHA-256 of the token, hex encoded.
+	Hash      string    `bson:"_id"`
+	Login     string    `bson:"login"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// revokedToken is an access token that was logged out before it expired.
+type revokedToken struct {
+	// ID is the "jti" claim of the token.
+	ID        string    `bson:"_id"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// TokenRepository stores the refresh tokens and the revocation list of access tokens.
+// Entries are dropped by the repository once they have expired.
+type TokenRepository interface {
+	// SaveRefresh stores a new refresh token.
+	SaveRefresh(ctx context.Context, t refreshToken) error
+	// TakeRefresh removes and returns the refresh token with the given hash or returns ErrNotFound.
+	// A refresh token can be taken only once, even by concurrent callers.
+	TakeRefresh(ctx context.Context, hash string) (refreshToken, error)
+	// Revoke adds an access token to the revocation list.
+	Revoke(ctx context.Context, t revokedToken) error
+	// IsRevoked reports whether the access token with the given id is on the revocation list.
+	IsRevoked(ctx context.Context, id string) (bool, error)
+}
@@ -0,0 +1,188 @@
+// Package tokens issues the JWT access tokens and refresh tokens handed out by the /auth routes.
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"example.com/pokemon-handbook/auth"
+	"example.com/pokemon-handbook/config"
+)
+
+// claims are the claims of an access token. The login is in the "sub" claim.
+type claims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role"`
+}
+
+// Service issues and verifies tokens. It implements auth.TokenVerifier.
+type Service struct {
+	repo TokenRepository
+	// signing is the key signing new tokens, keys holds every key accepted by id.
+	signing    config.JWTKey
+	keys       map[string][]byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// tokenPair is returned by the login and refresh endpoints.
+type tokenPair struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int64  `json:"expires_in" example:"900"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// NewService returns a Service signing access tokens with the first of keys and accepting all of them.
+// Without keys it generates a random one, so the tokens it issues don't survive a restart.
+func NewService(repo TokenRepository, keys []config.JWTKey, accessTTL, refreshTTL time.Duration) *Service {
+	if len(keys) == 0 {
+		fmt.Println("No JWTKeys are configured, access tokens are signed with a random key until the next restart.")
+		keys = []config.JWTKey{{ID: "random", Secret: randomString()}}
+	}
+
+	s := &Service{
+		repo:       repo,
+		signing:    keys[0],
+		keys:       make(map[string][]byte, len(keys)),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+	for _, key := range keys {
+		s.keys[key.ID] = []byte(key.Secret)
+	}
+	return s
+}
+
+// randomString returns 256 random bits, base64url encoded.
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issue returns a new access token and refresh token for p.
+func (s *Service) issue(ctx context.Context, p auth.Principal) (tokenPair, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        randomString(),
+			Subject:   p.Login,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTTL)),
+		},
+		Role: p.Role,
+	})
+	token.Header["kid"] = s.signing.ID
+	access, err := token.SignedString([]byte(s.signing.Secret))
+	if err != nil {
+		return tokenPair{}, err
+	}
+
+	refresh := randomString()
+	err = s.repo.SaveRefresh(ctx, refreshToken{
+		Hash:      hashRefreshToken(refresh),
+		Login:     p.Login,
+		ExpiresAt: now.Add(s.refreshTTL),
+	})
+	if err != nil {
+		return tokenPair{}, err
+	}
+
+	return tokenPair{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.accessTTL / time.Second),
+		RefreshToken: refresh,
+	}, nil
+}
+
+// parse checks the signature and expiry of an access token and returns its claims or auth.ErrInvalidToken.
+func (s *Service) parse(token string) (claims, error) {
+	result := claims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	_, err := parser.ParseWithClaims(token, &result, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := s.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		return key, nil
+	})
+	if err != nil || result.ID == "" || result.ExpiresAt == nil {
+		return claims{}, auth.ErrInvalidToken
+	}
+	return result, nil
+}
+
+// VerifyToken implements auth.TokenVerifier. The role comes from the token, so a changed role
+// applies once the client refreshes its tokens.
+func (s *Service) VerifyToken(ctx context.Context, token string) (auth.Principal, error) {
+	c, err := s.parse(token)
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	revoked, err := s.repo.IsRevoked(ctx, c.ID)
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	if revoked {
+		return auth.Principal{}, auth.ErrInvalidToken
+	}
+	return auth.Principal{Login: c.Subject, Role: c.Role}, nil
+}
+
+// refresh exchanges a refresh token for a new pair of tokens. The old refresh token can't be used again.
+func (s *Service) refresh(ctx context.Context, users auth.UserLookup, token string) (tokenPair, error) {
+	stored, err := s.repo.TakeRefresh(ctx, hashRefreshToken(token))
+	if errors.Is(err, ErrNotFound) {
+		return tokenPair{}, auth.ErrInvalidToken
+	}
+	if err != nil {
+		return tokenPair{}, err
+	}
+
+	// the user may have been deleted or given another role since the login
+	p, err := users.LookupUser(ctx, stored.Login)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		return tokenPair{}, auth.ErrInvalidToken
+	}
+	if err != nil {
+		return tokenPair{}, err
+	}
+	return s.issue(ctx, p)
+}
+
+// revoke puts an access token on the revocation list until it expires.
+// Tokens which don't verify are ignored since they are refused anyway.
+func (s *Service) revoke(ctx context.Context, token string) error {
+	c, err := s.parse(token)
+	if err != nil {
+		return nil
+	}
+	return s.repo.Revoke(ctx, revokedToken{ID: c.ID, ExpiresAt: c.ExpiresAt.Time})
+}
+
+// discardRefresh deletes a refresh token, whether or not it is still valid.
+func (s *Service) discardRefresh(ctx context.Context, token string) error {
+	_, err := s.repo.TakeRefresh(ctx, hashRefreshToken(token))
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
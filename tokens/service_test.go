@@ -0,0 +1,174 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"example.com/pokemon-handbook/auth"
+	"example.com/pokemon-handbook/config"
+)
+
+var (
+	currentKey = config.JWTKey{ID: "2022-07", Secret: "a secret of the current key, 32 bytes at least"}
+	retiredKey = config.JWTKey{ID: "2022-01", Secret: "a secret of the retired key, 32 bytes at least"}
+)
+
+// roles is an auth.UserLookup of the users with the given roles, by login.
+type roles map[string]string
+
+func (r roles) LookupUser(ctx context.Context, login string) (auth.Principal, error) {
+	role, ok := r[login]
+	if !ok {
+		return auth.Principal{}, auth.ErrInvalidCredentials
+	}
+	return auth.Principal{Login: login, Role: role}, nil
+}
+
+// signWithKid returns an access token of ash signed with key but naming kid in its header.
+func signWithKid(t *testing.T, key config.JWTKey, kid string) string {
+	t.Helper()
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        randomString(),
+			Subject:   "ash",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+		Role: auth.Admin,
+	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString([]byte(key.Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestVerifyToken(t *testing.T) {
+	tests := []struct {
+		name string
+		// token returns an access token of ash, given the repository of the verifying service.
+		token func(t *testing.T, repo TokenRepository) string
+		err   error
+	}{
+		{"current key", func(t *testing.T, repo TokenRepository) string {
+			return issueAccess(t, NewService(repo, []config.JWTKey{currentKey}, time.Minute, time.Hour))
+		}, nil},
+		{"retired key", func(t *testing.T, repo TokenRepository) string {
+			return issueAccess(t, NewService(repo, []config.JWTKey{retiredKey}, time.Minute, time.Hour))
+		}, auth.ErrInvalidToken},
+		{"unknown kid", func(t *testing.T, repo TokenRepository) string {
+			return signWithKid(t, currentKey, "unknown")
+		}, auth.ErrInvalidToken},
+		{"no kid", func(t *testing.T, repo TokenRepository) string {
+			return signWithKid(t, currentKey, "")
+		}, auth.ErrInvalidToken},
+		{"kid of another key", func(t *testing.T, repo TokenRepository) string {
+			return signWithKid(t, retiredKey, currentKey.ID)
+		}, auth.ErrInvalidToken},
+		{"expired", func(t *testing.T, repo TokenRepository) string {
+			return issueAccess(t, NewService(repo, []config.JWTKey{currentKey}, -time.Minute, time.Hour))
+		}, auth.ErrInvalidToken},
+		{"revoked", func(t *testing.T, repo TokenRepository) string {
+			s := NewService(repo, []config.JWTKey{currentKey}, time.Minute, time.Hour)
+			token := issueAccess(t, s)
+			if err := s.revoke(context.Background(), token); err != nil {
+				t.Fatal(err)
+			}
+			return token
+		}, auth.ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewMemoryRepository()
+			s := NewService(repo, []config.JWTKey{currentKey}, time.Minute, time.Hour)
+
+			p, err := s.VerifyToken(context.Background(), tt.token(t, repo))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if err == nil && (p.Login != "ash" || p.Role != auth.Admin) {
+				t.Errorf("principal = %+v, want ash as admin", p)
+			}
+		})
+	}
+}
+
+// issueAccess returns an access token of ash as admin issued by s.
+func issueAccess(t *testing.T, s *Service) string {
+	t.Helper()
+	pair, err := s.issue(context.Background(), auth.Principal{Login: "ash", Role: auth.Admin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pair.AccessToken
+}
+
+func TestRefresh(t *testing.T) {
+	tests := []struct {
+		name       string
+		refreshTTL time.Duration
+		// before changes the users and tokens between the login of ash as editor and the refresh.
+		before func(t *testing.T, s *Service, users roles, refresh string)
+		err    error
+		role   string
+	}{
+		{"refresh", time.Hour, nil, nil, auth.Editor},
+		{"changed role", time.Hour, func(t *testing.T, s *Service, users roles, refresh string) {
+			users["ash"] = auth.Viewer
+		}, nil, auth.Viewer},
+		{"deleted user", time.Hour, func(t *testing.T, s *Service, users roles, refresh string) {
+			delete(users, "ash")
+		}, auth.ErrInvalidToken, ""},
+		{"already used", time.Hour, func(t *testing.T, s *Service, users roles, refresh string) {
+			if _, err := s.refresh(context.Background(), users, refresh); err != nil {
+				t.Fatal(err)
+			}
+		}, auth.ErrInvalidToken, ""},
+		{"revoked by a logout", time.Hour, func(t *testing.T, s *Service, users roles, refresh string) {
+			if err := s.discardRefresh(context.Background(), refresh); err != nil {
+				t.Fatal(err)
+			}
+		}, auth.ErrInvalidToken, ""},
+		{"expired", -time.Second, nil, auth.ErrInvalidToken, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := NewService(NewMemoryRepository(), []config.JWTKey{currentKey}, time.Minute, tt.refreshTTL)
+			users := roles{"ash": auth.Editor}
+			login, err := s.issue(ctx, auth.Principal{Login: "ash", Role: auth.Editor})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.before != nil {
+				tt.before(t, s, users, login.RefreshToken)
+			}
+
+			pair, err := s.refresh(ctx, users, login.RefreshToken)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			p, err := s.VerifyToken(ctx, pair.AccessToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Role != tt.role {
+				t.Errorf("role = %s, want %s", p.Role, tt.role)
+			}
+			if pair.RefreshToken == login.RefreshToken {
+				t.Error("the refresh token was handed out again")
+			}
+		})
+	}
+}
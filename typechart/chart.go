@@ -0,0 +1,114 @@
+// Package typechart holds the effectiveness of the 18 elemental types against each other.
+package typechart
+
+// Types lists the elemental types in the order of the rows and columns of the chart.
+var Types = []string{
+	"normal", "fire", "water", "electric", "grass", "ice",
+	"fighting", "poison", "ground", "flying", "psychic", "bug",
+	"rock", "ghost", "dragon", "dark", "steel", "fairy",
+}
+
+// h marks attacks that are not very effective.
+const h = 0.5
+
+// chart[a][d] is the damage multiplier of an attack of type Types[a] against a pokemon of type Types[d].
+var chart = [18][18]float64{
+	//        Nor Fir Wat Ele Gra Ice Fig Poi Gro Fly Psy Bug Roc Gho Dra Dar Ste Fai
+	/* Nor */ {1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, h, 0, 1, 1, h, 1},
+	/* Fir */ {1, h, h, 1, 2, 2, 1, 1, 1, 1, 1, 2, h, 1, h, 1, 2, 1},
+	/* Wat */ {1, 2, h, 1, h, 1, 1, 1, 2, 1, 1, 1, 2, 1, h, 1, 1, 1},
+	/* Ele */ {1, 1, 2, h, h, 1, 1, 1, 0, 2, 1, 1, 1, 1, h, 1, 1, 1},
+	/* Gra */ {1, h, 2, 1, h, 1, 1, h, 2, h, 1, h, 2, 1, h, 1, h, 1},
+	/* Ice */ {1, h, h, 1, 2, h, 1, 1, 2, 2, 1, 1, 1, 1, 2, 1, h, 1},
+	/* Fig */ {2, 1, 1, 1, 1, 2, 1, h, 1, h, h, h, 2, 0, 1, 2, 2, h},
+	/* Poi */ {1, 1, 1, 1, 2, 1, 1, h, h, 1, 1, 1, h, h, 1, 1, 0, 2},
+	/* Gro */ {1, 2, 1, 2, h, 1, 1, 2, 1, 0, 1, h, 2, 1, 1, 1, 2, 1},
+	/* Fly */ {1, 1, 1, h, 2, 1, 2, 1, 1, 1, 1, 2, h, 1, 1, 1, h, 1},
+	/* Psy */ {1, 1, 1, 1, 1, 1, 2, 2, 1, 1, h, 1, 1, 1, 1, 0, h, 1},
+	/* Bug */ {1, h, 1, 1, 2, 1, h, h, 1, h, 2, 1, 1, h, 1, 2, h, h},
+	/* Roc */ {1, 2, 1, 1, 1, 2, h, 1, h, 2, 1, 2, 1, 1, 1, 1, h, 1},
+	/* Gho */ {0, 1, 1, 1, 1, 1, 1, 1, 1, 1, 2, 1, 1, 2, 1, h, 1, 1},
+	/* Dra */ {1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 2, 1, h, 0},
+	/* Dar */ {1, 1, 1, 1, 1, 1, h, 1, 1, 1, 2, 1, 1, 2, 1, h, 1, h},
+	/* Ste */ {1, h, h, h, 1, 2, 1, 1, 1, 1, 1, 1, 2, 1, 1, 1, h, 2},
+	/* Fai */ {1, h, 1, 1, 1, 1, 2, h, 1, 1, 1, 1, 1, 1, 2, 2, h, 1},
+}
+
+var index = func() map[string]int {
+	m := make(map[string]int, len(Types))
+	for i, t := range Types {
+		m[t] = i
+	}
+	return m
+}()
+
+// IsType reports whether t is one of the 18 elemental types.
+func IsType(t string) bool {
+	_, ok := index[t]
+	return ok
+}
+
+// Effectiveness returns the damage multiplier of an attack of type attack against a pokemon of the defend types.
+// The multipliers of a dual-typed pokemon are multiplied, so the result is 0, 0.25, 0.5, 1, 2 or 4.
+// Unknown types are ignored.
+func Effectiveness(attack string, defend ...string) float64 {
+	a, ok := index[attack]
+	if !ok {
+		return 1
+	}
+	multiplier := 1.0
+	for _, t := range defend {
+		if d, ok := index[t]; ok {
+			multiplier *= chart[a][d]
+		}
+	}
+	return multiplier
+}
+
+// Profile sorts the attacking types by how they fare against a pokemon of some defending types.
+type Profile struct {
+	// Weaknesses maps the attacking types that deal more than normal damage to their multiplier.
+	Weaknesses map[string]float64 `json:"weaknesses"`
+	// Resistances maps the attacking types that deal less than normal damage to their multiplier.
+	Resistances map[string]float64 `json:"resistances"`
+	// Immunities lists the attacking types that deal no damage.
+	Immunities []string `json:"immunities"`
+	// Neutral lists the attacking types that deal normal damage.
+	Neutral []string `json:"neutral"`
+}
+
+// DefenseProfile returns how every attacking type fares against a pokemon of the defend types.
+func DefenseProfile(defend ...string) Profile {
+	p := Profile{
+		Weaknesses:  map[string]float64{},
+		Resistances: map[string]float64{},
+		Immunities:  []string{},
+		Neutral:     []string{},
+	}
+	for _, attack := range Types {
+		switch m := Effectiveness(attack, defend...); {
+		case m == 0:
+			p.Immunities = append(p.Immunities, attack)
+		case m < 1:
+			p.Resistances[attack] = m
+		case m > 1:
+			p.Weaknesses[attack] = m
+		default:
+			p.Neutral = append(p.Neutral, attack)
+		}
+	}
+	return p
+}
+
+// Describe names the effect of a damage multiplier the way the games do.
+func Describe(multiplier float64) string {
+	switch {
+	case multiplier == 0:
+		return "no effect"
+	case multiplier < 1:
+		return "not very effective"
+	case multiplier > 1:
+		return "super effective"
+	}
+	return "normal"
+}
@@ -0,0 +1,82 @@
+package typechart
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEffectiveness(t *testing.T) {
+	tests := []struct {
+		attack string
+		defend []string
+		want   float64
+	}{
+		{"electric", []string{"water"}, 2},
+		{"electric", []string{"water", "flying"}, 4},
+		{"electric", []string{"ground"}, 0},
+		{"electric", []string{"ground", "flying"}, 0},
+		{"fire", []string{"water"}, 0.5},
+		{"fire", []string{"water", "rock"}, 0.25},
+		{"fire", []string{"grass", "water"}, 1},
+		{"normal", []string{"ghost"}, 0},
+		{"dragon", []string{"fairy"}, 0},
+		{"fighting", []string{"normal"}, 2},
+		{"normal", []string{"normal"}, 1},
+		{"normal", nil, 1},
+		{"unknown", []string{"water"}, 1},
+		{"grass", []string{"unknown", "water"}, 2},
+	}
+	for _, tt := range tests {
+		if got := Effectiveness(tt.attack, tt.defend...); got != tt.want {
+			t.Errorf("Effectiveness(%s, %v) = %v, want %v", tt.attack, tt.defend, got, tt.want)
+		}
+	}
+}
+
+func TestDefenseProfile(t *testing.T) {
+	tests := []struct {
+		defend      []string
+		weaknesses  map[string]float64
+		immunities  []string
+		resistances int
+	}{
+		{[]string{"electric"}, map[string]float64{"ground": 2}, []string{}, 3},
+		{[]string{"ground", "flying"}, map[string]float64{"water": 2, "ice": 4}, []string{"electric", "ground"}, 3},
+		{[]string{"normal", "ghost"}, map[string]float64{"dark": 2}, []string{"normal", "fighting", "ghost"}, 2},
+	}
+	for _, tt := range tests {
+		p := DefenseProfile(tt.defend...)
+		if !reflect.DeepEqual(p.Weaknesses, tt.weaknesses) {
+			t.Errorf("weaknesses of %v = %v, want %v", tt.defend, p.Weaknesses, tt.weaknesses)
+		}
+		if !reflect.DeepEqual(p.Immunities, tt.immunities) {
+			t.Errorf("immunities of %v = %v, want %v", tt.defend, p.Immunities, tt.immunities)
+		}
+		if len(p.Resistances) != tt.resistances {
+			t.Errorf("resistances of %v = %v, want %d of them", tt.defend, p.Resistances, tt.resistances)
+		}
+		total := len(p.Weaknesses) + len(p.Resistances) + len(p.Immunities) + len(p.Neutral)
+		if total != len(Types) {
+			t.Errorf("the profile of %v sorts %d types, want %d", tt.defend, total, len(Types))
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		multiplier float64
+		want       string
+	}{
+		{0, "no effect"},
+		{0.25, "not very effective"},
+		{0.5, "not very effective"},
+		{1, "normal"},
+		{2, "super effective"},
+		{4, "super effective"},
+	}
+	for _, tt := range tests {
+		if got := Describe(tt.multiplier); got != tt.want {
+			t.Errorf("Describe(%v) = %q, want %q", tt.multiplier, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package typechart
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type matchup struct {
+	Attack     string   `json:"attack" example:"fire"`
+	Defend     []string `json:"defend" example:"grass,steel"`
+	Multiplier float64  `json:"multiplier" example:"4"`
+	Effect     string   `json:"effect" enums:"no effect,not very effective,normal,super effective" example:"super effective"`
+}
+
+// parseTypes splits a comma-separated list of one or two distinct elemental types.
+func parseTypes(s string) ([]string, error) {
+	var types []string
+	for _, t := range strings.Split(s, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if !IsType(t) {
+			return nil, fmt.Errorf("unknown type %q", t)
+		}
+		if len(types) > 0 && types[0] == t {
+			return nil, fmt.Errorf("type %q is listed twice", t)
+		}
+		types = append(types, t)
+	}
+	if len(types) > 2 {
+		return nil, fmt.Errorf("a pokemon can't have more than 2 types")
+	}
+	return types, nil
+}
+
+// GetTypeMatchup godoc
+// @title        Get Type Matchup
+// @summary      Compute the effectiveness of an attack type
+// @description  Get the damage multiplier of an attack of one type against a pokemon of one or two types.
+// @produce      json
+// @param        attack  query  string  true  "attacking type"  example(fire)
+// @param        defend  query  string  true  "comma-separated defending types"  example(grass,steel)
+// @success      200 {object} matchup
+// @failure      400 {string} string "unknown type"
+// @router       /types/matchup [get]
+func GetTypeMatchup(c *gin.Context) {
+	attack := strings.ToLower(strings.TrimSpace(c.Query("attack")))
+	if !IsType(attack) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("unknown type %q", attack)})
+		return
+	}
+	defend, err := parseTypes(c.Query("defend"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	multiplier := Effectiveness(attack, defend...)
+	c.IndentedJSON(http.StatusOK, matchup{
+		Attack:     attack,
+		Defend:     defend,
+		Multiplier: multiplier,
+		Effect:     Describe(multiplier),
+	})
+}
+
+// GetTypes godoc
+// @title        Get Types
+// @summary      Retrieve the elemental types
+// @description  Get the 18 elemental types a pokemon can have.
+// @produce      json
+// @success      200 {array} string
+// @router       /types [get]
+func GetTypes(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, Types)
+}
@@ -0,0 +1,124 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"sync"
+	"time"
+
+	"example.com/pokemon-handbook/auth"
+)
+
+// Authenticator checks credentials against a UserRepository.
+//
+// Verified credentials are remembered for a short time, so clients sending Basic credentials
+// with every request don't pay for a lookup and a bcrypt comparison each time. The cache keeps
+// only a salted digest of the password, and the user handlers drop the entry of a user they change.
+type Authenticator struct {
+	repo UserRepository
+	ttl  time.Duration
+	salt []byte
+
+	mu    sync.Mutex
+	cache map[string]cachedLogin
+}
+
+type cachedLogin struct {
+	digest    [sha256.Size]byte
+	principal auth.Principal
+	expires   time.Time
+}
+
+// dummyHash is compared against when the login is unknown, so that the response time
+// doesn't tell whether a login exists.
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// NewAuthenticator returns an Authenticator remembering verified credentials for ttl. A zero ttl disables the cache.
+func NewAuthenticator(repo UserRepository, ttl time.Duration) *Authenticator {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err)
+	}
+	return &Authenticator{
+		repo:  repo,
+		ttl:   ttl,
+		salt:  salt,
+		cache: make(map[string]cachedLogin),
+	}
+}
+
+// Authenticate implements auth.Authenticator.
+func (a *Authenticator) Authenticate(ctx context.Context, login, password string) (auth.Principal, error) {
+	digest := a.digest(password)
+	if p, ok := a.cached(login, digest); ok {
+		return p, nil
+	}
+
+	u, err := a.repo.Get(ctx, login)
+	if errors.Is(err, ErrNotFound) {
+		dummyHashOnce.Do(func() { dummyHash, _ = hashPassword("") })
+		checkPassword(dummyHash, password)
+		return auth.Principal{}, auth.ErrInvalidCredentials
+	}
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	if !checkPassword(u.Password, password) {
+		return auth.Principal{}, auth.ErrInvalidCredentials
+	}
+
+	p := auth.Principal{Login: u.Login, Role: u.Role}
+	if a.ttl > 0 {
+		a.mu.Lock()
+		a.cache[login] = cachedLogin{digest: digest, principal: p, expires: time.Now().Add(a.ttl)}
+		a.mu.Unlock()
+	}
+	return p, nil
+}
+
+// Forget drops the remembered credentials of login, so its next request is checked against the repository.
+func (a *Authenticator) Forget(login string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.cache, login)
+}
+
+func (a *Authenticator) cached(login string, digest [sha256.Size]byte) (auth.Principal, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry, ok := a.cache[login]
+	if !ok {
+		return auth.Principal{}, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(a.cache, login)
+		return auth.Principal{}, false
+	}
+	if subtle.ConstantTimeCompare(entry.digest[:], digest[:]) != 1 {
+		return auth.Principal{}, false
+	}
+	return entry.principal, true
+}
+
+func (a *Authenticator) digest(password string) [sha256.Size]byte {
+	return sha256.Sum256(append(append([]byte{}, a.salt...), password...))
+}
+
+// LookupUser implements auth.UserLookup.
+func (a *Authenticator) LookupUser(ctx context.Context, login string) (auth.Principal, error) {
+	u, err := a.repo.Get(ctx, login)
+	if errors.Is(err, ErrNotFound) {
+		return auth.Principal{}, auth.ErrInvalidCredentials
+	}
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	return auth.Principal{Login: u.Login, Role: u.Role}, nil
+}
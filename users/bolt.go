@@ -0,0 +1,128 @@
+package users
+
+import (
+	"context"
+
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type boltRepository struct {
+	db     *bbolt.DB
+	bucket []byte
+}
+
+// NewBoltRepository returns a UserRepository that stores users in the given bucket of a bolt database file.
+// Documents are encoded as BSON and keyed by login.
+func NewBoltRepository(db *bbolt.DB, bucket string) (UserRepository, error) {
+	r := &boltRepository{db: db, bucket: []byte(bucket)}
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(r.bucket)
+		return err
+	})
+	return r, err
+}
+
+func (r *boltRepository) put(b *bbolt.Bucket, u user) error {
+	data, err := bson.Marshal(u)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(u.Login), data)
+}
+
+func (r *boltRepository) Create(ctx context.Context, u *user) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		if b.Get([]byte(u.Login)) != nil {
+			return ErrDuplicateLogin
+		}
+		u.stamp(1)
+		return r.put(b, *u)
+	})
+}
+
+// get returns the user with the given login, or ErrNotFound.
+func (r *boltRepository) get(b *bbolt.Bucket, login string) (user, error) {
+	result := user{}
+	data := b.Get([]byte(login))
+	if data == nil {
+		return result, ErrNotFound
+	}
+	err := bson.Unmarshal(data, &result)
+	return result, err
+}
+
+func (r *boltRepository) Get(ctx context.Context, login string) (user, error) {
+	result := user{}
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		result, err = r.get(tx.Bucket(r.bucket), login)
+		return err
+	})
+	return result, err
+}
+
+func (r *boltRepository) List(ctx context.Context) ([]user, error) {
+	var users = []user{}
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(r.bucket).ForEach(func(k, v []byte) error {
+			result := user{}
+			if err := bson.Unmarshal(v, &result); err != nil {
+				return err
+			}
+			users = append(users, result)
+			return nil
+		})
+	})
+	return users, err
+}
+
+func (r *boltRepository) Upsert(ctx context.Context, u *user, version int64) (bool, error) {
+	created := false
+	err := r.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		old, err := r.get(b, u.Login)
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+		created = err == ErrNotFound
+		if version != 0 && (created || old.Version != version) {
+			return ErrVersionMismatch
+		}
+		u.stamp(old.Version + 1)
+		return r.put(b, *u)
+	})
+	return created, err
+}
+
+func (r *boltRepository) Delete(ctx context.Context, login string, version int64) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		u, err := r.get(b, login)
+		if err != nil {
+			return err
+		}
+		if version != 0 && u.Version != version {
+			return ErrVersionMismatch
+		}
+		return b.Delete([]byte(login))
+	})
+}
+
+func (r *boltRepository) HasRole(ctx context.Context, role string) (bool, error) {
+	found := false
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(r.bucket).ForEach(func(k, v []byte) error {
+			result := user{}
+			if err := bson.Unmarshal(v, &result); err != nil {
+				return err
+			}
+			if result.Role == role {
+				found = true
+			}
+			return nil
+		})
+	})
+	return found, err
+}
@@ -2,60 +2,105 @@ package users
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"example.com/pokemon-handbook/audit"
+	"example.com/pokemon-handbook/auth"
+	"example.com/pokemon-handbook/conditional"
 	"example.com/pokemon-handbook/config"
+	"example.com/pokemon-handbook/jsonpatch"
 )
 
 type user struct {
-	Login    string `json:"login"`
+	Login string `json:"login"`
+	// Password is accepted in requests but never sent back. It is stored as a bcrypt hash.
 	Password string `json:"password"`
-	Role     string `json:"role"`
+	Role     string `json:"role" enums:"viewer,editor,admin"`
+	// Version counts the changes of the user and UpdatedAt is the time of the last one. The repository sets
+	// them and they are sent as the ETag and Last-Modified headers.
+	Version   int64     `bson:"version,omitempty" json:"-"`
+	UpdatedAt time.Time `bson:"updated_at" json:"-"`
 }
 
-func CheckAdminInDB() {
-	newUser := user{
-		Login:    config.Conf.UserName,
-		Password: config.Conf.Password,
-		Role:     "admin",
-	}
+// MarshalJSON leaves the password hash out of every response.
+func (u user) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Login string `json:"login"`
+		Role  string `json:"role"`
+	}{u.Login, u.Role})
+}
 
-	collection, cancel, err := config.ConnectToMongoDB(config.Conf.UserCollecName)
-	defer cancel()
+// Handler serves the /users routes on top of a UserRepository.
+type Handler struct {
+	repo     UserRepository
+	auditLog *audit.Log
+	lockout  LockoutClearer
+	keys     KeyRevoker
+	caches   []CredentialCache
+}
+
+// KeyRevoker is implemented by whatever issues keys to users, such as apikeys.Handler. The handler revokes
+// the keys of the users it deletes, so that a user created later with the same login doesn't get them.
+type KeyRevoker interface {
+	// RevokeKeys deletes the keys of login on behalf of the request of c.
+	RevokeKeys(c *gin.Context, login string) error
+}
+
+// LockoutClearer is implemented by whatever locks logins out after failed attempts, such as auth.Lockout.
+// The handler clears the lockout of users whose password it changes.
+type LockoutClearer interface {
+	// Clear forgets the failed attempts of login on behalf of the request of c and audits it.
+	Clear(c *gin.Context, login string)
+}
+
+// CredentialCache is implemented by whatever remembers verified credentials, such as Authenticator.
+// The handler tells it about every user it changes or deletes.
+type CredentialCache interface {
+	// Forget drops whatever is remembered about login.
+	Forget(login string)
+}
+
+// NewHandler returns a Handler that stores users in repo, records changes in auditLog, clears the lockout
+// of users whose password is changed, revokes the keys of deleted users and tells caches about every change.
+func NewHandler(repo UserRepository, auditLog *audit.Log, lockout LockoutClearer, keys KeyRevoker, caches ...CredentialCache) *Handler {
+	return &Handler{repo: repo, auditLog: auditLog, lockout: lockout, keys: keys, caches: caches}
+}
+
+// CheckAdminInDB adds the admin account from the config file to repo
+// unless a user with the admin role is already stored there.
+func CheckAdminInDB(repo UserRepository) {
+	exists, err := repo.HasRole(context.Background(), auth.Admin)
 	if err != nil {
-		fmt.Println(err)
+		log.Fatal(err)
+	}
+	if exists {
+		fmt.Println("A user with admin role already exists.")
 		return
 	}
 
-	var existedAdminUser bson.M
-	err = collection.FindOne(
-		context.Background(),
-		bson.D{{Key: "role", Value: "admin"}},
-	).Decode(&existedAdminUser)
+	hash, err := hashPassword(config.Conf.Password)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			res, err := collection.InsertOne(context.Background(), newUser)
-			if err != nil {
-				fmt.Println(err)
-				return
-			}
-			id := res.InsertedID
-			fmt.Printf("id value %v\n", id)
-
-			// users = append(users, newUser)
-			fmt.Println("The user with admin role is added to the users collection of the database.")
-			return
-		}
 		log.Fatal(err)
 	}
-	fmt.Printf("A user with admin role already exists: %v\n", existedAdminUser)
+	newUser := user{
+		Login:    config.Conf.UserName,
+		Password: hash,
+		Role:     auth.Admin,
+	}
+
+	if err := repo.Create(context.Background(), &newUser); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("The user with admin role is added to the users collection of the database.")
 }
 
 // Post User godoc
@@ -63,42 +108,41 @@ func CheckAdminInDB() {
 // @summary      Post user to the MongoDB
 // @description  Post a user to the MongoDB. If the database doesn't exist, create and insert a new value. Pass values in json format.
 // @produce      json
+// @param        user  body  user  true  "the new user; the password is never returned"
 // @success      201 {object} user
+// @header       201 {string} ETag "version of the user"
 // @failure      400 {string} string "object can't be parsed into JSON"
+// @failure      400 {string} string "password must not be empty"
+// @failure      400 {string} string "unknown role"
+// @failure      409 {string} string "a user with such login already exists"
 // @router       /users [post]
-func PostUser(c *gin.Context) {
+func (h *Handler) PostUser(c *gin.Context) {
 	var newUser user
 
 	if err := c.BindJSON(&newUser); err != nil {
 		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "object can't be parsed into JSON"})
 		return
 	}
-
-	collection, cancel, err := config.ConnectToMongoDB(config.Conf.UserCollecName)
-	defer cancel()
-	if err != nil {
-		fmt.Println(err)
+	if newUser.Password == "" {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "password must not be empty"})
+		return
+	}
+	if !checkRole(c, newUser) || !setPasswordHash(c, &newUser) {
 		return
 	}
 
-	_, err = collection.Find(context.Background(), bson.D{{Key: "login", Value: newUser.Login}})
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			res, err := collection.InsertOne(context.Background(), newUser)
-			if err != nil {
-				fmt.Println(err)
-				return
-			}
-			id := res.InsertedID
-			fmt.Printf("id value %v\n", id)
-
-			// users = append(users, newUser)
-			c.IndentedJSON(http.StatusCreated, newUser)
+	if err := h.repo.Create(c.Request.Context(), &newUser); err != nil {
+		if errors.Is(err, ErrDuplicateLogin) {
+			c.IndentedJSON(http.StatusConflict, gin.H{"message": "a user with such login already exists"})
 			return
 		}
-		log.Fatal(err)
+		respondWithInternalError(c, err)
+		return
 	}
-	fmt.Printf("A user with such login already exists, choose another login!")
+	h.auditLog.Record(c, audit.ActionCreate, resource(newUser.Login), nil, newUser)
+
+	conditional.SetHeaders(c, newUser.Version, newUser.UpdatedAt)
+	c.IndentedJSON(http.StatusCreated, newUser)
 }
 
 // GetUsers godoc
@@ -110,37 +154,10 @@ func PostUser(c *gin.Context) {
 // @failure      400 {string} string "object can't be parsed into JSON"
 // @failure      404 {string} string "Error: Not Found"
 // @router       /users [get]
-func GetUsers(c *gin.Context) {
-	var users = []user{}
-
-	collection, cancel, err := config.ConnectToMongoDB(config.Conf.UserCollecName)
-	defer cancel()
+func (h *Handler) GetUsers(c *gin.Context) {
+	users, err := h.repo.List(c.Request.Context())
 	if err != nil {
-		fmt.Println(err)
-		return
-	}
-
-	cur, err := collection.Find(context.Background(), bson.D{})
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer cur.Close(context.Background())
-	for cur.Next(context.Background()) {
-		// To decode into a struct, use cursor.Decode()
-		result := user{}
-		err := cur.Decode(&result)
-		if err != nil {
-			log.Fatal(err)
-		}
-		fmt.Printf("User entry: %v\n", result)
-		users = append(users, result)
-
-		// To get the raw bson bytes use cursor.Current
-		raw := cur.Current
-		fmt.Printf("Raw result entry: %v\n", raw)
-	}
-	if err := cur.Err(); err != nil {
-		fmt.Println(err)
+		respondWithInternalError(c, err)
 		return
 	}
 	c.IndentedJSON(http.StatusOK, users)
@@ -150,25 +167,27 @@ func GetUsers(c *gin.Context) {
 // @title        Get User By Login
 // @summary      Retrieve user from the MongoDB based on given Login
 // @description  Get a user from the MongoDB by given login. Pass values in json format. If there aren't any users with the login gives a message "user not found".
+// @description  The ETag and Last-Modified headers tell the version of the user. Sending them back in If-None-Match or If-Modified-Since gets a 304 response while the user is unchanged.
 // @produce      json
+// @param        If-None-Match      header  string  false  "ETag of the user the client has"
+// @param        If-Modified-Since  header  string  false  "Last-Modified of the user the client has"
 // @success      200 {object} user
+// @header       200 {string} ETag "version of the user"
+// @header       200 {string} Last-Modified "time of the last change of the user"
+// @success      304 {string} string "the user is unchanged"
 // @failure      404 {string} string "user not found"
 // @router       /users/{id} [get]
-func GetUserByLogin(c *gin.Context) {
-	login := c.Param("id")
-
-	collection, cancel, err := config.ConnectToMongoDB(config.Conf.UserCollecName)
-	defer cancel()
+func (h *Handler) GetUserByLogin(c *gin.Context) {
+	result, err := h.repo.Get(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		fmt.Println(err)
+		if errors.Is(err, ErrNotFound) {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"message": "user not found"})
+			return
+		}
+		respondWithInternalError(c, err)
 		return
 	}
-
-	result := user{}
-	err = collection.FindOne(context.Background(), bson.D{{Key: "login", Value: login}}).Decode(&result)
-	if err != nil {
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "user not found"})
-		// log.Fatal(err)
+	if conditional.NotModified(c, result.Version, result.UpdatedAt) {
 		return
 	}
 	c.IndentedJSON(http.StatusOK, result)
@@ -178,11 +197,20 @@ func GetUserByLogin(c *gin.Context) {
 // @title        Update User By ID
 // @summary      Update user's data in the MongoDB based on given ID
 // @description  Update an existing user in the MongoDB by ID. Pass values in json format. If there isn't user with the ID creates a new user.
+// @description  An empty password keeps the current one. With an If-Match header the user is only replaced while it is at the version of that ETag.
 // @produce      json
-// @success      200 {object} user
-// @failure      201      {string}  string        ""
+// @param        If-Match  header  string  false  "ETag of the user the change is based on"
+// @param        user      body    user    true   "the new state of the user; the password is never returned"
+// @success      200 {string} string "user was updated"
+// @success      201 {object} user
+// @header       200,201 {string} ETag "new version of the user"
+// @failure      400 {string} string "object can't be parsed into JSON"
+// @failure      400 {string} string "password must not be empty"
+// @failure      400 {string} string "unknown role"
+// @failure      406 {string} string "user's login cannot be changed"
+// @failure      412 {string} string "the user has been changed since it was read"
 // @router       /users/{id} [put]
-func UpdateUserByLogin(c *gin.Context) {
+func (h *Handler) UpdateUserByLogin(c *gin.Context) {
 	login := c.Param("id")
 	var newUser user
 
@@ -191,62 +219,220 @@ func UpdateUserByLogin(c *gin.Context) {
 		return
 	}
 
-	collection, cancel, err := config.ConnectToMongoDB(config.Conf.UserCollecName)
-	defer cancel()
+	if newUser.Login == "" {
+		newUser.Login = login
+	}
+	if newUser.Login != login {
+		c.IndentedJSON(http.StatusNotAcceptable, gin.H{"message": "user's login cannot be changed"})
+		return
+	}
+	if !checkRole(c, newUser) {
+		return
+	}
+
+	var before interface{}
+	existing, err := h.repo.Get(c.Request.Context(), login)
+	if err == nil {
+		before = existing
+	} else if !errors.Is(err, ErrNotFound) {
+		respondWithInternalError(c, err)
+		return
+	}
+	version, ok := conditional.Match(c, existing.Version, before != nil)
+	if !ok {
+		return
+	}
+
+	passwordChanged := newUser.Password != ""
+	if !passwordChanged {
+		if before == nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "password must not be empty"})
+			return
+		}
+		newUser.Password = existing.Password
+	} else if !setPasswordHash(c, &newUser) {
+		return
+	}
+
+	created, err := h.repo.Upsert(c.Request.Context(), &newUser, version)
 	if err != nil {
-		fmt.Println(err)
+		respondWithWriteError(c, err)
 		return
 	}
+	h.forget(newUser.Login)
+	action := audit.ActionUpdate
+	if created {
+		action = audit.ActionCreate
+	}
+	h.auditLog.Record(c, action, resource(login), before, newUser)
+	if passwordChanged {
+		h.lockout.Clear(c, login)
+	}
+
+	conditional.SetHeaders(c, newUser.Version, newUser.UpdatedAt)
+	if created {
+		c.IndentedJSON(http.StatusCreated, newUser)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "user was updated"})
+}
 
-	opts := options.FindOneAndUpdate().SetUpsert(true)
-	filter := bson.D{{Key: "login", Value: login}}
-	update := bson.D{{Key: "$set", Value: newUser}}
-	var updatedPokemon bson.M
-	err = collection.FindOneAndUpdate(
-		context.Background(),
-		filter,
-		update,
-		opts,
-	).Decode(&updatedPokemon)
+// PatchUserByLogin godoc
+// @title        Patch User By Login
+// @summary      Change some fields of a user
+// @description  Update an existing user by login with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902), told apart by the Content-Type. The patched document is the user as returned by GET /users/{id}, without the password: add a password member to change it.
+// @description  With an If-Match header the user is only patched while it is at the version of that ETag.
+// @accept       application/merge-patch+json,application/json-patch+json
+// @produce      json
+// @param        If-Match  header  string  false  "ETag of the user the patch is based on"
+// @param        patch     body    object  true  "the patch, e.g. {\"role\": \"editor\"} or [{\"op\": \"replace\", \"path\": \"/role\", \"value\": \"editor\"}]"
+// @success      200 {object} user
+// @header       200 {string} ETag "new version of the user"
+// @failure      404 {string} string "user not found"
+// @failure      400 {string} string "invalid patch"
+// @failure      400 {string} string "unknown role"
+// @failure      406 {string} string "user's login cannot be changed"
+// @failure      409 {string} string "test failed"
+// @failure      409 {string} string "the user was changed or deleted while it was patched"
+// @failure      412 {string} string "the user has been changed since it was read"
+// @failure      415 {string} string "the patch must be sent as application/merge-patch+json or application/json-patch+json"
+// @failure      422 {string} string "the patch can't be applied"
+// @router       /users/{id} [patch]
+func (h *Handler) PatchUserByLogin(c *gin.Context) {
+	login := c.Param("id")
+	before, err := h.repo.Get(c.Request.Context(), login)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			// users = append(users, newUser)
-			fmt.Printf("id value %v\n", newUser.Login)
-			c.IndentedJSON(http.StatusCreated, newUser)
+		if errors.Is(err, ErrNotFound) {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"message": "user not found"})
 			return
 		}
-		log.Fatal(err)
+		respondWithInternalError(c, err)
+		return
 	}
-	c.IndentedJSON(http.StatusOK, gin.H{"message": "user was updated"})
+	version, ok := conditional.Match(c, before.Version, true)
+	if !ok {
+		return
+	}
+
+	var newUser user
+	if !jsonpatch.Bind(c, before, &newUser) {
+		return
+	}
+	if newUser.Login != login {
+		c.IndentedJSON(http.StatusNotAcceptable, gin.H{"message": "user's login cannot be changed"})
+		return
+	}
+	if !checkRole(c, newUser) {
+		return
+	}
+	passwordChanged := newUser.Password != ""
+	if !passwordChanged {
+		newUser.Password = before.Password
+	} else if !setPasswordHash(c, &newUser) {
+		return
+	}
+
+	// the patch was applied to before, so it is only written while the user is still at that version
+	if _, err := h.repo.Upsert(c.Request.Context(), &newUser, before.Version); err != nil {
+		if errors.Is(err, ErrVersionMismatch) && version == 0 {
+			c.IndentedJSON(http.StatusConflict, gin.H{"message": "the user was changed while it was patched, retry"})
+			return
+		}
+		respondWithWriteError(c, err)
+		return
+	}
+	h.forget(login)
+	h.auditLog.Record(c, audit.ActionUpdate, resource(login), before, newUser)
+	if passwordChanged {
+		h.lockout.Clear(c, login)
+	}
+	conditional.SetHeaders(c, newUser.Version, newUser.UpdatedAt)
+	c.IndentedJSON(http.StatusOK, newUser)
 }
 
 // DeleteUserByLogin godoc
 // @title        Delete User By Login
 // @summary      Delete user in the MongoDB based on given login
-// @description  Delete an existing user in the MongoDB by login and gives a message. Pass values in json format. If there isn't user with the login gives a message.
+// @description  Delete an existing user in the MongoDB by login and gives a message. Pass values in json format. If there isn't user with the login gives a message. The API keys of the user are revoked.
+// @description  With an If-Match header the user is only deleted while it is at the version of that ETag.
 // @produce      json
+// @param        If-Match  header  string  false  "ETag of the user the deletion is based on"
 // @success      200 {object} user "user was deleted"
-// @failure      201 {string} string "user not found"
+// @failure      404 {string} string "user not found"
+// @failure      412 {string} string "the user has been changed since it was read"
 // @router       /users/{id} [delete]
-func DeleteUserByLogin(c *gin.Context) {
-	login := c.Param("id")
-
-	collection, cancel, err := config.ConnectToMongoDB(config.Conf.UserCollecName)
-	defer cancel()
+func (h *Handler) DeleteUserByLogin(c *gin.Context) {
+	before, err := h.repo.Get(c.Request.Context(), c.Param("id"))
+	if err == nil {
+		version, ok := conditional.Match(c, before.Version, true)
+		if !ok {
+			return
+		}
+		// the keys are revoked first, so that a failure leaves the user to be deleted again
+		if err := h.keys.RevokeKeys(c, before.Login); err != nil {
+			respondWithInternalError(c, err)
+			return
+		}
+		err = h.repo.Delete(c.Request.Context(), c.Param("id"), version)
+	}
 	if err != nil {
-		fmt.Println(err)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			c.IndentedJSON(http.StatusNotFound, gin.H{"message": "user not found"})
+		case errors.Is(err, ErrVersionMismatch):
+			conditional.Failed(c)
+		default:
+			respondWithInternalError(c, err)
+		}
 		return
 	}
+	h.forget(c.Param("id"))
+	h.auditLog.Record(c, audit.ActionDelete, resource(before.Login), before, nil)
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "user was deleted"})
+}
+
+// checkRole writes a 400 response and returns false when u has none of the known roles.
+func checkRole(c *gin.Context, u user) bool {
+	if auth.IsRole(u.Role) {
+		return true
+	}
+	c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("unknown role %q, use one of %s", u.Role, strings.Join(auth.Roles(), ", "))})
+	return false
+}
+
+// resource names the user with the given login in the audit log.
+func resource(login string) string {
+	return "users/" + login
+}
+
+func (h *Handler) forget(login string) {
+	for _, cache := range h.caches {
+		cache.Forget(login)
+	}
+}
 
-	res, err := collection.DeleteOne(context.Background(), bson.D{{Key: "login", Value: login}})
+// setPasswordHash replaces the plain text password of u with its hash.
+// When it returns false the error response has already been written.
+func setPasswordHash(c *gin.Context, u *user) bool {
+	hash, err := hashPassword(u.Password)
 	if err != nil {
-		log.Fatal(err)
-		return
+		respondWithInternalError(c, err)
+		return false
 	}
-	// users = append(users[:s], users[s+1:]...)
-	if res.DeletedCount == 0 {
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "user not found"})
-	} else {
-		c.IndentedJSON(http.StatusOK, gin.H{"message": "user was deleted"})
+	u.Password = hash
+	return true
+}
+
+// respondWithWriteError responds to the errors of Upsert.
+func respondWithWriteError(c *gin.Context, err error) {
+	if errors.Is(err, ErrVersionMismatch) {
+		conditional.Failed(c)
+		return
 	}
+	respondWithInternalError(c, err)
+}
+
+func respondWithInternalError(c *gin.Context, err error) {
+	fmt.Println(err)
+	c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
 }
@@ -0,0 +1,270 @@
+package users
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/pokemon-handbook/audit"
+	"example.com/pokemon-handbook/auth"
+)
+
+// clearedLockouts records the logins whose lockout the handler clears.
+type clearedLockouts []string
+
+func (l *clearedLockouts) Clear(c *gin.Context, login string) {
+	*l = append(*l, login)
+}
+
+// revokedKeys records the logins whose keys the handler revokes, failing with err.
+type revokedKeys struct {
+	logins []string
+	err    error
+}
+
+func (k *revokedKeys) RevokeKeys(c *gin.Context, login string) error {
+	if k.err != nil {
+		return k.err
+	}
+	k.logins = append(k.logins, login)
+	return nil
+}
+
+// newTestRouter serves the handler of repo, to which the given users are added.
+func newTestRouter(t *testing.T, repo UserRepository, users ...user) *gin.Engine {
+	t.Helper()
+	return newTestRouterWith(t, repo, &clearedLockouts{}, &revokedKeys{}, users...)
+}
+
+// newTestRouterWith serves the handler of repo, to which the given users are added, clearing lockouts with lockout
+// and revoking keys with keys.
+func newTestRouterWith(t *testing.T, repo UserRepository, lockout LockoutClearer, keys KeyRevoker, users ...user) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	for i := range users {
+		if err := repo.Create(context.Background(), &users[i]); err != nil {
+			t.Fatalf("Create(%s): %v", users[i].Login, err)
+		}
+	}
+	auditLog := audit.NewLog(audit.NewMemoryRepository())
+	h := NewHandler(repo, auditLog, lockout, keys)
+
+	r := gin.New()
+	r.GET("/audit", auditLog.GetAudit)
+	r.POST("/users", h.PostUser)
+	r.GET("/users/:id", h.GetUserByLogin)
+	r.PUT("/users/:id", h.UpdateUserByLogin)
+	r.PATCH("/users/:id", h.PatchUserByLogin)
+	r.DELETE("/users/:id", h.DeleteUserByLogin)
+	return r
+}
+
+func serve(r *gin.Engine, method, path string, header http.Header, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// racingRepository runs a concurrent change once a handler has read a user, before it writes it back.
+type racingRepository struct {
+	UserRepository
+	afterGet func()
+}
+
+func (r *racingRepository) Get(ctx context.Context, login string) (user, error) {
+	u, err := r.UserRepository.Get(ctx, login)
+	if fn := r.afterGet; fn != nil {
+		r.afterGet = nil
+		fn()
+	}
+	return u, err
+}
+
+func TestPatchUserRace(t *testing.T) {
+	mergePatch := http.Header{"Content-Type": {"application/merge-patch+json"}}
+	tests := []struct {
+		name    string
+		ifMatch string
+		// race is the request served between the read and the write of the patch.
+		race    func(r *gin.Engine) *httptest.ResponseRecorder
+		status  int
+		role    string
+		deleted bool
+	}{
+		{"no race", "", nil, http.StatusOK, auth.Editor, false},
+		{"interleaved patch", "", func(r *gin.Engine) *httptest.ResponseRecorder {
+			return serve(r, http.MethodPatch, "/users/ash", mergePatch, `{"role": "admin"}`)
+		}, http.StatusConflict, auth.Admin, false},
+		{"interleaved patch with If-Match", `"1"`, func(r *gin.Engine) *httptest.ResponseRecorder {
+			return serve(r, http.MethodPatch, "/users/ash", mergePatch, `{"role": "admin"}`)
+		}, http.StatusPreconditionFailed, auth.Admin, false},
+		{"interleaved delete", "", func(r *gin.Engine) *httptest.ResponseRecorder {
+			return serve(r, http.MethodDelete, "/users/ash", nil, "")
+		}, http.StatusConflict, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &racingRepository{UserRepository: NewMemoryRepository()}
+			r := newTestRouter(t, repo, user{Login: "ash", Password: "hash", Role: auth.Viewer})
+			if tt.race != nil {
+				repo.afterGet = func() {
+					if w := tt.race(r); w.Code != http.StatusOK {
+						t.Errorf("racing request: status = %d: %s", w.Code, w.Body)
+					}
+				}
+			}
+
+			header := http.Header{"Content-Type": mergePatch["Content-Type"]}
+			if tt.ifMatch != "" {
+				header.Set("If-Match", tt.ifMatch)
+			}
+			w := serve(r, http.MethodPatch, "/users/ash", header, `{"role": "editor"}`)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+
+			stored, err := repo.UserRepository.Get(context.Background(), "ash")
+			if tt.deleted {
+				if !errors.Is(err, ErrNotFound) {
+					t.Fatalf("the deleted user was brought back: %+v, %v", stored, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Role != tt.role || stored.Version != 2 {
+				t.Errorf("stored role %s at version %d, want %s at version 2", stored.Role, stored.Version, tt.role)
+			}
+		})
+	}
+}
+
+func TestPasswordChangeClearsLockout(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		cleared     bool
+	}{
+		{"put with a password", http.MethodPut, "application/json", `{"login": "ash", "password": "new", "role": "viewer"}`, true},
+		{"put without a password", http.MethodPut, "application/json", `{"login": "ash", "role": "editor"}`, false},
+		{"patch of the password", http.MethodPatch, "application/merge-patch+json", `{"password": "new"}`, true},
+		{"patch of the role", http.MethodPatch, "application/merge-patch+json", `{"role": "editor"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lockout := &clearedLockouts{}
+			r := newTestRouterWith(t, NewMemoryRepository(), lockout, &revokedKeys{}, user{Login: "ash", Password: "hash", Role: auth.Viewer})
+
+			w := serve(r, tt.method, "/users/ash", http.Header{"Content-Type": {tt.contentType}}, tt.body)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			if cleared := len(*lockout) > 0; cleared != tt.cleared {
+				t.Errorf("lockout cleared = %v, want %v", cleared, tt.cleared)
+			}
+		})
+	}
+}
+
+func TestDeleteUserRevokesKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		revoked []string
+	}{
+		{"revoked", nil, http.StatusOK, []string{"ash"}},
+		{"revocation failure", errors.New("storage is down"), http.StatusInternalServerError, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewMemoryRepository()
+			keys := &revokedKeys{err: tt.err}
+			r := newTestRouterWith(t, repo, &clearedLockouts{}, keys, user{Login: "ash", Password: "hash", Role: auth.Viewer})
+
+			w := serve(r, http.MethodDelete, "/users/ash", nil, "")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if !reflect.DeepEqual(keys.logins, tt.revoked) {
+				t.Errorf("revoked the keys of %v, want %v", keys.logins, tt.revoked)
+			}
+			// a user whose keys may still work must be left to be deleted again
+			if _, err := repo.Get(context.Background(), "ash"); (err == nil) != (tt.err != nil) {
+				t.Errorf("Get after the deletion: %v", err)
+			}
+		})
+	}
+}
+
+func TestAuditedUserChanges(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		// action, before and after are the expected audit entry.
+		action, before, after string
+	}{
+		{"create", http.MethodPost, "/users", "application/json", `{"login": "misty", "password": "starmie", "role": "editor"}`,
+			audit.ActionCreate, `null`, `{"login":"misty","role":"editor"}`},
+		{"replace", http.MethodPut, "/users/ash", "application/json", `{"login": "ash", "password": "new", "role": "editor"}`,
+			audit.ActionUpdate, `{"login":"ash","role":"viewer"}`, `{"login":"ash","role":"editor"}`},
+		{"patch", http.MethodPatch, "/users/ash", "application/merge-patch+json", `{"password": "new"}`,
+			audit.ActionUpdate, `{"login":"ash","role":"viewer"}`, `{"login":"ash","role":"viewer"}`},
+		{"delete", http.MethodDelete, "/users/ash", "", "",
+			audit.ActionDelete, `{"login":"ash","role":"viewer"}`, `null`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRouter(t, NewMemoryRepository(), user{Login: "ash", Password: "$2a$10$hashofpikachu", Role: auth.Viewer})
+
+			w := serve(r, tt.method, tt.path, http.Header{"Content-Type": {tt.contentType}}, tt.body)
+			if w.Code != http.StatusOK && w.Code != http.StatusCreated {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			w = serve(r, http.MethodGet, "/audit", nil, "")
+			var entries []struct {
+				Action string          `json:"action"`
+				Before json.RawMessage `json:"before"`
+				After  json.RawMessage `json:"after"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+				t.Fatalf("%v: %s", err, w.Body)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("%d entries are recorded, want 1: %s", len(entries), w.Body)
+			}
+			e := entries[0]
+			if e.Action != tt.action || compact(t, e.Before) != tt.before || compact(t, e.After) != tt.after {
+				t.Errorf("recorded %s from %s to %s, want %s from %s to %s", e.Action, e.Before, e.After, tt.action, tt.before, tt.after)
+			}
+		})
+	}
+}
+
+// compact returns the JSON of raw without insignificant spaces.
+func compact(t *testing.T, raw json.RawMessage) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := json.Compact(&b, raw); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package users
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+type memoryRepository struct {
+	mu    sync.RWMutex
+	users map[string]user
+}
+
+// NewMemoryRepository returns a UserRepository that keeps users in process memory.
+// It is safe for concurrent use and loses its data when the process exits.
+func NewMemoryRepository() UserRepository {
+	return &memoryRepository{users: make(map[string]user)}
+}
+
+func (r *memoryRepository) Create(ctx context.Context, u *user) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[u.Login]; ok {
+		return ErrDuplicateLogin
+	}
+	u.stamp(1)
+	r.users[u.Login] = *u
+	return nil
+}
+
+func (r *memoryRepository) Get(ctx context.Context, login string) (user, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.users[login]
+	if !ok {
+		return user{}, ErrNotFound
+	}
+	return u, nil
+}
+
+func (r *memoryRepository) List(ctx context.Context) ([]user, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]user, 0, len(r.users))
+	for _, u := range r.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Login < users[j].Login })
+	return users, nil
+}
+
+func (r *memoryRepository) Upsert(ctx context.Context, u *user, version int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, exists := r.users[u.Login]
+	if version != 0 && (!exists || old.Version != version) {
+		return false, ErrVersionMismatch
+	}
+	u.stamp(old.Version + 1)
+	r.users[u.Login] = *u
+	return !exists, nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, login string, version int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[login]
+	if !ok {
+		return ErrNotFound
+	}
+	if version != 0 && u.Version != version {
+		return ErrVersionMismatch
+	}
+	delete(r.users, login)
+	return nil
+}
+
+func (r *memoryRepository) HasRole(ctx context.Context, role string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, u := range r.users {
+		if u.Role == role {
+			return true, nil
+		}
+	}
+	return false, nil
+}
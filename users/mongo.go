@@ -0,0 +1,117 @@
+package users
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoRepository returns a UserRepository backed by the given MongoDB collection
+// and creates the indexes it relies on.
+func NewMongoRepository(collection *mongo.Collection) (UserRepository, error) {
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "login", Value: 1}},
+		Options: options.Index().SetName("login").SetUnique(true),
+	})
+	return &mongoRepository{collection: collection}, err
+}
+
+func (r *mongoRepository) Create(ctx context.Context, u *user) error {
+	u.stamp(1)
+	_, err := r.collection.InsertOne(ctx, u)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateLogin
+	}
+	return err
+}
+
+func (r *mongoRepository) Get(ctx context.Context, login string) (user, error) {
+	result := user{}
+
+	err := r.collection.FindOne(ctx, bson.D{{Key: "login", Value: login}}).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		return result, ErrNotFound
+	}
+	return result, err
+}
+
+func (r *mongoRepository) List(ctx context.Context) ([]user, error) {
+	var users = []user{}
+
+	opts := options.Find().SetSort(bson.D{{Key: "login", Value: 1}})
+	cur, err := r.collection.Find(ctx, bson.D{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		result := user{}
+		if err := cur.Decode(&result); err != nil {
+			return nil, err
+		}
+		users = append(users, result)
+	}
+	return users, cur.Err()
+}
+
+// Upsert only inserts when version is 0. The version of u is 0, which leaves it out of $set,
+// and the stored one is incremented in the same update.
+func (r *mongoRepository) Upsert(ctx context.Context, u *user, version int64) (bool, error) {
+	opts := options.FindOneAndUpdate().SetUpsert(version == 0).SetReturnDocument(options.Before)
+	filter := bson.D{{Key: "login", Value: u.Login}}
+	if version != 0 {
+		filter = append(filter, bson.E{Key: "version", Value: version})
+	}
+	u.stamp(0)
+	update := bson.D{{Key: "$set", Value: u}, {Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}}}
+
+	old := user{}
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&old)
+	if err == mongo.ErrNoDocuments {
+		if version != 0 {
+			return false, ErrVersionMismatch
+		}
+		u.Version = 1
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	u.Version = old.Version + 1
+	return false, nil
+}
+
+func (r *mongoRepository) Delete(ctx context.Context, login string, version int64) error {
+	filter := bson.D{{Key: "login", Value: login}}
+	if version != 0 {
+		filter = append(filter, bson.E{Key: "version", Value: version})
+	}
+	res, err := r.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		// tell a user at another version from a missing one
+		if version != 0 {
+			if _, err := r.Get(ctx, login); err == nil {
+				return ErrVersionMismatch
+			}
+		}
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoRepository) HasRole(ctx context.Context, role string) (bool, error) {
+	err := r.collection.FindOne(ctx, bson.D{{Key: "role", Value: role}}).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	return err == nil, err
+}
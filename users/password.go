@@ -0,0 +1,58 @@
+package users
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"example.com/pokemon-handbook/config"
+)
+
+// hashPassword returns the bcrypt hash of password with the cost set in the config file.
+func hashPassword(password string) (string, error) {
+	cost := config.Conf.BcryptCost
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	return string(hash), err
+}
+
+// checkPassword reports whether password matches hash. The comparison takes constant time.
+func checkPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// isHashed reports whether s is a bcrypt hash rather than a plain text password.
+func isHashed(s string) bool {
+	_, err := bcrypt.Cost([]byte(s))
+	return err == nil
+}
+
+// MigratePlaintextPasswords replaces the passwords stored in plain text, as they were before hashing was introduced,
+// with their hashes. Users whose password is already hashed are left alone, so it is safe to run on every start.
+func MigratePlaintextPasswords(repo UserRepository) error {
+	users, err := repo.List(context.Background())
+	if err != nil {
+		return err
+	}
+
+	migrated := 0
+	for _, u := range users {
+		if isHashed(u.Password) {
+			continue
+		}
+		if u.Password, err = hashPassword(u.Password); err != nil {
+			return err
+		}
+		if _, err := repo.Upsert(context.Background(), &u, u.Version); err != nil {
+			return err
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		fmt.Printf("Hashed the plain text passwords of %d users.\n", migrated)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by a UserRepository when there is no user with the requested login.
+var ErrNotFound = errors.New("user not found")
+
+// ErrDuplicateLogin is returned by UserRepository.Create when a user with the same login is already stored.
+var ErrDuplicateLogin = errors.New("a user with such login already exists")
+
+// ErrVersionMismatch is returned by UserRepository.Upsert and Delete when the user to change is not
+// at the expected version.
+var ErrVersionMismatch = errors.New("the user has been changed since it was read")
+
+// stamp sets the version and the modification time of a user about to be stored.
+func (u *user) stamp(version int64) {
+	u.Version = version
+	u.UpdatedAt = time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// UserRepository is the storage used by the user handlers.
+//
+// Every write of a user increments its Version and sets its UpdatedAt. Upsert and Delete can be told
+// the version the user must be at, so that changes based on an outdated read are refused.
+type UserRepository interface {
+	// Create stores a new user at version 1, setting the Version and UpdatedAt of u, or returns ErrDuplicateLogin.
+	Create(ctx context.Context, u *user) error
+	// Get returns the user with the given login or ErrNotFound.
+	Get(ctx context.Context, login string) (user, error)
+	// List returns all users.
+	List(ctx context.Context) ([]user, error)
+	// Upsert replaces the user with u.Login or inserts it, reporting whether it was created, and sets the
+	// Version and UpdatedAt of u to the stored ones. Unless version is 0, the user must be stored at that
+	// version or ErrVersionMismatch is returned.
+	Upsert(ctx context.Context, u *user, version int64) (created bool, err error)
+	// Delete removes the user with the given login or returns ErrNotFound. Unless version is 0, the user
+	// must be at that version or ErrVersionMismatch is returned.
+	Delete(ctx context.Context, login string, version int64) error
+	// HasRole reports whether at least one user has the given role.
+	HasRole(ctx context.Context, role string) (bool, error)
+}
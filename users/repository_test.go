@@ -0,0 +1,98 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"example.com/pokemon-handbook/auth"
+	"example.com/pokemon-handbook/config"
+)
+
+// backends returns a repository of every kind that needs no server.
+func backends(t *testing.T) map[string]UserRepository {
+	t.Helper()
+	db, err := config.OpenBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	bolt, err := NewBoltRepository(db, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]UserRepository{"memory": NewMemoryRepository(), "bolt": bolt}
+}
+
+func TestRepositoryWrites(t *testing.T) {
+	tests := []struct {
+		name string
+		// write changes the repository, which holds ash at version 1.
+		write func(ctx context.Context, repo UserRepository) error
+		err   error
+		// version is the version of ash afterwards, 0 once ash is deleted.
+		version int64
+	}{
+		{"create a duplicate login", func(ctx context.Context, repo UserRepository) error {
+			return repo.Create(ctx, &user{Login: "ash", Role: auth.Admin})
+		}, ErrDuplicateLogin, 1},
+		{"upsert", func(ctx context.Context, repo UserRepository) error {
+			_, err := repo.Upsert(ctx, &user{Login: "ash", Role: auth.Editor}, 0)
+			return err
+		}, nil, 2},
+		{"upsert at the current version", func(ctx context.Context, repo UserRepository) error {
+			_, err := repo.Upsert(ctx, &user{Login: "ash", Role: auth.Editor}, 1)
+			return err
+		}, nil, 2},
+		{"upsert at an old version", func(ctx context.Context, repo UserRepository) error {
+			_, err := repo.Upsert(ctx, &user{Login: "ash", Role: auth.Editor}, 2)
+			return err
+		}, ErrVersionMismatch, 1},
+		{"upsert a new user", func(ctx context.Context, repo UserRepository) error {
+			u := &user{Login: "misty", Role: auth.Viewer}
+			created, err := repo.Upsert(ctx, u, 0)
+			if err == nil && (!created || u.Version != 1) {
+				return errors.New("the new user wasn't created at version 1")
+			}
+			return err
+		}, nil, 1},
+		{"delete", func(ctx context.Context, repo UserRepository) error {
+			return repo.Delete(ctx, "ash", 0)
+		}, nil, 0},
+		{"delete at the current version", func(ctx context.Context, repo UserRepository) error {
+			return repo.Delete(ctx, "ash", 1)
+		}, nil, 0},
+		{"delete at an old version", func(ctx context.Context, repo UserRepository) error {
+			return repo.Delete(ctx, "ash", 2)
+		}, ErrVersionMismatch, 1},
+		{"delete a missing user", func(ctx context.Context, repo UserRepository) error {
+			return repo.Delete(ctx, "brock", 0)
+		}, ErrNotFound, 1},
+	}
+	for _, tt := range tests {
+		for name, repo := range backends(t) {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				ctx := context.Background()
+				ash := &user{Login: "ash", Role: auth.Viewer}
+				if err := repo.Create(ctx, ash); err != nil {
+					t.Fatal(err)
+				}
+				if ash.Version != 1 || ash.UpdatedAt.IsZero() {
+					t.Fatalf("Create stamped version %d at %v", ash.Version, ash.UpdatedAt)
+				}
+
+				if err := tt.write(ctx, repo); !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+				u, err := repo.Get(ctx, "ash")
+				if err != nil && !errors.Is(err, ErrNotFound) {
+					t.Fatal(err)
+				}
+				if u.Version != tt.version {
+					t.Errorf("version = %d, want %d", u.Version, tt.version)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package users
+
+import (
+	"context"
+	"fmt"
+
+	"example.com/pokemon-handbook/auth"
+)
+
+// MigrateLegacyRoles gives the editor role to the users stored with a role that is not known to the auth package.
+// Before roles were enforced, every account could change pokemons, and editor keeps that ability
+// without granting the management of users. It is safe to run on every start.
+func MigrateLegacyRoles(repo UserRepository) error {
+	users, err := repo.List(context.Background())
+	if err != nil {
+		return err
+	}
+
+	migrated := 0
+	for _, u := range users {
+		if auth.IsRole(u.Role) {
+			continue
+		}
+		u.Role = auth.Editor
+		if _, err := repo.Upsert(context.Background(), &u, u.Version); err != nil {
+			return err
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		fmt.Printf("Gave the %s role to %d users with an unknown role.\n", auth.Editor, migrated)
+	}
+	return nil
+}